@@ -0,0 +1,111 @@
+package altlimit
+
+import (
+	"net/netip"
+	"sort"
+)
+
+type Config struct {
+	MaxAccounts int
+	// Prefix lengths accounts are grouped by: 32/128 per address, e.g. 24/64 per subnet
+	IPv4PrefixLen int
+	IPv6PrefixLen int
+}
+
+// Session is an online account and the address it connected from
+type Session struct {
+	Username string
+	UUID     string
+	IP       string
+}
+
+// Group is a set of online accounts sharing an address or subnet
+type Group struct {
+	Key      string
+	Sessions []Session
+}
+
+// Limiter enforces the maximum number of distinct accounts online per address group
+type Limiter struct {
+	cfg *Config
+}
+
+func NewLimiter(cfg *Config) *Limiter {
+	if cfg.IPv4PrefixLen <= 0 || cfg.IPv4PrefixLen > 32 {
+		cfg.IPv4PrefixLen = 32
+	}
+	if cfg.IPv6PrefixLen <= 0 || cfg.IPv6PrefixLen > 128 {
+		cfg.IPv6PrefixLen = 128
+	}
+	return &Limiter{cfg: cfg}
+}
+
+// Key returns the group an address belongs to, or "" if ip cannot be parsed
+func (l *Limiter) Key(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	bits := l.cfg.IPv6PrefixLen
+	if addr.Is4() {
+		bits = l.cfg.IPv4PrefixLen
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	if bits == addr.BitLen() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
+
+// Check counts the other accounts online in ip's group. ok is false when admitting
+// uuid would exceed MaxAccounts; reconnects of an account already online are not counted twice.
+func (l *Limiter) Check(ip, uuid string, online []Session) (count int, ok bool) {
+	key := l.Key(ip)
+	if key == "" || l.cfg.MaxAccounts <= 0 {
+		return 0, true
+	}
+
+	seen := make(map[string]struct{})
+	for _, s := range online {
+		if s.UUID == uuid || l.Key(s.IP) != key {
+			continue
+		}
+		seen[s.UUID] = struct{}{}
+	}
+	return len(seen), len(seen) < l.cfg.MaxAccounts
+}
+
+// Shared returns the groups with more than one online account, largest first
+func (l *Limiter) Shared(online []Session) []Group {
+	byKey := make(map[string][]Session)
+	for _, s := range online {
+		if key := l.Key(s.IP); key != "" {
+			byKey[key] = append(byKey[key], s)
+		}
+	}
+
+	var groups []Group
+	for key, sessions := range byKey {
+		if len(sessions) < 2 {
+			continue
+		}
+		sort.Slice(sessions, func(i, j int) bool { return sessions[i].Username < sessions[j].Username })
+		groups = append(groups, Group{Key: key, Sessions: sessions})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].Sessions) != len(groups[j].Sessions) {
+			return len(groups[i].Sessions) > len(groups[j].Sessions)
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+func (l *Limiter) Config() *Config {
+	return l.cfg
+}
@@ -0,0 +1,84 @@
+package altlimit
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		ip   string
+		want string
+	}{
+		{"per address", Config{}, "1.2.3.4", "1.2.3.4"},
+		{"mapped address", Config{}, "::ffff:1.2.3.4", "1.2.3.4"},
+		{"ipv4 subnet", Config{IPv4PrefixLen: 24}, "1.2.3.4", "1.2.3.0/24"},
+		{"ipv6 per address", Config{}, "2001:db8::1", "2001:db8::1"},
+		{"ipv6 subnet", Config{IPv6PrefixLen: 64}, "2001:db8:0:1::5", "2001:db8:0:1::/64"},
+		{"out of range falls back", Config{IPv4PrefixLen: 40}, "1.2.3.4", "1.2.3.4"},
+		{"invalid", Config{}, "nope", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewLimiter(&tt.cfg).Key(tt.ip); got != tt.want {
+				t.Fatalf("Key(%s) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	online := []Session{
+		{Username: "A", UUID: "a", IP: "1.2.3.4"},
+		{Username: "B", UUID: "b", IP: "1.2.3.4"},
+		{Username: "C", UUID: "c", IP: "1.2.3.9"},
+		{Username: "D", UUID: "d", IP: "5.6.7.8"},
+	}
+
+	tests := []struct {
+		name      string
+		cfg       Config
+		ip        string
+		uuid      string
+		wantCount int
+		wantOK    bool
+	}{
+		{"disabled", Config{}, "1.2.3.4", "new", 0, true},
+		{"below limit", Config{MaxAccounts: 3}, "1.2.3.4", "new", 2, true},
+		{"at limit", Config{MaxAccounts: 2}, "1.2.3.4", "new", 2, false},
+		{"reconnect not counted", Config{MaxAccounts: 2}, "1.2.3.4", "a", 1, true},
+		{"subnet counts neighbours", Config{MaxAccounts: 3, IPv4PrefixLen: 24}, "1.2.3.200", "new", 3, false},
+		{"other address", Config{MaxAccounts: 1}, "9.9.9.9", "new", 0, true},
+		{"unparsable ip admitted", Config{MaxAccounts: 1}, "nope", "new", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, ok := NewLimiter(&tt.cfg).Check(tt.ip, tt.uuid, online)
+			if count != tt.wantCount || ok != tt.wantOK {
+				t.Fatalf("Check(%s, %s) = %d, %v, want %d, %v", tt.ip, tt.uuid, count, ok, tt.wantCount, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestShared(t *testing.T) {
+	l := NewLimiter(&Config{IPv4PrefixLen: 24})
+	groups := l.Shared([]Session{
+		{Username: "Zed", IP: "1.2.3.4"},
+		{Username: "Amy", IP: "1.2.3.5"},
+		{Username: "Bob", IP: "5.6.7.8"},
+		{Username: "Cid", IP: "9.9.9.1"},
+		{Username: "Dan", IP: "9.9.9.2"},
+		{Username: "Eve", IP: "9.9.9.3"},
+		{Username: "Bad", IP: "nope"},
+	})
+
+	if len(groups) != 2 {
+		t.Fatalf("Shared() = %+v, want 2 groups", groups)
+	}
+	if groups[0].Key != "9.9.9.0/24" || len(groups[0].Sessions) != 3 {
+		t.Fatalf("largest group = %+v, want 9.9.9.0/24 with 3 sessions", groups[0])
+	}
+	if g := groups[1]; g.Key != "1.2.3.0/24" || g.Sessions[0].Username != "Amy" || g.Sessions[1].Username != "Zed" {
+		t.Fatalf("second group = %+v, want 1.2.3.0/24 sorted by name", g)
+	}
+}
@@ -0,0 +1,195 @@
+package asn
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/RMS-Server/RMS-Gate/internal/iprules"
+)
+
+// Record is the autonomous system an address belongs to
+type Record struct {
+	ASN uint32
+	Org string
+}
+
+// Database maps addresses to ASNs from a local CSV file and reloads it whenever it changes.
+// Each line is "network,asn,organization", the layout of the MaxMind GeoLite2-ASN CSV
+// export; a header line, "#" comments, "AS" prefixes and bare addresses are accepted.
+type Database struct {
+	log  logr.Logger
+	path string
+
+	mu       sync.RWMutex
+	trie     *iprules.Trie[*Record]
+	prefixes int
+	asns     int
+	modTime  time.Time
+}
+
+func NewDatabase(ctx context.Context, log logr.Logger, path string, reloadInterval time.Duration) *Database {
+	d := &Database{
+		log:  log.WithName("asn"),
+		path: path,
+		trie: iprules.NewTrie[*Record](),
+	}
+	d.reload()
+
+	if reloadInterval <= 0 {
+		reloadInterval = 30 * time.Second
+	}
+	go d.watch(ctx, reloadInterval)
+	return d
+}
+
+// Lookup returns the ASN record of ip
+func (d *Database) Lookup(ip string) (*Record, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	rec, _, ok := d.trie.Lookup(addr)
+	return rec, ok
+}
+
+// Counts returns the number of loaded prefixes and distinct ASNs
+func (d *Database) Counts() (prefixes, asns int) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.prefixes, d.asns
+}
+
+func (d *Database) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(d.path)
+			if err != nil {
+				continue
+			}
+			d.mu.RLock()
+			changed := !info.ModTime().Equal(d.modTime)
+			d.mu.RUnlock()
+			if changed {
+				d.reload()
+			}
+		}
+	}
+}
+
+func (d *Database) reload() {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		d.log.Error(err, "ASN database not readable", "path", d.path)
+		return
+	}
+
+	file, err := os.Open(d.path)
+	if err != nil {
+		d.log.Error(err, "Failed to open ASN database", "path", d.path)
+		return
+	}
+	defer file.Close()
+
+	// Build the new trie without holding the lock so lookups keep working
+	trie, records, skipped, err := parse(file)
+	if err != nil {
+		// Keep serving the previous data until the file is fixed
+		d.log.Error(err, "Failed to parse ASN database", "path", d.path)
+		d.mu.Lock()
+		d.modTime = info.ModTime()
+		d.mu.Unlock()
+		return
+	}
+
+	d.mu.Lock()
+	d.trie = trie
+	d.prefixes = trie.Len()
+	d.asns = records
+	d.modTime = info.ModTime()
+	d.mu.Unlock()
+
+	d.log.Info("ASN database loaded", "path", d.path, "prefixes", trie.Len(), "asns", records, "skipped", skipped)
+}
+
+func parse(r io.Reader) (*iprules.Trie[*Record], int, int, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	trie := iprules.NewTrie[*Record]()
+	// Records are shared between prefixes of the same ASN to save memory
+	records := make(map[uint32]*Record)
+	skipped := 0
+
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				skipped++
+				continue
+			}
+			return nil, 0, 0, err
+		}
+		if len(fields) < 2 {
+			skipped++
+			continue
+		}
+
+		prefix, err := iprules.ParsePrefix(fields[0])
+		if err != nil {
+			// Header line or garbage
+			skipped++
+			continue
+		}
+		number, err := ParseASN(fields[1])
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		rec, ok := records[number]
+		if !ok {
+			rec = &Record{ASN: number}
+			if len(fields) > 2 {
+				rec.Org = strings.TrimSpace(fields[2])
+			}
+			records[number] = rec
+		}
+		trie.Insert(prefix, rec)
+	}
+	return trie, len(records), skipped, nil
+}
+
+// ParseASN accepts "13335" or "AS13335"
+func ParseASN(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	return uint32(n), err
+}
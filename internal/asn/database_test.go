@@ -0,0 +1,108 @@
+package asn
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestParseASN(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint32
+		wantErr bool
+	}{
+		{"13335", 13335, false},
+		{"AS13335", 13335, false},
+		{" as15169 ", 15169, false},
+		{"AS", 0, true},
+		{"ASN1", 0, true},
+		{"-1", 0, true},
+		{"4294967296", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseASN(tt.in)
+			if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+				t.Fatalf("ParseASN(%q) = %d, %v, want %d (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	const data = `network,autonomous_system_number,autonomous_system_organization
+# comment
+1.1.1.0/24,13335,"Cloudflare, Inc."
+104.16.0.0/13,AS13335,Cloudflare
+8.8.8.8,15169,Google LLC
+2001:4860::/32,15169
+garbage,1
+10.0.0.0/8,notanumber,Private
+"unterminated
+`
+	trie, asns, skipped, err := parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trie.Len() != 4 || asns != 2 {
+		t.Fatalf("parse = %d prefixes, %d asns, want 4 and 2", trie.Len(), asns)
+	}
+	// header, garbage, bad number and the broken quote
+	if skipped != 4 {
+		t.Fatalf("parse skipped %d lines, want 4", skipped)
+	}
+}
+
+func TestDatabaseLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asn.csv")
+	data := "1.1.1.0/24,13335,\"Cloudflare, Inc.\"\n104.16.0.0/13,13335,Cloudflare\n2001:4860::/32,15169,Google LLC\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDatabase(t.Context(), logr.Discard(), path, time.Hour)
+
+	tests := []struct {
+		ip      string
+		wantASN uint32
+		wantOrg string
+		wantOK  bool
+	}{
+		{"1.1.1.1", 13335, "Cloudflare, Inc.", true},
+		// the first organization seen for an ASN is kept
+		{"104.16.1.1", 13335, "Cloudflare, Inc.", true},
+		{"::ffff:1.1.1.1", 13335, "Cloudflare, Inc.", true},
+		{"2001:4860:4860::8888", 15169, "Google LLC", true},
+		{"9.9.9.9", 0, "", false},
+		{"nope", 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			rec, ok := d.Lookup(tt.ip)
+			if ok != tt.wantOK {
+				t.Fatalf("Lookup(%s) found %v, want %v", tt.ip, ok, tt.wantOK)
+			}
+			if ok && (rec.ASN != tt.wantASN || rec.Org != tt.wantOrg) {
+				t.Fatalf("Lookup(%s) = %+v, want AS%d %q", tt.ip, rec, tt.wantASN, tt.wantOrg)
+			}
+		})
+	}
+
+	if prefixes, asns := d.Counts(); prefixes != 3 || asns != 2 {
+		t.Fatalf("Counts() = %d, %d, want 3, 2", prefixes, asns)
+	}
+}
+
+func TestDatabaseMissingFile(t *testing.T) {
+	d := NewDatabase(t.Context(), logr.Discard(), filepath.Join(t.TempDir(), "missing.csv"), time.Hour)
+	if _, ok := d.Lookup("1.1.1.1"); ok {
+		t.Fatal("Lookup found an address without a database")
+	}
+	if prefixes, asns := d.Counts(); prefixes != 0 || asns != 0 {
+		t.Fatalf("Counts() = %d, %d, want 0, 0", prefixes, asns)
+	}
+}
@@ -0,0 +1,81 @@
+package asn
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Action int
+
+const (
+	Allow Action = iota
+	Deny
+	// Flag lets the login through but logs it
+	Flag
+)
+
+func (a Action) String() string {
+	switch a {
+	case Deny:
+		return "deny"
+	case Flag:
+		return "flag"
+	default:
+		return "allow"
+	}
+}
+
+func parseAction(s string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "allow":
+		return Allow, nil
+	case "deny":
+		return Deny, nil
+	case "flag":
+		return Flag, nil
+	}
+	return Allow, fmt.Errorf("unknown ASN policy %q", s)
+}
+
+// Policy decides what to do with logins from each ASN
+type Policy struct {
+	actions       map[uint32]Action
+	defaultAction Action
+}
+
+// NewPolicy builds a policy from "AS13335" -> "deny" style rules. Invalid rules are
+// returned as errors and skipped.
+func NewPolicy(rules map[string]string, defaultAction string) (*Policy, []error) {
+	var errs []error
+	def, err := parseAction(defaultAction)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	p := &Policy{actions: make(map[uint32]Action, len(rules)), defaultAction: def}
+	for key, value := range rules {
+		number, err := ParseASN(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid ASN %q: %w", key, err))
+			continue
+		}
+		action, err := parseAction(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.actions[number] = action
+	}
+	return p, errs
+}
+
+// Decide returns the action for a known ASN; addresses missing from the database are always allowed
+func (p *Policy) Decide(rec *Record) Action {
+	if rec == nil {
+		return Allow
+	}
+	if action, ok := p.actions[rec.ASN]; ok {
+		return action
+	}
+	return p.defaultAction
+}
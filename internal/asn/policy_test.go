@@ -0,0 +1,51 @@
+package asn
+
+import "testing"
+
+func TestPolicyDecide(t *testing.T) {
+	p, errs := NewPolicy(map[string]string{
+		"AS13335": "deny",
+		"15169":   "Flag",
+		"AS64500": "allow",
+	}, "allow")
+	if len(errs) != 0 {
+		t.Fatalf("NewPolicy errors = %v", errs)
+	}
+	strict, _ := NewPolicy(map[string]string{"AS64500": "allow"}, "deny")
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		rec    *Record
+		want   Action
+	}{
+		{"denied asn", p, &Record{ASN: 13335}, Deny},
+		{"flagged asn", p, &Record{ASN: 15169}, Flag},
+		{"unlisted asn", p, &Record{ASN: 1}, Allow},
+		{"unknown address", p, nil, Allow},
+		{"default deny", strict, &Record{ASN: 1}, Deny},
+		{"allowed under default deny", strict, &Record{ASN: 64500}, Allow},
+		{"unknown address under default deny", strict, nil, Allow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Decide(tt.rec); got != tt.want {
+				t.Fatalf("Decide(%+v) = %s, want %s", tt.rec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicyInvalidRules(t *testing.T) {
+	p, errs := NewPolicy(map[string]string{"ASX": "deny", "AS1": "block", "AS2": "flag"}, "reject")
+	if len(errs) != 3 {
+		t.Fatalf("NewPolicy errors = %v, want the bad ASN, action and default", errs)
+	}
+	// Valid rules still apply and an invalid default falls back to allow
+	if got := p.Decide(&Record{ASN: 2}); got != Flag {
+		t.Fatalf("Decide(AS2) = %s, want flag", got)
+	}
+	if got := p.Decide(&Record{ASN: 1}); got != Allow {
+		t.Fatalf("Decide(AS1) = %s, want allow", got)
+	}
+}
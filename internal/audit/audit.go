@@ -0,0 +1,251 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Outcomes recorded for commands and state changes
+const (
+	// OutcomeAllowed marks a command of Gate or another plugin, whose result RMS Gate does not see
+	OutcomeAllowed = "allowed"
+	OutcomeDenied  = "denied"
+	OutcomeOK      = "ok"
+	OutcomeFailed  = "failed"
+	// OutcomeFiltered marks a command answered by RMS Gate with restricted servers left out
+	OutcomeFiltered = "filtered"
+)
+
+// Entry is one audited command or state change
+type Entry struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor is the player name, "console", or the subsystem for automatic state changes
+	Actor string `json:"actor"`
+	UUID  string `json:"uuid,omitempty"`
+	// Command is the command name, or the state change such as "backend.disabled"
+	Command string `json:"command"`
+	Args    string `json:"args,omitempty"`
+	Outcome string `json:"outcome"`
+}
+
+// Log is an append-only audit trail in audit.db. A nil *Log records nothing,
+// so components can hold one unconditionally.
+type Log struct {
+	log     logr.Logger
+	db      *sql.DB
+	dataDir string
+}
+
+func NewLog(log logr.Logger, dataDir string) *Log {
+	l := &Log{
+		log:     log.WithName("audit"),
+		dataDir: dataDir,
+	}
+	l.initDB()
+	return l
+}
+
+func (l *Log) initDB() {
+	db, err := sql.Open("sqlite3", filepath.Join(l.dataDir, "audit.db"))
+	if err != nil {
+		l.log.Error(err, "Failed to open audit database")
+		return
+	}
+	l.db = db
+
+	_, _ = db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			time INTEGER NOT NULL,
+			actor TEXT NOT NULL,
+			uuid TEXT NOT NULL DEFAULT '',
+			command TEXT NOT NULL,
+			args TEXT NOT NULL DEFAULT '',
+			outcome TEXT NOT NULL DEFAULT ''
+		)
+	`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_actor ON audit_log (actor COLLATE NOCASE, id)`)
+}
+
+// Record appends an entry, stamping it with the current time when unset
+func (l *Log) Record(e Entry) {
+	if l == nil || l.db == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	_, err := l.db.Exec(`
+		INSERT INTO audit_log (time, actor, uuid, command, args, outcome)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, e.Time.UnixMilli(), e.Actor, e.UUID, e.Command, e.Args, e.Outcome)
+	if err != nil {
+		l.log.Error(err, "Failed to record audit entry", "actor", e.Actor, "command", e.Command)
+	}
+}
+
+// Command records a command line run by actor
+func (l *Log) Command(actor, uuid, cmdline, outcome string) {
+	name, args, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(cmdline, "/")), " ")
+	l.Record(Entry{Actor: actor, UUID: uuid, Command: strings.ToLower(name), Args: strings.TrimSpace(args), Outcome: outcome})
+}
+
+// Change records a state change made by a subsystem, with key=value details
+func (l *Log) Change(actor, change, outcome string, details ...any) {
+	l.Record(Entry{Actor: actor, Command: change, Args: formatDetails(details), Outcome: outcome})
+}
+
+func formatDetails(details []any) string {
+	parts := make([]string, 0, len(details)/2)
+	for i := 0; i+1 < len(details); i += 2 {
+		parts = append(parts, fmt.Sprintf("%v=%v", details[i], details[i+1]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Recent returns the newest entries first, only those of actor when it is not empty
+func (l *Log) Recent(limit int, actor string) ([]Entry, error) {
+	if l == nil || l.db == nil {
+		return nil, nil
+	}
+
+	query := `SELECT id, time, actor, uuid, command, args, outcome FROM audit_log`
+	args := []any{}
+	if actor != "" {
+		query += ` WHERE actor = ? COLLATE NOCASE`
+		args = append(args, actor)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func scanEntry(rows *sql.Rows) (Entry, error) {
+	var e Entry
+	var ts int64
+	if err := rows.Scan(&e.ID, &ts, &e.Actor, &e.UUID, &e.Command, &e.Args, &e.Outcome); err != nil {
+		return e, err
+	}
+	e.Time = time.UnixMilli(ts)
+	return e, nil
+}
+
+// Export writes all entries, oldest first, as a JSON array; only those of actor when it is not empty
+func (l *Log) Export(w io.Writer, actor string) (int, error) {
+	if l == nil || l.db == nil {
+		return 0, fmt.Errorf("audit log is not available")
+	}
+
+	query := `SELECT id, time, actor, uuid, command, args, outcome FROM audit_log`
+	args := []any{}
+	if actor != "" {
+		query += ` WHERE actor = ? COLLATE NOCASE`
+		args = append(args, actor)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// Stream the rows so large logs are not held in memory
+	if _, err := io.WriteString(w, "[\n"); err != nil {
+		return 0, err
+	}
+	count := 0
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return count, err
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return count, err
+		}
+		sep := "  "
+		if count > 0 {
+			sep = ",\n  "
+		}
+		if _, err := io.WriteString(w, sep+string(data)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	return count, err
+}
+
+// ExportFile exports to a timestamped JSON file in the data directory and returns its path
+func (l *Log) ExportFile(actor string) (string, int, error) {
+	if l == nil {
+		return "", 0, fmt.Errorf("audit log is not available")
+	}
+
+	name := "audit-export-" + time.Now().Format("20060102-150405")
+	if actor != "" {
+		name += "-" + fileSafe(actor)
+	}
+	path := filepath.Join(l.dataDir, name+".json")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	count, err := l.Export(file, actor)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", 0, err
+	}
+	return path, count, nil
+}
+
+// fileSafe keeps the characters of a player name that are safe in a file name
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func (l *Log) Close() error {
+	if l != nil && l.db != nil {
+		return l.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		cmdline     string
+		wantCommand string
+		wantArgs    string
+	}{
+		{"lb disable lobby 10.0.0.1:25565", "lb", "disable lobby 10.0.0.1:25565"},
+		{"/WL add Steve", "wl", "add Steve"},
+		{"  glist  ", "glist", ""},
+		{"perm grant Steve   mod", "perm", "grant Steve   mod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmdline, func(t *testing.T) {
+			l := NewLog(logr.Discard(), t.TempDir())
+			defer l.Close()
+
+			l.Command("Steve", "uuid-1", tt.cmdline, OutcomeOK)
+			entries, err := l.Recent(1, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := entries[0]
+			if e.Actor != "Steve" || e.UUID != "uuid-1" || e.Command != tt.wantCommand || e.Args != tt.wantArgs || e.Outcome != OutcomeOK {
+				t.Fatalf("recorded %+v, want command %q args %q", e, tt.wantCommand, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		details  []any
+		wantArgs string
+	}{
+		{"no details", nil, ""},
+		{"pairs", []any{"server", "lobby", "seconds", 300}, "server=lobby seconds=300"},
+		{"odd trailing key dropped", []any{"server", "lobby", "dangling"}, "server=lobby"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLog(logr.Discard(), t.TempDir())
+			defer l.Close()
+
+			l.Change("load-balancer", "backend.disabled", OutcomeOK, tt.details...)
+			entries, err := l.Recent(1, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e := entries[0]; e.Command != "backend.disabled" || e.Args != tt.wantArgs || e.UUID != "" {
+				t.Fatalf("recorded %+v, want args %q", e, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRecentAndExport(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLog(logr.Discard(), dir)
+	defer l.Close()
+
+	l.Command("Steve", "", "wl list", OutcomeOK)
+	l.Command("Alex", "", "lb", OutcomeDenied)
+	l.Command("steve", "", "perm info Alex", OutcomeFailed)
+	l.Change("dynamic-server", "server.start", OutcomeOK, "server", "survival")
+
+	tests := []struct {
+		name  string
+		limit int
+		actor string
+		want  []string // commands, newest first
+	}{
+		{"all", 10, "", []string{"server.start", "perm", "lb", "wl"}},
+		{"limited", 2, "", []string{"server.start", "perm"}},
+		{"actor ignores case", 10, "STEVE", []string{"perm", "wl"}},
+		{"subsystem", 10, "dynamic-server", []string{"server.start"}},
+		{"unknown actor", 10, "Herobrine", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := l.Recent(tt.limit, tt.actor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("Recent(%d, %q) = %d entries, want %d", tt.limit, tt.actor, len(entries), len(tt.want))
+			}
+			for i, e := range entries {
+				if e.Command != tt.want[i] {
+					t.Fatalf("Recent(%d, %q)[%d] = %s, want %s", tt.limit, tt.actor, i, e.Command, tt.want[i])
+				}
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	n, err := l.Export(&buf, "steve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []Entry
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatalf("export is not a JSON array: %v\n%s", err, buf.String())
+	}
+	if n != 2 || len(exported) != 2 || exported[0].Command != "wl" || exported[1].Command != "perm" {
+		t.Fatalf("Export = %d entries %+v, want wl then perm", n, exported)
+	}
+
+	path, n, err := l.ExportFile("Ste/ve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir || n != 0 {
+		t.Fatalf("ExportFile = %s with %d entries, want a file in %s", path, n, dir)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNilLog(t *testing.T) {
+	var l *Log
+	l.Command("Steve", "", "wl list", OutcomeOK)
+	l.Change("load-balancer", "backend.enabled", OutcomeOK)
+	if entries, err := l.Recent(10, ""); entries != nil || err != nil {
+		t.Fatalf("Recent on a nil log = %v, %v", entries, err)
+	}
+	if _, err := l.Export(&bytes.Buffer{}, ""); err == nil {
+		t.Fatal("Export on a nil log succeeded")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,15 +9,183 @@ import (
 )
 
 type Config struct {
-	APIUrl            string               `json:"apiUrl"`
-	TimeoutSeconds    int                  `json:"timeoutSeconds"`
-	ServerTier        int                  `json:"serverTier"`
-	MsgNotInWhitelist string               `json:"msgNotInWhitelist"`
-	MsgServerError    string               `json:"msgServerError"`
-	MCSManager        *MCSManagerConfig    `json:"mcsManager"`
-	DynamicServer     *DynamicServerConfig `json:"dynamicServer"`
-	Permission        *PermissionConfig    `json:"permission"`
-	LoadBalancer      *LoadBalancerConfig  `json:"loadBalancer"`
+	APIUrl         string               `json:"apiUrl"`
+	APIEndpoints   []*APIEndpointConfig `json:"apiEndpoints"`
+	APIFailover    *APIFailoverConfig   `json:"apiFailover"`
+	TimeoutSeconds int                  `json:"timeoutSeconds"`
+	APIAuth        *APIAuthConfig       `json:"apiAuth"`
+	ServerTier     int                  `json:"serverTier"`
+	ServerTiers    map[string]int       `json:"serverTiers"`
+	// ServerAccess restricts servers by permission level or member list
+	ServerAccess        map[string]*ServerAccessConfig `json:"serverAccess"`
+	MsgNotInWhitelist   string                         `json:"msgNotInWhitelist"`
+	MsgServerError      string                         `json:"msgServerError"`
+	MsgDenyReason       string                         `json:"msgDenyReason"`
+	MsgBannedUntil      string                         `json:"msgBannedUntil"`
+	MsgRequiredTier     string                         `json:"msgRequiredTier"`
+	MsgTierDenied       string                         `json:"msgTierDenied"`
+	MsgServerRestricted string                         `json:"msgServerRestricted"`
+	OfflineGrace        *OfflineGraceConfig            `json:"offlineGrace"`
+	Whitelist           *WhitelistConfig               `json:"whitelist"`
+	Login               *LoginConfig                   `json:"login"`
+	Throttle            *ThrottleConfig                `json:"throttle"`
+	IPRules             *IPRulesConfig                 `json:"ipRules"`
+	AltLimit            *AltLimitConfig                `json:"altLimit"`
+	ASN                 *ASNConfig                     `json:"asn"`
+	Maintenance         *MaintenanceConfig             `json:"maintenance"`
+	Revalidation        *RevalidationConfig            `json:"revalidation"`
+	MCSManager          *MCSManagerConfig              `json:"mcsManager"`
+	DynamicServer       *DynamicServerConfig           `json:"dynamicServer"`
+	Permission          *PermissionConfig              `json:"permission"`
+	Audit               *AuditConfig                   `json:"audit"`
+	LoadBalancer        *LoadBalancerConfig            `json:"loadBalancer"`
+	// ServerCommand registers RMS Gate's own /server, which lists and suggests only the servers
+	// a player may join. Gate's builtinCommands must be disabled for it.
+	ServerCommand bool `json:"serverCommand"`
+}
+
+// ServerAccessConfig admits players at minLevel or above and the listed members
+// (usernames or UUIDs); restricted servers are hidden from /server and /glist for everyone else.
+type ServerAccessConfig struct {
+	MinLevel int      `json:"minLevel"`
+	Members  []string `json:"members"`
+}
+
+// APIEndpointConfig is one RMS API node. Lower priorities are tried first.
+// When apiEndpoints is empty, apiUrl is used as the only endpoint.
+type APIEndpointConfig struct {
+	URL      string `json:"url"`
+	Priority int    `json:"priority"`
+}
+
+// APIFailoverConfig controls when a failing API node is skipped
+type APIFailoverConfig struct {
+	FailThreshold   int `json:"failThreshold"`
+	CooldownSeconds int `json:"cooldownSeconds"`
+}
+
+// APIAuthConfig authenticates every outgoing RMS API call (whitelist and permission).
+// Mechanisms are optional and can be combined; file paths are relative to the plugin data dir.
+type APIAuthConfig struct {
+	BearerToken    string `json:"bearerToken"`
+	HMACSecret     string `json:"hmacSecret"`
+	CAFile         string `json:"caFile"`
+	ClientCertFile string `json:"clientCertFile"`
+	ClientKeyFile  string `json:"clientKeyFile"`
+	ResponseSecret string `json:"responseSecret"`
+}
+
+type OfflineGraceConfig struct {
+	Enabled      bool `json:"enabled"`
+	GraceSeconds int  `json:"graceSeconds"`
+}
+
+// WhitelistConfig selects the whitelist providers. Providers are asked in
+// order ("http", "file", "sqlite") until one of them knows the player.
+// The sqlite provider backs /wl and is put first when not listed.
+type WhitelistConfig struct {
+	Providers             []string `json:"providers"`
+	FilePath              string   `json:"filePath"`
+	ReloadIntervalSeconds int      `json:"reloadIntervalSeconds"`
+	SQLitePath            string   `json:"sqlitePath"`
+	GuestDefaultSeconds   int      `json:"guestDefaultSeconds"`
+	// LoginContext opts in to sending connection details to the HTTP API
+	LoginContext *LoginContextConfig `json:"loginContext"`
+}
+
+type LoginContextConfig struct {
+	IP          bool `json:"ip"`
+	Protocol    bool `json:"protocol"`
+	VirtualHost bool `json:"virtualHost"`
+	OnlineMode  bool `json:"onlineMode"`
+	ASN         bool `json:"asn"`
+}
+
+// LoginConfig orders the pre-login checks. Checks run top to bottom and the first deny wins;
+// available checks missing from the list are appended in their default order.
+type LoginConfig struct {
+	Checks []*LoginCheckConfig `json:"checks"`
+}
+
+type LoginCheckConfig struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// ThrottleConfig limits login attempts per IP and overall. IPs that are rate limited
+// banThreshold times within banWindowSeconds are banned for banSeconds.
+// maxInFlight caps concurrent whitelist checks so floods do not reach the RMS API.
+type ThrottleConfig struct {
+	Enabled            bool   `json:"enabled"`
+	PerIPPerMinute     int    `json:"perIpPerMinute"`
+	PerIPBurst         int    `json:"perIpBurst"`
+	GlobalPerSecond    int    `json:"globalPerSecond"`
+	GlobalBurst        int    `json:"globalBurst"`
+	BanThreshold       int    `json:"banThreshold"`
+	BanWindowSeconds   int    `json:"banWindowSeconds"`
+	BanSeconds         int    `json:"banSeconds"`
+	MaxInFlight        int    `json:"maxInFlight"`
+	InFlightWaitMillis int    `json:"inFlightWaitMillis"`
+	MsgThrottled       string `json:"msgThrottled"`
+	MsgBanned          string `json:"msgBanned"`
+	MsgBusy            string `json:"msgBusy"`
+}
+
+// IPRulesConfig holds static CIDR lists; bans added with /ipban are stored in ipbans.db.
+// Deny rules and bans win over allow; a non-empty allow list rejects every other address.
+type IPRulesConfig struct {
+	Allow         []string `json:"allow"`
+	Deny          []string `json:"deny"`
+	MsgDenied     string   `json:"msgDenied"`
+	MsgNotAllowed string   `json:"msgNotAllowed"`
+}
+
+// AltLimitConfig caps distinct accounts online per address. Prefix lengths group
+// addresses, e.g. 24 and 64 to count per /24 and /64 instead of per address.
+// Players at or above exemptLevel may exceed the limit; 0 disables exemptions.
+type AltLimitConfig struct {
+	Enabled       bool   `json:"enabled"`
+	MaxAccounts   int    `json:"maxAccounts"`
+	IPv4PrefixLen int    `json:"ipv4PrefixLen"`
+	IPv6PrefixLen int    `json:"ipv6PrefixLen"`
+	ExemptLevel   int    `json:"exemptLevel"`
+	MsgTooMany    string `json:"msgTooMany"`
+}
+
+// ASNConfig loads a local "network,asn,organization" CSV (e.g. GeoLite2-ASN) and applies
+// a policy per ASN: "allow", "deny" or "flag" (allow and log). Unlisted ASNs use
+// defaultPolicy; addresses missing from the database are always allowed.
+type ASNConfig struct {
+	Enabled               bool              `json:"enabled"`
+	DatabasePath          string            `json:"databasePath"`
+	ReloadIntervalSeconds int               `json:"reloadIntervalSeconds"`
+	DefaultPolicy         string            `json:"defaultPolicy"`
+	Policies              map[string]string `json:"policies"`
+	MsgDenied             string            `json:"msgDenied"`
+}
+
+// MaintenanceConfig controls who may join during maintenance and how players are notified.
+// Maintenance itself is switched on and off with /rmsgate maintenance.
+type MaintenanceConfig struct {
+	BypassLevel          int    `json:"bypassLevel"`
+	KickOnline           bool   `json:"kickOnline"`
+	KickCountdownSeconds int    `json:"kickCountdownSeconds"`
+	Motd                 string `json:"motd"`
+	MsgDefault           string `json:"msgDefault"`
+	MsgKickCountdown     string `json:"msgKickCountdown"`
+	// MsgUntil is appended to the maintenance message when an end time is set, %s is that time
+	MsgUntil string `json:"msgUntil"`
+}
+
+// RevalidationConfig re-checks online players so revoked players are removed.
+// ListenAddr enables the endpoint the RMS API can call; requests must send the token as a bearer token.
+type RevalidationConfig struct {
+	Enabled          bool   `json:"enabled"`
+	IntervalSeconds  int    `json:"intervalSeconds"`
+	BatchSize        int    `json:"batchSize"`
+	BatchDelayMillis int    `json:"batchDelayMillis"`
+	ListenAddr       string `json:"listenAddr"`
+	Token            string `json:"token"`
 }
 
 type LoadBalancerConfig struct {
@@ -46,43 +214,206 @@ type BackendConfig struct {
 }
 
 type PermissionConfig struct {
-	Enabled         bool     `json:"enabled"`
-	CacheTTLSeconds int      `json:"cacheTtlSeconds"`
-	AdminCommands   []string `json:"adminCommands"`
-	MsgNoPermission string   `json:"msgNoPermission"`
+	Enabled bool `json:"enabled"`
+	// CacheTTLSeconds is the age after which cached levels are reported as stale
+	CacheTTLSeconds int `json:"cacheTtlSeconds"`
+	// RefreshIntervalSeconds is how often the permission list is reloaded in the background
+	RefreshIntervalSeconds int `json:"refreshIntervalSeconds"`
+	// NegativeCacheSeconds is how long unknown players are remembered before refetching
+	NegativeCacheSeconds int      `json:"negativeCacheSeconds"`
+	AdminCommands        []string `json:"adminCommands"`
+	MsgNoPermission      string   `json:"msgNoPermission"`
+	// CommandLevels maps command paths ("lb status", "lb *", "dserver delay") to the minimum
+	// level; the most specific match wins and unmatched commands fall back to adminCommands
+	CommandLevels map[string]int `json:"commandLevels"`
+	// Nodes grants extra permission nodes to players at or above a level, for other Gate plugins
+	Nodes map[string]int `json:"nodes"`
+	// VerifyResponses rejects permission responses without a valid signature (see apiAuth.responseSecret)
+	VerifyResponses bool `json:"verifyResponses"`
+	// OverridesPath is the local groups and grants file merged on top of the API levels
+	OverridesPath string `json:"overridesPath"`
+	// Elevation configures /elevate; nil disables it
+	Elevation *ElevationConfig `json:"elevation"`
+}
+
+// ElevationConfig lets players at minLevel or above raise themselves to level for at most maxSeconds
+type ElevationConfig struct {
+	Enabled    bool `json:"enabled"`
+	MinLevel   int  `json:"minLevel"`
+	Level      int  `json:"level"`
+	MaxSeconds int  `json:"maxSeconds"`
+	// CooldownSeconds is the wait after an elevation ends before the next one, maxSeconds when unset
+	CooldownSeconds int  `json:"cooldownSeconds"`
+	RequireReason   bool `json:"requireReason"`
+}
+
+// AuditConfig controls the audit log in audit.db, which records every command and
+// load balancer and dynamic server state change
+type AuditConfig struct {
+	Enabled bool `json:"enabled"`
+	// RecentLimit is how many entries /audit recent shows
+	RecentLimit int `json:"recentLimit"`
 }
 
 type MCSManagerConfig struct {
+	MCSManagerPanelConfig
+	// Panels are further MCSManager panels, referenced by name from serverUuidMap.
+	// The top-level settings are the panel named "default".
+	Panels map[string]*MCSManagerPanelConfig `json:"panels,omitempty"`
+}
+
+type MCSManagerPanelConfig struct {
 	BaseURL  string `json:"baseUrl"`
 	APIKey   string `json:"apiKey"`
 	DaemonID string `json:"daemonId"`
 }
 
+// InstanceConfig locates the MCSManager instance of a server. In JSON it is either the
+// instance UUID, on the default panel and daemon, or an object that also names the panel and daemon.
+type InstanceConfig struct {
+	UUID     string `json:"uuid"`
+	Panel    string `json:"panel,omitempty"`
+	DaemonID string `json:"daemonId,omitempty"`
+}
+
+func (i *InstanceConfig) UnmarshalJSON(data []byte) error {
+	var uuid string
+	if err := json.Unmarshal(data, &uuid); err == nil {
+		*i = InstanceConfig{UUID: uuid}
+		return nil
+	}
+	type plain InstanceConfig
+	return json.Unmarshal(data, (*plain)(i))
+}
+
+func (i InstanceConfig) MarshalJSON() ([]byte, error) {
+	if i.Panel == "" && i.DaemonID == "" {
+		return json.Marshal(i.UUID)
+	}
+	type plain InstanceConfig
+	return json.Marshal(plain(i))
+}
+
 type DynamicServerConfig struct {
-	ServerUUIDMap              map[string]string `json:"serverUuidMap"`
-	AutoStartServers           []string          `json:"autoStartServers"`
-	StartupTimeoutSeconds      int               `json:"startupTimeoutSeconds"`
-	PollIntervalSeconds        int               `json:"pollIntervalSeconds"`
-	ConnectivityTimeoutSeconds int               `json:"connectivityTimeoutSeconds"`
-	IdleShutdownSeconds        int               `json:"idleShutdownSeconds"`
-	MsgStarting                string            `json:"msgStarting"`
-	MsgStartupTimeout          string            `json:"msgStartupTimeout"`
+	ServerUUIDMap              map[string]InstanceConfig `json:"serverUuidMap"`
+	AutoStartServers           []string                  `json:"autoStartServers"`
+	StartupTimeoutSeconds      int                       `json:"startupTimeoutSeconds"`
+	PollIntervalSeconds        int                       `json:"pollIntervalSeconds"`
+	ConnectivityTimeoutSeconds int                       `json:"connectivityTimeoutSeconds"`
+	IdleShutdownSeconds        int                       `json:"idleShutdownSeconds"`
+	MsgStarting                string                    `json:"msgStarting"`
+	MsgStartupTimeout          string                    `json:"msgStartupTimeout"`
+	MsgPanelUnavailable        string                    `json:"msgPanelUnavailable"`
+	MsgAlreadyStarting         string                    `json:"msgAlreadyStarting"`
+	MsgStopping                string                    `json:"msgStopping"`
+	MsgBusy                    string                    `json:"msgBusy"`
 }
 
 func defaultConfig() *Config {
 	return &Config{
-		APIUrl:            "http://localhost:8080/api/whitelist",
-		TimeoutSeconds:    10,
-		ServerTier:        1,
-		MsgNotInWhitelist: "您当前不在白名单中",
-		MsgServerError:    "500服务器内部错误，请联系管理员",
+		APIUrl:         "http://localhost:8080/api/whitelist",
+		TimeoutSeconds: 10,
+		APIEndpoints:   []*APIEndpointConfig{},
+		APIFailover: &APIFailoverConfig{
+			FailThreshold:   3,
+			CooldownSeconds: 30,
+		},
+		APIAuth:             &APIAuthConfig{},
+		ServerTier:          1,
+		ServerTiers:         map[string]int{},
+		ServerAccess:        map[string]*ServerAccessConfig{},
+		MsgNotInWhitelist:   "您当前不在白名单中",
+		MsgServerError:      "500服务器内部错误，请联系管理员",
+		MsgDenyReason:       "原因：%s",
+		MsgBannedUntil:      "封禁至：%s",
+		MsgRequiredTier:     "需要白名单等级：%d",
+		MsgTierDenied:       "你没有进入服务器 %s 的权限",
+		MsgServerRestricted: "服务器 %s 仅限特定玩家进入",
+		OfflineGrace: &OfflineGraceConfig{
+			Enabled:      true,
+			GraceSeconds: 86400,
+		},
+		Whitelist: &WhitelistConfig{
+			Providers:             []string{"sqlite", "http"},
+			FilePath:              "whitelist.yml",
+			ReloadIntervalSeconds: 5,
+			SQLitePath:            "whitelist.db",
+			GuestDefaultSeconds:   86400,
+			LoginContext:          &LoginContextConfig{},
+		},
+		Login: &LoginConfig{
+			Checks: []*LoginCheckConfig{
+				{Name: "iprules", Enabled: true},
+				{Name: "asn", Enabled: true},
+				{Name: "throttle", Enabled: true},
+				{Name: "maintenance", Enabled: true},
+				{Name: "alts", Enabled: true},
+				{Name: "whitelist", Enabled: true},
+			},
+		},
+		Throttle: &ThrottleConfig{
+			Enabled:            true,
+			PerIPPerMinute:     10,
+			PerIPBurst:         5,
+			GlobalPerSecond:    20,
+			GlobalBurst:        50,
+			BanThreshold:       10,
+			BanWindowSeconds:   60,
+			BanSeconds:         600,
+			MaxInFlight:        16,
+			InFlightWaitMillis: 2000,
+			MsgThrottled:       "登录过于频繁，请稍后再试",
+			MsgBanned:          "登录尝试过多，已被临时封禁至 %s",
+			MsgBusy:            "服务器繁忙，请稍后再试",
+		},
+		IPRules: &IPRulesConfig{
+			Allow:         []string{},
+			Deny:          []string{},
+			MsgDenied:     "你的 IP 已被封禁",
+			MsgNotAllowed: "当前网络不允许连接此服务器",
+		},
+		AltLimit: &AltLimitConfig{
+			Enabled:       false,
+			MaxAccounts:   2,
+			IPv4PrefixLen: 32,
+			IPv6PrefixLen: 64,
+			ExemptLevel:   4,
+			MsgTooMany:    "你的网络已有 %d 个账号在线（上限 %d 个）",
+		},
+		ASN: &ASNConfig{
+			Enabled:               false,
+			DatabasePath:          "asn.csv",
+			ReloadIntervalSeconds: 30,
+			DefaultPolicy:         "allow",
+			Policies:              map[string]string{},
+			MsgDenied:             "不允许通过 VPN 或数据中心网络连接",
+		},
+		Maintenance: &MaintenanceConfig{
+			BypassLevel:          4,
+			KickOnline:           true,
+			KickCountdownSeconds: 30,
+			Motd:                 "§c服务器维护中，请稍后再来",
+			MsgDefault:           "服务器正在维护，请稍后再来",
+			MsgKickCountdown:     "服务器将在 %d 秒后进入维护模式",
+			MsgUntil:             "预计结束时间：%s",
+		},
+		Revalidation: &RevalidationConfig{
+			Enabled:          true,
+			IntervalSeconds:  300,
+			BatchSize:        20,
+			BatchDelayMillis: 1000,
+			ListenAddr:       "",
+			Token:            "",
+		},
 		MCSManager: &MCSManagerConfig{
-			BaseURL:  "https://mcsm.example.com/api",
-			APIKey:   "your-api-key",
-			DaemonID: "your-daemon-id",
+			MCSManagerPanelConfig: MCSManagerPanelConfig{
+				BaseURL:  "https://mcsm.example.com/api",
+				APIKey:   "your-api-key",
+				DaemonID: "your-daemon-id",
+			},
 		},
 		DynamicServer: &DynamicServerConfig{
-			ServerUUIDMap:              map[string]string{},
+			ServerUUIDMap:              map[string]InstanceConfig{},
 			AutoStartServers:           []string{},
 			StartupTimeoutSeconds:      60,
 			PollIntervalSeconds:        2,
@@ -90,12 +421,36 @@ func defaultConfig() *Config {
 			IdleShutdownSeconds:        60,
 			MsgStarting:                "正在启动服务器 %s，请稍候...",
 			MsgStartupTimeout:          "服务器 %s 启动超时，请稍后重试",
+			MsgPanelUnavailable:        "服务器 %s 的管理面板暂时无法访问，请稍后重试",
+			MsgAlreadyStarting:         "服务器 %s 正在启动中，请稍候...",
+			MsgStopping:                "服务器 %s 正在关闭，请稍后再试",
+			MsgBusy:                    "服务器 %s 正忙，请稍后再试",
 		},
 		Permission: &PermissionConfig{
-			Enabled:         true,
-			CacheTTLSeconds: 300,
-			AdminCommands:   []string{"send", "dserver", "glist", "server", "lb"},
-			MsgNoPermission: "你没有权限执行此命令",
+			Enabled:                true,
+			CacheTTLSeconds:        300,
+			RefreshIntervalSeconds: 60,
+			NegativeCacheSeconds:   60,
+			AdminCommands:          []string{"send", "dserver", "glist", "server", "lb", "rmsgate", "wl", "ipban", "perm", "audit"},
+			MsgNoPermission:        "你没有权限执行此命令",
+			CommandLevels: map[string]int{
+				"elevate list":   4,
+				"elevate revoke": 4,
+			},
+			Nodes:         map[string]int{},
+			OverridesPath: "permission-overrides.json",
+			Elevation: &ElevationConfig{
+				Enabled:         false,
+				MinLevel:        3,
+				Level:           4,
+				MaxSeconds:      3600,
+				CooldownSeconds: 3600,
+				RequireReason:   false,
+			},
+		},
+		Audit: &AuditConfig{
+			Enabled:     true,
+			RecentLimit: 15,
 		},
 		LoadBalancer: &LoadBalancerConfig{
 			Enabled: false,
@@ -141,10 +496,51 @@ func LoadConfig(configDir string, log logr.Logger) *Config {
 		return defaultConfig()
 	}
 
+	// Maintenance can be switched on at any time, so it always needs settings
+	if cfg.Maintenance == nil {
+		cfg.Maintenance = defaultConfig().Maintenance
+	}
+	if cfg.IPRules == nil {
+		cfg.IPRules = defaultConfig().IPRules
+	}
+	if cfg.APIFailover == nil {
+		cfg.APIFailover = defaultConfig().APIFailover
+	}
+	// Auditing stays on for configs written before it existed
+	if cfg.Audit == nil {
+		cfg.Audit = defaultConfig().Audit
+	}
+	// A config without a permission section gets the default rules. An existing section is
+	// kept as written, since leaving a command out of adminCommands is how it is opened.
+	if cfg.Permission == nil {
+		cfg.Permission = defaultConfig().Permission
+	}
+	fillMessages(&cfg, defaultConfig())
+
 	log.Info("Configuration loaded successfully")
 	return &cfg
 }
 
+// fillMessages sets messages that are missing from a config written before they existed.
+// Most are format strings, and an empty one would show players "%!(EXTRA ...)".
+func fillMessages(cfg, def *Config) {
+	defaultMessage(&cfg.MsgTierDenied, def.MsgTierDenied)
+	defaultMessage(&cfg.MsgServerRestricted, def.MsgServerRestricted)
+	defaultMessage(&cfg.Maintenance.MsgUntil, def.Maintenance.MsgUntil)
+	if cfg.DynamicServer != nil {
+		defaultMessage(&cfg.DynamicServer.MsgPanelUnavailable, def.DynamicServer.MsgPanelUnavailable)
+		defaultMessage(&cfg.DynamicServer.MsgAlreadyStarting, def.DynamicServer.MsgAlreadyStarting)
+		defaultMessage(&cfg.DynamicServer.MsgStopping, def.DynamicServer.MsgStopping)
+		defaultMessage(&cfg.DynamicServer.MsgBusy, def.DynamicServer.MsgBusy)
+	}
+}
+
+func defaultMessage(msg *string, def string) {
+	if *msg == "" {
+		*msg = def
+	}
+}
+
 func saveConfig(path string, cfg *Config) error {
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// loadConfig writes content as the config file of a new directory and loads it
+func loadConfig(t *testing.T, content string) *Config {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rms-gate-config.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(dir, logr.Discard())
+}
+
+func TestInstanceConfigJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want InstanceConfig
+		// out is the marshaled form, in when empty
+		out string
+	}{
+		{"uuid string", `"abc123"`, InstanceConfig{UUID: "abc123"}, ""},
+		{"object with panel", `{"uuid":"abc123","panel":"eu"}`, InstanceConfig{UUID: "abc123", Panel: "eu"}, ""},
+		{"object with daemon", `{"uuid":"abc123","daemonId":"node2"}`, InstanceConfig{UUID: "abc123", DaemonID: "node2"}, ""},
+		{"object with both", `{"uuid":"abc123","panel":"eu","daemonId":"node2"}`, InstanceConfig{UUID: "abc123", Panel: "eu", DaemonID: "node2"}, ""},
+		{"plain object collapses to string", `{"uuid":"abc123"}`, InstanceConfig{UUID: "abc123"}, `"abc123"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got InstanceConfig
+			if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+				t.Fatalf("Unmarshal(%s) = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Unmarshal(%s) = %+v, want %+v", tt.in, got, tt.want)
+			}
+
+			out, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.out
+			if want == "" {
+				want = tt.in
+			}
+			if string(out) != want {
+				t.Fatalf("Marshal(%+v) = %s, want %s", got, out, want)
+			}
+		})
+	}
+}
+
+func TestInstanceConfigInvalid(t *testing.T) {
+	for _, in := range []string{`42`, `["abc"]`, `{"uuid":1}`} {
+		var got InstanceConfig
+		if err := json.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("Unmarshal(%s) = %+v, want an error", in, got)
+		}
+	}
+}
+
+func TestServerUUIDMapRoundTrip(t *testing.T) {
+	in := `{"lobby":"uuid-1","survival":{"uuid":"uuid-2","panel":"eu","daemonId":"node2"}}`
+
+	var m map[string]InstanceConfig
+	if err := json.Unmarshal([]byte(in), &m); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Fatalf("round trip = %s, want %s", out, in)
+	}
+}
+
+func TestLoadConfigPermissionDefaults(t *testing.T) {
+	def := defaultConfig().Permission
+
+	tests := []struct {
+		name          string
+		content       string
+		wantAdmin     []string
+		wantLevels    map[string]int
+		wantElevation bool
+	}{
+		{
+			name:          "missing section gets the defaults",
+			content:       `{}`,
+			wantAdmin:     def.AdminCommands,
+			wantLevels:    def.CommandLevels,
+			wantElevation: true,
+		},
+		{
+			name: "configured section kept as written",
+			content: `{"permission": {
+				"enabled": true,
+				"adminCommands": ["send", "wl"],
+				"commandLevels": {"Elevate  List": 2}
+			}}`,
+			wantAdmin:  []string{"send", "wl"},
+			wantLevels: map[string]int{"Elevate  List": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perm := loadConfig(t, tt.content).Permission
+			if perm == nil {
+				t.Fatal("Permission = nil")
+			}
+			if !slices.Equal(perm.AdminCommands, tt.wantAdmin) {
+				t.Fatalf("adminCommands = %v, want %v", perm.AdminCommands, tt.wantAdmin)
+			}
+			if !maps.Equal(perm.CommandLevels, tt.wantLevels) {
+				t.Fatalf("commandLevels = %v, want %v", perm.CommandLevels, tt.wantLevels)
+			}
+			if (perm.Elevation != nil) != tt.wantElevation {
+				t.Fatalf("elevation = %+v, want set %v", perm.Elevation, tt.wantElevation)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMessages(t *testing.T) {
+	def := defaultConfig()
+
+	tests := []struct {
+		name    string
+		content string
+		check   func(cfg *Config) (got, want string)
+	}{
+		{
+			name:    "tier denied filled",
+			content: `{"msgTierDenied": ""}`,
+			check:   func(cfg *Config) (string, string) { return cfg.MsgTierDenied, def.MsgTierDenied },
+		},
+		{
+			name:    "server restricted filled",
+			content: `{}`,
+			check:   func(cfg *Config) (string, string) { return cfg.MsgServerRestricted, def.MsgServerRestricted },
+		},
+		{
+			name:    "panel unavailable filled",
+			content: `{"dynamicServer": {"msgStarting": "Starting %s"}}`,
+			check: func(cfg *Config) (string, string) {
+				return cfg.DynamicServer.MsgPanelUnavailable, def.DynamicServer.MsgPanelUnavailable
+			},
+		},
+		{
+			name:    "server state messages filled",
+			content: `{"dynamicServer": {"msgStarting": "Starting %s"}}`,
+			check: func(cfg *Config) (string, string) {
+				got, want := cfg.DynamicServer, def.DynamicServer
+				return got.MsgAlreadyStarting + got.MsgStopping + got.MsgBusy, want.MsgAlreadyStarting + want.MsgStopping + want.MsgBusy
+			},
+		},
+		{
+			name:    "maintenance until filled",
+			content: `{"maintenance": {"msgDefault": "Down for maintenance"}}`,
+			check:   func(cfg *Config) (string, string) { return cfg.Maintenance.MsgUntil, def.Maintenance.MsgUntil },
+		},
+		{
+			name:    "custom message kept",
+			content: `{"msgTierDenied": "Tier %d needed", "dynamicServer": {"msgStarting": "Starting %s"}}`,
+			check:   func(cfg *Config) (string, string) { return cfg.MsgTierDenied, "Tier %d needed" },
+		},
+		{
+			name:    "custom dynamic server message kept",
+			content: `{"dynamicServer": {"msgStarting": "Starting %s"}}`,
+			check:   func(cfg *Config) (string, string) { return cfg.DynamicServer.MsgStarting, "Starting %s" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := tt.check(loadConfig(t, tt.content)); got != want {
+				t.Fatalf("message = %q, want %q", got, want)
+			}
+		})
+	}
+}
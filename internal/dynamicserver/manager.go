@@ -2,6 +2,8 @@ package dynamicserver
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -10,13 +12,14 @@ import (
 	"github.com/go-logr/logr"
 	"go.minekube.com/gate/pkg/edition/java/proxy"
 
+	"github.com/RMS-Server/RMS-Gate/internal/audit"
 	"github.com/RMS-Server/RMS-Gate/internal/loadbalancer"
 	"github.com/RMS-Server/RMS-Gate/internal/mcsmanager"
 	"github.com/RMS-Server/RMS-Gate/internal/minecraft"
 )
 
 type Config struct {
-	ServerUUIDMap              map[string]string
+	ServerUUIDMap              map[string]mcsmanager.Instance
 	AutoStartServers           []string
 	StartupTimeoutSeconds      int
 	PollIntervalSeconds        int
@@ -24,6 +27,33 @@ type Config struct {
 	IdleShutdownSeconds        int
 	MsgStarting                string
 	MsgStartupTimeout          string
+	MsgPanelUnavailable        string
+}
+
+var (
+	// ErrNotConfigured means the server has no MCSManager instance, or the panel does not know it
+	ErrNotConfigured = errors.New("no MCSManager instance for server")
+	// ErrPanelUnavailable means MCSManager could not be reached or rejected the API key, so
+	// nothing is known about the server itself
+	ErrPanelUnavailable = errors.New("MCSManager unavailable")
+	// ErrStartFailed means the panel refused to start the instance or it did not come up in time
+	ErrStartFailed = errors.New("server failed to start")
+	// ErrServerStopping means the instance is shutting down and cannot be started until it has stopped
+	ErrServerStopping = errors.New("server is stopping")
+	// ErrServerBusy means the panel is running another operation on the instance
+	ErrServerBusy = errors.New("server is busy")
+)
+
+// panelError sorts a client error into ErrNotConfigured, ErrPanelUnavailable or ErrStartFailed
+func panelError(err error) error {
+	switch {
+	case errors.Is(err, mcsmanager.ErrInstanceNotFound), errors.Is(err, mcsmanager.ErrUnknownPanel):
+		return fmt.Errorf("%w: %w", ErrNotConfigured, err)
+	case errors.Is(err, mcsmanager.ErrAPI):
+		return fmt.Errorf("%w: %w", ErrStartFailed, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrPanelUnavailable, err)
+	}
 }
 
 type ShutdownConfig struct {
@@ -58,8 +88,8 @@ func (s *ShutdownConfig) SetEnabled(v bool) {
 }
 
 type startingServer struct {
-	done   chan struct{}
-	result bool
+	done chan struct{}
+	err  error
 }
 
 type Manager struct {
@@ -67,8 +97,9 @@ type Manager struct {
 	cancel context.CancelFunc
 	log    logr.Logger
 	proxy  *proxy.Proxy
-	mcs    *mcsmanager.Client
+	mcs    *mcsmanager.Pool
 	cfg    *Config
+	audit  *audit.Log
 
 	mu              sync.Mutex
 	startingServers map[string]*startingServer
@@ -76,7 +107,7 @@ type Manager struct {
 	serverConfigs   map[string]*ShutdownConfig
 }
 
-func NewManager(ctx context.Context, log logr.Logger, p *proxy.Proxy, mcs *mcsmanager.Client, cfg *Config) *Manager {
+func NewManager(ctx context.Context, log logr.Logger, p *proxy.Proxy, mcs *mcsmanager.Pool, cfg *Config) *Manager {
 	ctx, cancel := context.WithCancel(ctx)
 	m := &Manager{
 		ctx:             ctx,
@@ -90,11 +121,64 @@ func NewManager(ctx context.Context, log logr.Logger, p *proxy.Proxy, mcs *mcsma
 		serverConfigs:   make(map[string]*ShutdownConfig),
 	}
 
-	m.log.Info("DynamicServerManager initialized", "autoStart", cfg.AutoStartServers)
+	m.log.Info("DynamicServerManager initialized", "autoStart", cfg.AutoStartServers, "panels", mcs.Panels())
+	go m.validateInstances()
 	go m.periodicIdleCheck()
 	return m
 }
 
+// validateInstances checks at startup that every mapped instance exists on its panel and
+// daemon, so a typo shows up in the log instead of on the first player's connect
+func (m *Manager) validateInstances() {
+	for _, serverName := range m.cfg.AutoStartServers {
+		if _, ok := m.cfg.ServerUUIDMap[serverName]; !ok {
+			m.log.Error(nil, "Auto-start server has no MCSManager instance configured", "server", serverName)
+		}
+	}
+
+	failed := m.mcs.Validate(m.ctx, m.cfg.ServerUUIDMap)
+	for serverName, err := range failed {
+		inst := m.cfg.ServerUUIDMap[serverName]
+		m.log.Error(err, "MCSManager instance not usable", "server", serverName,
+			"panel", inst.Panel, "daemon", inst.DaemonID, "uuid", inst.UUID)
+	}
+	if len(failed) == 0 {
+		m.log.Info("All MCSManager instances validated", "count", len(m.cfg.ServerUUIDMap))
+	}
+}
+
+// instance returns the client for the server's panel and daemon, and its instance UUID
+func (m *Manager) instance(serverName string) (*mcsmanager.Client, string, error) {
+	inst, ok := m.cfg.ServerUUIDMap[serverName]
+	if !ok {
+		return nil, "", ErrNotConfigured
+	}
+	client, err := m.mcs.Client(inst)
+	if err != nil {
+		return nil, "", panelError(err)
+	}
+	return client, inst.UUID, nil
+}
+
+// SetAuditLog records server starts, stops and shutdown settings in the audit log
+func (m *Manager) SetAuditLog(a *audit.Log) {
+	m.audit = a
+}
+
+// auditChange records an automatic state change of serverName
+func (m *Manager) auditChange(change, serverName string, ok bool, details ...any) {
+	m.auditChangeBy("dynamic-server", change, serverName, ok, details...)
+}
+
+// auditChangeBy records a state change of serverName made by a player or the console
+func (m *Manager) auditChangeBy(actor, change, serverName string, ok bool, details ...any) {
+	outcome := audit.OutcomeOK
+	if !ok {
+		outcome = audit.OutcomeFailed
+	}
+	m.audit.Change(actor, change, outcome, append([]any{"server", serverName}, details...)...)
+}
+
 func (m *Manager) IsAutoStartServer(name string) bool {
 	for _, s := range m.cfg.AutoStartServers {
 		if s == name {
@@ -104,12 +188,16 @@ func (m *Manager) IsAutoStartServer(name string) bool {
 	return false
 }
 
-func (m *Manager) EnsureServerRunning(serverName string) bool {
+// EnsureServerRunning starts the server through MCSManager and waits until it accepts
+// connections. Concurrent calls for the same server share one start. A server that is
+// already starting is only waited for; one that is stopping or busy fails with
+// ErrServerStopping or ErrServerBusy.
+func (m *Manager) EnsureServerRunning(serverName string) error {
 	m.mu.Lock()
 	if s, ok := m.startingServers[serverName]; ok {
 		m.mu.Unlock()
 		<-s.done
-		return s.result
+		return s.err
 	}
 
 	s := &startingServer{done: make(chan struct{})}
@@ -123,66 +211,124 @@ func (m *Manager) EnsureServerRunning(serverName string) bool {
 		m.mu.Unlock()
 	}()
 
-	m.log.Info("Starting server", "server", serverName)
+	client, instanceUUID, err := m.instance(serverName)
+	if err != nil {
+		m.log.Error(err, "No MCSManager instance configured for server", "server", serverName)
+		s.err = err
+		return s.err
+	}
 
-	instanceUUID, ok := m.cfg.ServerUUIDMap[serverName]
-	if !ok {
-		m.log.Error(nil, "No MCSManager UUID configured for server", "server", serverName)
-		s.result = false
-		return false
+	state, err := client.GetInstanceStatus(m.ctx, instanceUUID)
+	if err != nil {
+		m.log.Error(err, "Failed to get instance status", "server", serverName)
+		s.err = panelError(err)
+		return s.err
 	}
 
-	started, err := m.mcs.StartInstance(m.ctx, instanceUUID)
-	if err != nil || !started {
-		m.log.Error(err, "Failed to send start command", "server", serverName)
-		s.result = false
-		return false
+	switch state {
+	case mcsmanager.StateRunning:
+		return nil
+	case mcsmanager.StateStopping:
+		s.err = ErrServerStopping
+		return s.err
+	case mcsmanager.StateBusy:
+		s.err = ErrServerBusy
+		return s.err
+	case mcsmanager.StateStarting:
+		// A start sent now would fail or queue a second one; wait for the one in progress
+		m.log.Info("Server is already starting", "server", serverName)
+	default:
+		m.log.Info("Starting server", "server", serverName, "state", state)
+		if err := client.StartInstance(m.ctx, instanceUUID); err != nil {
+			m.log.Error(err, "Failed to send start command", "server", serverName)
+			m.auditChange("server.start", serverName, false, "error", err)
+			s.err = panelError(err)
+			return s.err
+		}
 	}
 
-	if !m.waitForServerReady(serverName, instanceUUID) {
-		m.log.Error(nil, "Server failed to start", "server", serverName)
-		s.result = false
-		return false
+	if err := m.waitForServerReady(serverName, client, instanceUUID); err != nil {
+		m.log.Error(err, "Server failed to start", "server", serverName)
+		m.auditChange("server.start", serverName, false, "error", err)
+		s.err = err
+		return s.err
 	}
 
 	m.log.Info("Server is now running", "server", serverName)
-	s.result = true
-	return true
+	m.auditChange("server.start", serverName, true)
+	return nil
 }
 
-func (m *Manager) waitForServerReady(serverName, instanceUUID string) bool {
+func (m *Manager) waitForServerReady(serverName string, client *mcsmanager.Client, instanceUUID string) error {
 	pollInterval := time.Duration(m.cfg.PollIntervalSeconds) * time.Second
 	maxAttempts := m.cfg.StartupTimeoutSeconds / m.cfg.PollIntervalSeconds
 
+	// lastErr is set while the panel cannot be reached, so a timeout can be told apart from a slow start
+	var lastErr error
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		select {
 		case <-m.ctx.Done():
-			return false
+			return fmt.Errorf("%w: %w", ErrStartFailed, m.ctx.Err())
 		default:
 		}
 
-		status, err := m.mcs.GetInstanceStatus(m.ctx, instanceUUID)
+		state, err := client.GetInstanceStatus(m.ctx, instanceUUID)
 		if err != nil {
+			if !errors.Is(err, mcsmanager.ErrTransport) {
+				return panelError(err)
+			}
 			m.log.V(1).Info("Status check error, retrying", "server", serverName, "error", err)
+			lastErr = err
 			time.Sleep(pollInterval)
 			continue
 		}
+		lastErr = nil
 
-		m.log.V(1).Info("Server status check", "server", serverName, "attempt", attempt+1, "status", status)
+		m.log.V(1).Info("Server status check", "server", serverName, "attempt", attempt+1, "state", state)
 
-		if status == 3 {
+		switch state {
+		case mcsmanager.StateRunning:
 			m.log.Info("Server process running, checking connectivity", "server", serverName)
-			return m.checkServerConnectivity(serverName)
-		} else if status == 0 || status == 1 || status == 2 {
+			if !m.checkServerConnectivity(serverName) {
+				return fmt.Errorf("%w: not accepting connections", ErrStartFailed)
+			}
+			return nil
+		case mcsmanager.StateStopped, mcsmanager.StateStopping, mcsmanager.StateStarting, mcsmanager.StateBusy:
 			time.Sleep(pollInterval)
-		} else {
-			m.log.Error(nil, "Server entered error state", "server", serverName, "status", status)
-			return false
+		default:
+			m.log.Error(nil, "Server entered error state", "server", serverName, "state", state)
+			return fmt.Errorf("%w: instance state %s", ErrStartFailed, state)
 		}
 	}
 
+	if lastErr != nil {
+		m.log.Error(lastErr, "MCSManager unreachable during startup", "server", serverName)
+		return panelError(lastErr)
+	}
 	m.log.Error(nil, "Server startup timed out", "server", serverName)
-	return false
+	return fmt.Errorf("%w: not ready before timeout", ErrStartFailed)
+}
+
+// ServerState returns the MCSManager state of the server's instance
+func (m *Manager) ServerState(serverName string) (mcsmanager.InstanceState, error) {
+	client, instanceUUID, err := m.instance(serverName)
+	if err != nil {
+		return mcsmanager.StateUnknown, err
+	}
+	state, err := client.GetInstanceStatus(m.ctx, instanceUUID)
+	if err != nil {
+		return state, panelError(err)
+	}
+	return state, nil
+}
+
+// IsReachable reports whether any backend of the server accepts connections right now
+func (m *Manager) IsReachable(serverName string) bool {
+	server := m.proxy.Server(serverName)
+	if server == nil {
+		return false
+	}
+	return m.checkAnyBackendReachable(server, serverName)
 }
 
 func (m *Manager) checkServerConnectivity(serverName string) bool {
@@ -306,9 +452,9 @@ func (m *Manager) scheduleShutdown(serverName string) {
 		return
 	}
 
-	instanceUUID, ok := m.cfg.ServerUUIDMap[serverName]
-	if !ok {
-		m.log.V(1).Info("No UUID configured for server, cannot schedule shutdown", "server", serverName)
+	client, instanceUUID, err := m.instance(serverName)
+	if err != nil {
+		m.log.V(1).Info("No instance configured for server, cannot schedule shutdown", "server", serverName, "error", err)
 		return
 	}
 
@@ -320,9 +466,13 @@ func (m *Manager) scheduleShutdown(serverName string) {
 	}
 	m.mu.Unlock()
 
-	status, err := m.mcs.GetInstanceStatus(m.ctx, instanceUUID)
-	if err != nil || status != 3 {
-		m.log.V(1).Info("Server is not running, skipping idle shutdown schedule", "server", serverName, "status", status)
+	state, err := client.GetInstanceStatus(m.ctx, instanceUUID)
+	if err != nil {
+		m.log.V(1).Info("Cannot check server state, skipping idle shutdown schedule", "server", serverName, "error", err)
+		return
+	}
+	if state != mcsmanager.StateRunning {
+		m.log.V(1).Info("Server is not running, skipping idle shutdown schedule", "server", serverName, "state", state)
 		return
 	}
 
@@ -346,11 +496,17 @@ func (m *Manager) scheduleShutdown(serverName string) {
 
 		m.log.Info("Server idle, sending stop command", "server", serverName, "idleSeconds", m.cfg.IdleShutdownSeconds)
 
-		stopped, err := m.mcs.StopInstance(m.ctx, instanceUUID)
-		if err != nil || !stopped {
+		// Flush the world first; the stop command saves too, but not on every server type
+		if err := client.SendCommand(m.ctx, instanceUUID, "save-all"); err != nil {
+			m.log.V(1).Info("save-all before stop failed, stopping anyway", "server", serverName, "error", err)
+		}
+
+		if err := client.StopInstance(m.ctx, instanceUUID); err != nil {
 			m.log.Error(err, "Failed to stop server", "server", serverName)
+			m.auditChange("server.idle-stop", serverName, false, "error", err)
 		} else {
 			m.log.Info("Successfully stopped server", "server", serverName)
+			m.auditChange("server.idle-stop", serverName, true, "idleSeconds", m.cfg.IdleShutdownSeconds)
 		}
 	})
 
@@ -370,7 +526,7 @@ func (m *Manager) cancelShutdown(serverName string) {
 	}
 }
 
-func (m *Manager) SetShutdownDelay(serverName string, delaySeconds int) {
+func (m *Manager) SetShutdownDelay(serverName string, delaySeconds int, by string) {
 	m.mu.Lock()
 	cfg, ok := m.serverConfigs[serverName]
 	if !ok {
@@ -385,9 +541,10 @@ func (m *Manager) SetShutdownDelay(serverName string, delaySeconds int) {
 	m.cancelShutdown(serverName)
 
 	m.log.Info("Set protection period", "server", serverName, "seconds", delaySeconds)
+	m.auditChangeBy(by, "protection.set", serverName, true, "seconds", delaySeconds)
 }
 
-func (m *Manager) ClearProtectionPeriod(serverName string) {
+func (m *Manager) ClearProtectionPeriod(serverName, by string) {
 	m.mu.Lock()
 	cfg := m.serverConfigs[serverName]
 	m.mu.Unlock()
@@ -395,10 +552,11 @@ func (m *Manager) ClearProtectionPeriod(serverName string) {
 	if cfg != nil {
 		cfg.ClearProtection()
 		m.log.Info("Cleared protection period", "server", serverName)
+		m.auditChangeBy(by, "protection.cleared", serverName, true)
 	}
 }
 
-func (m *Manager) SetAutoShutdownEnabled(serverName string, enabled bool) {
+func (m *Manager) SetAutoShutdownEnabled(serverName string, enabled bool, by string) {
 	m.mu.Lock()
 	cfg, ok := m.serverConfigs[serverName]
 	if !ok {
@@ -413,8 +571,10 @@ func (m *Manager) SetAutoShutdownEnabled(serverName string, enabled bool) {
 		m.cancelShutdown(serverName)
 		cfg.ClearProtection()
 		m.log.Info("Auto-shutdown disabled", "server", serverName)
+		m.auditChangeBy(by, "autoshutdown.disabled", serverName, true)
 	} else {
 		m.log.Info("Auto-shutdown enabled", "server", serverName)
+		m.auditChangeBy(by, "autoshutdown.enabled", serverName, true)
 	}
 }
 
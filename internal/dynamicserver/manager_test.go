@@ -0,0 +1,92 @@
+package dynamicserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/RMS-Server/RMS-Gate/internal/mcsmanager"
+	"github.com/go-logr/logr"
+)
+
+func TestPanelError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"transport", &mcsmanager.Error{Kind: mcsmanager.ErrTransport, Op: "start"}, ErrPanelUnavailable},
+		{"auth", &mcsmanager.Error{Kind: mcsmanager.ErrAuth, Op: "start", StatusCode: 403}, ErrPanelUnavailable},
+		{"refused", &mcsmanager.Error{Kind: mcsmanager.ErrAPI, Op: "start", StatusCode: 500, Message: "instance busy"}, ErrStartFailed},
+		{"unknown instance", &mcsmanager.Error{Kind: mcsmanager.ErrAPI, Op: "status", Err: mcsmanager.ErrInstanceNotFound}, ErrNotConfigured},
+		{"unknown panel", fmt.Errorf("route: %w", mcsmanager.ErrUnknownPanel), ErrNotConfigured},
+		{"other", errors.New("boom"), ErrPanelUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := panelError(tt.err)
+			if !errors.Is(got, tt.want) || !errors.Is(got, tt.err) {
+				t.Fatalf("panelError(%v) = %v, want %v wrapping the cause", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureServerRunningStates(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     mcsmanager.InstanceState
+		wantErr   error
+		wantStart bool
+	}{
+		{"running", mcsmanager.StateRunning, nil, false},
+		// no startup time is allowed, so waiting ends in a timeout
+		{"stopped", mcsmanager.StateStopped, ErrStartFailed, true},
+		{"starting", mcsmanager.StateStarting, ErrStartFailed, false},
+		{"stopping", mcsmanager.StateStopping, ErrServerStopping, false},
+		{"busy", mcsmanager.StateBusy, ErrServerBusy, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var starts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/service/remote_service_instances":
+					data := []map[string]any{{"instanceUuid": "inst", "status": int(tt.state)}}
+					json.NewEncoder(w).Encode(map[string]any{"status": 200, "data": map[string]any{"maxPage": 1, "data": data}})
+				case "/protected_instance/open":
+					starts.Add(1)
+					json.NewEncoder(w).Encode(map[string]any{"status": 200})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			m := &Manager{
+				ctx: t.Context(),
+				log: logr.Discard(),
+				mcs: mcsmanager.NewPool(logr.Discard(), map[string]*mcsmanager.Config{
+					mcsmanager.DefaultPanel: {BaseURL: srv.URL, APIKey: "key", DaemonID: "d0"},
+				}),
+				cfg: &Config{
+					ServerUUIDMap:       map[string]mcsmanager.Instance{"lobby": {UUID: "inst"}},
+					PollIntervalSeconds: 1,
+				},
+				startingServers: make(map[string]*startingServer),
+			}
+
+			err := m.EnsureServerRunning("lobby")
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("EnsureServerRunning = %v, want %v", err, tt.wantErr)
+			}
+			if got := starts.Load() > 0; got != tt.wantStart {
+				t.Fatalf("start sent = %v, want %v", got, tt.wantStart)
+			}
+		})
+	}
+}
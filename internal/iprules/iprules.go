@@ -0,0 +1,316 @@
+package iprules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Ban is a deny rule added with /ipban
+type Ban struct {
+	Prefix    netip.Prefix
+	Reason    string
+	ExpiresAt time.Time
+	AddedBy   string
+	AddedAt   time.Time
+}
+
+func (b *Ban) Expired() bool {
+	return !b.ExpiresAt.IsZero() && time.Now().After(b.ExpiresAt)
+}
+
+// Rule identifies what decided an evaluation
+type Rule int
+
+const (
+	RuleNone Rule = iota
+	RuleConfigDeny
+	RuleBan
+	RuleNotAllowed
+)
+
+type Decision struct {
+	Denied bool
+	Rule   Rule
+	Prefix netip.Prefix
+	Ban    *Ban
+}
+
+// Manager evaluates IPs against the config allow/deny lists and the persisted bans
+type Manager struct {
+	log    logr.Logger
+	mu     sync.RWMutex
+	db     *sql.DB
+	dbPath string
+
+	allow *Trie[struct{}]
+	deny  *Trie[struct{}]
+	bans  *Trie[*Ban]
+	// banList mirrors bans for listing, keyed by prefix string
+	banList map[string]*Ban
+}
+
+// NewManager loads the bans from ipbans.db in dataDir. Invalid CIDRs in allow or deny are logged and skipped.
+func NewManager(log logr.Logger, dataDir string, allow, deny []string) *Manager {
+	m := &Manager{
+		log:     log.WithName("iprules"),
+		dbPath:  filepath.Join(dataDir, "ipbans.db"),
+		allow:   NewTrie[struct{}](),
+		deny:    NewTrie[struct{}](),
+		bans:    NewTrie[*Ban](),
+		banList: make(map[string]*Ban),
+	}
+	m.loadList(m.allow, allow, "allow")
+	m.loadList(m.deny, deny, "deny")
+	m.initDB()
+	m.loadFromDB()
+	return m
+}
+
+func (m *Manager) loadList(trie *Trie[struct{}], cidrs []string, name string) {
+	for _, s := range cidrs {
+		prefix, err := ParsePrefix(s)
+		if err != nil {
+			m.log.Error(err, "Invalid CIDR in IP list, ignoring", "list", name, "cidr", s)
+			continue
+		}
+		trie.Insert(prefix, struct{}{})
+	}
+}
+
+func (m *Manager) initDB() {
+	db, err := sql.Open("sqlite3", m.dbPath)
+	if err != nil {
+		return
+	}
+	m.db = db
+
+	_, _ = db.Exec(`
+		CREATE TABLE IF NOT EXISTS ip_bans (
+			prefix TEXT PRIMARY KEY,
+			reason TEXT NOT NULL DEFAULT '',
+			expires_at INTEGER NOT NULL DEFAULT 0,
+			added_by TEXT NOT NULL DEFAULT '',
+			added_at INTEGER NOT NULL
+		)
+	`)
+}
+
+func (m *Manager) loadFromDB() {
+	if m.db == nil {
+		return
+	}
+
+	rows, err := m.db.Query(`SELECT prefix, reason, expires_at, added_by, added_at FROM ip_bans`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for rows.Next() {
+		var b Ban
+		var prefix string
+		var expiresAt, addedAt int64
+		if err := rows.Scan(&prefix, &b.Reason, &expiresAt, &b.AddedBy, &addedAt); err != nil {
+			continue
+		}
+		p, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			continue
+		}
+		b.Prefix = p
+		b.AddedAt = time.UnixMilli(addedAt)
+		if expiresAt > 0 {
+			b.ExpiresAt = time.UnixMilli(expiresAt)
+		}
+		m.bans.Insert(p, &b)
+		m.banList[p.String()] = &b
+	}
+}
+
+// ParsePrefix accepts a CIDR or a single address, which becomes a /32 or /128
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return normalize(p), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Evaluate checks ip against deny rules first, then the allow list if it is not empty
+func (m *Manager) Evaluate(ip string) Decision {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Decision{}
+	}
+	addr = addr.Unmap()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, prefix, ok := m.deny.Lookup(addr); ok {
+		return Decision{Denied: true, Rule: RuleConfigDeny, Prefix: prefix}
+	}
+	// Expired bans stay in the trie until the next purge, skip them for a shorter active ban
+	active := func(b *Ban) bool { return !b.Expired() }
+	if ban, prefix, ok := m.bans.LookupFunc(addr, active); ok {
+		return Decision{Denied: true, Rule: RuleBan, Prefix: prefix, Ban: ban}
+	}
+	if m.allow.Len() > 0 {
+		if _, _, ok := m.allow.Lookup(addr); !ok {
+			return Decision{Denied: true, Rule: RuleNotAllowed}
+		}
+	}
+	return Decision{}
+}
+
+// Add bans prefix, replacing an existing ban of the same prefix. A zero duration bans permanently.
+func (m *Manager) Add(prefix netip.Prefix, duration time.Duration, reason, addedBy string) (*Ban, error) {
+	prefix = normalize(prefix)
+	ban := &Ban{
+		Prefix:  prefix,
+		Reason:  reason,
+		AddedBy: addedBy,
+		AddedAt: time.Now(),
+	}
+	if duration > 0 {
+		ban.ExpiresAt = ban.AddedAt.Add(duration)
+	}
+
+	var expiresAt int64
+	if !ban.ExpiresAt.IsZero() {
+		expiresAt = ban.ExpiresAt.UnixMilli()
+	}
+
+	if m.db != nil {
+		_, err := m.db.Exec(`
+			INSERT OR REPLACE INTO ip_bans (prefix, reason, expires_at, added_by, added_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, prefix.String(), reason, expiresAt, addedBy, ban.AddedAt.UnixMilli())
+		if err != nil {
+			return nil, fmt.Errorf("save ban: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	m.bans.Insert(prefix, ban)
+	m.banList[prefix.String()] = ban
+	m.mu.Unlock()
+	return ban, nil
+}
+
+// Remove lifts the ban on exactly prefix, returning false if there was none
+func (m *Manager) Remove(prefix netip.Prefix) (bool, error) {
+	prefix = normalize(prefix)
+	key := prefix.String()
+
+	m.mu.RLock()
+	_, exists := m.banList[key]
+	m.mu.RUnlock()
+	if !exists {
+		return false, nil
+	}
+
+	if m.db != nil {
+		if _, err := m.db.Exec(`DELETE FROM ip_bans WHERE prefix = ?`, key); err != nil {
+			return false, err
+		}
+	}
+
+	m.mu.Lock()
+	m.bans.Remove(prefix)
+	delete(m.banList, key)
+	m.mu.Unlock()
+	return true, nil
+}
+
+// List returns the active bans ordered by when they were added
+func (m *Manager) List() []*Ban {
+	m.mu.RLock()
+	result := make([]*Ban, 0, len(m.banList))
+	for _, b := range m.banList {
+		if !b.Expired() {
+			result = append(result, b)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AddedAt.Before(result[j].AddedAt)
+	})
+	return result
+}
+
+// Counts returns the number of allow rules, deny rules and bans
+func (m *Manager) Counts() (allow, deny, bans int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.allow.Len(), m.deny.Len(), m.bans.Len()
+}
+
+// PurgeExpired removes expired bans and returns how many were removed
+func (m *Manager) PurgeExpired() int {
+	m.mu.Lock()
+	var expired int
+	for key, b := range m.banList {
+		if b.Expired() {
+			m.bans.Remove(b.Prefix)
+			delete(m.banList, key)
+			expired++
+		}
+	}
+	m.mu.Unlock()
+
+	if m.db != nil && expired > 0 {
+		_, _ = m.db.Exec(`DELETE FROM ip_bans WHERE expires_at > 0 AND expires_at <= ?`, time.Now().UnixMilli())
+	}
+	return expired
+}
+
+// StartPurgeLoop periodically removes expired bans until ctx is done
+func (m *Manager) StartPurgeLoop(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n := m.PurgeExpired(); n > 0 {
+					m.log.Info("Purged expired IP bans", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// Close closes the database connection
+func (m *Manager) Close() error {
+	if m.db != nil {
+		return m.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package iprules
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		bans  map[string]time.Duration // negative durations are already expired
+		ip    string
+		want  Rule
+		// wantPrefix is the prefix reported for a denial
+		wantPrefix string
+	}{
+		{name: "no rules", ip: "1.2.3.4", want: RuleNone},
+		{name: "config deny", deny: []string{"1.2.0.0/16"}, ip: "1.2.3.4", want: RuleConfigDeny, wantPrefix: "1.2.0.0/16"},
+		{name: "config deny wins over ban", deny: []string{"1.2.0.0/16"}, bans: map[string]time.Duration{"1.2.3.4": 0}, ip: "1.2.3.4", want: RuleConfigDeny, wantPrefix: "1.2.0.0/16"},
+		{name: "permanent ban", bans: map[string]time.Duration{"1.2.3.4": 0}, ip: "1.2.3.4", want: RuleBan, wantPrefix: "1.2.3.4/32"},
+		{name: "active ban", bans: map[string]time.Duration{"1.2.3.0/24": time.Hour}, ip: "1.2.3.4", want: RuleBan, wantPrefix: "1.2.3.0/24"},
+		{name: "expired ban", bans: map[string]time.Duration{"1.2.3.0/24": -time.Hour}, ip: "1.2.3.4", want: RuleNone},
+		{
+			name: "expired ban does not shadow shorter active ban",
+			bans: map[string]time.Duration{"1.2.3.4": -time.Hour, "1.2.0.0/16": time.Hour},
+			ip:   "1.2.3.4", want: RuleBan, wantPrefix: "1.2.0.0/16",
+		},
+		{name: "allow list match", allow: []string{"10.0.0.0/8"}, ip: "10.1.2.3", want: RuleNone},
+		{name: "allow list miss", allow: []string{"10.0.0.0/8"}, ip: "1.2.3.4", want: RuleNotAllowed},
+		{name: "ban wins over allow", allow: []string{"10.0.0.0/8"}, bans: map[string]time.Duration{"10.0.0.1": 0}, ip: "10.0.0.1", want: RuleBan, wantPrefix: "10.0.0.1/32"},
+		{name: "mapped address", deny: []string{"1.2.3.0/24"}, ip: "::ffff:1.2.3.4", want: RuleConfigDeny, wantPrefix: "1.2.3.0/24"},
+		{name: "invalid ip", deny: []string{"0.0.0.0/0"}, ip: "not-an-ip", want: RuleNone},
+		{name: "invalid config cidr skipped", deny: []string{"bogus"}, ip: "1.2.3.4", want: RuleNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(logr.Discard(), t.TempDir(), tt.allow, tt.deny)
+			defer m.Close()
+
+			for cidr, d := range tt.bans {
+				prefix, err := ParsePrefix(cidr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ban, err := m.Add(prefix, max(d, 0), "test", "console")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d < 0 {
+					ban.ExpiresAt = time.Now().Add(d)
+				}
+			}
+
+			got := m.Evaluate(tt.ip)
+			if got.Rule != tt.want || got.Denied != (tt.want != RuleNone) {
+				t.Fatalf("Evaluate(%s) = %+v, want rule %d", tt.ip, got, tt.want)
+			}
+			if tt.wantPrefix != "" && got.Prefix != netip.MustParsePrefix(tt.wantPrefix) {
+				t.Fatalf("Evaluate(%s) prefix = %s, want %s", tt.ip, got.Prefix, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestBansPersist(t *testing.T) {
+	dir := t.TempDir()
+
+	m := NewManager(logr.Discard(), dir, nil, nil)
+	if _, err := m.Add(netip.MustParsePrefix("1.2.3.0/24"), time.Hour, "spam", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Add(netip.MustParsePrefix("5.6.7.8/32"), 0, "", "console"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := m.Remove(netip.MustParsePrefix("5.6.7.8/32")); !ok || err != nil {
+		t.Fatalf("Remove = %v, %v", ok, err)
+	}
+	m.Close()
+
+	m = NewManager(logr.Discard(), dir, nil, nil)
+	defer m.Close()
+
+	bans := m.List()
+	if len(bans) != 1 {
+		t.Fatalf("List() after reload has %d bans, want 1", len(bans))
+	}
+	if b := bans[0]; b.Prefix.String() != "1.2.3.0/24" || b.Reason != "spam" || b.AddedBy != "alice" || b.ExpiresAt.IsZero() {
+		t.Fatalf("reloaded ban = %+v", b)
+	}
+	if got := m.Evaluate("5.6.7.8"); got.Denied {
+		t.Fatalf("removed ban still applies: %+v", got)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	m := NewManager(logr.Discard(), t.TempDir(), nil, nil)
+	defer m.Close()
+
+	expired, err := m.Add(netip.MustParsePrefix("1.2.3.4/32"), time.Hour, "", "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := m.Add(netip.MustParsePrefix("1.2.3.5/32"), 0, "", "console"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := m.PurgeExpired(); n != 1 {
+		t.Fatalf("PurgeExpired() = %d, want 1", n)
+	}
+	if _, _, bans := m.Counts(); bans != 1 {
+		t.Fatalf("bans after purge = %d, want 1", bans)
+	}
+}
@@ -0,0 +1,179 @@
+package iprules
+
+import (
+	"net/netip"
+)
+
+// Trie is a path-compressed binary prefix trie with longest-prefix matching.
+// IPv4 and IPv6 prefixes live in separate trees; IPv4-mapped IPv6 addresses match IPv4 prefixes.
+type Trie[V any] struct {
+	v4   *trieNode[V]
+	v6   *trieNode[V]
+	size int
+}
+
+type trieNode[V any] struct {
+	prefix netip.Prefix
+	child  [2]*trieNode[V]
+	value  V
+	set    bool
+}
+
+func NewTrie[V any]() *Trie[V] {
+	return &Trie[V]{}
+}
+
+func (t *Trie[V]) root(addr netip.Addr) **trieNode[V] {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+// Insert adds or replaces the value for prefix
+func (t *Trie[V]) Insert(prefix netip.Prefix, value V) {
+	prefix = normalize(prefix)
+	leaf := &trieNode[V]{prefix: prefix, value: value, set: true}
+
+	n := t.root(prefix.Addr())
+	for {
+		cur := *n
+		if cur == nil {
+			*n = leaf
+			t.size++
+			return
+		}
+
+		common := commonBits(cur.prefix, prefix)
+		switch {
+		case common == cur.prefix.Bits() && common == prefix.Bits():
+			if !cur.set {
+				t.size++
+			}
+			cur.value, cur.set = value, true
+			return
+		case common == cur.prefix.Bits():
+			// cur covers prefix, descend
+			n = &cur.child[bitAt(prefix.Addr(), common)]
+		case common == prefix.Bits():
+			// prefix covers cur, insert above it
+			leaf.child[bitAt(cur.prefix.Addr(), common)] = cur
+			*n = leaf
+			t.size++
+			return
+		default:
+			// diverging prefixes, split with an empty branch node
+			branch := &trieNode[V]{prefix: netip.PrefixFrom(prefix.Addr(), common).Masked()}
+			branch.child[bitAt(cur.prefix.Addr(), common)] = cur
+			branch.child[bitAt(prefix.Addr(), common)] = leaf
+			*n = branch
+			t.size++
+			return
+		}
+	}
+}
+
+// Remove deletes the exact prefix and reports whether it was present
+func (t *Trie[V]) Remove(prefix netip.Prefix) bool {
+	prefix = normalize(prefix)
+
+	n := t.root(prefix.Addr())
+	for *n != nil {
+		cur := *n
+		if !cur.prefix.Contains(prefix.Addr()) || cur.prefix.Bits() > prefix.Bits() {
+			return false
+		}
+		if cur.prefix.Bits() < prefix.Bits() {
+			n = &cur.child[bitAt(prefix.Addr(), cur.prefix.Bits())]
+			continue
+		}
+		if !cur.set {
+			return false
+		}
+
+		var zero V
+		cur.value, cur.set = zero, false
+		t.size--
+
+		// Drop the node if it no longer separates two subtrees
+		switch {
+		case cur.child[0] == nil && cur.child[1] == nil:
+			*n = nil
+		case cur.child[0] == nil:
+			*n = cur.child[1]
+		case cur.child[1] == nil:
+			*n = cur.child[0]
+		}
+		return true
+	}
+	return false
+}
+
+// Lookup returns the value of the longest prefix containing addr
+func (t *Trie[V]) Lookup(addr netip.Addr) (V, netip.Prefix, bool) {
+	return t.LookupFunc(addr, nil)
+}
+
+// LookupFunc returns the value of the longest prefix containing addr whose value satisfies
+// match, so entries that no longer apply do not shadow shorter covering prefixes
+func (t *Trie[V]) LookupFunc(addr netip.Addr, match func(V) bool) (V, netip.Prefix, bool) {
+	addr = addr.Unmap()
+
+	var best *trieNode[V]
+	n := *t.root(addr)
+	for n != nil && n.prefix.Contains(addr) {
+		if n.set && (match == nil || match(n.value)) {
+			best = n
+		}
+		if n.prefix.Bits() == addr.BitLen() {
+			break
+		}
+		n = n.child[bitAt(addr, n.prefix.Bits())]
+	}
+
+	if best == nil {
+		var zero V
+		return zero, netip.Prefix{}, false
+	}
+	return best.value, best.prefix, true
+}
+
+// Len returns the number of prefixes stored
+func (t *Trie[V]) Len() int {
+	return t.size
+}
+
+func normalize(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr()
+	if addr.Is4In6() && prefix.Bits() >= 96 {
+		return netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96).Masked()
+	}
+	return prefix.Masked()
+}
+
+// bitAt returns bit i of addr, counting from the most significant bit
+func bitAt(addr netip.Addr, i int) int {
+	b := addr.AsSlice()
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits returns the length of the shared leading bits of a and b, capped at the shorter prefix
+func commonBits(a, b netip.Prefix) int {
+	limit := min(a.Bits(), b.Bits())
+	ab, bb := a.Addr().AsSlice(), b.Addr().AsSlice()
+
+	n := 0
+	for i := 0; i < len(ab) && n < limit; i++ {
+		x := ab[i] ^ bb[i]
+		if x == 0 {
+			n += 8
+			continue
+		}
+		for x&0x80 == 0 {
+			n++
+			x <<= 1
+		}
+		break
+	}
+	return min(n, limit)
+}
@@ -0,0 +1,151 @@
+package iprules
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestTrieLookup(t *testing.T) {
+	trie := NewTrie[string]()
+	for _, p := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "192.168.1.1/32", "2001:db8::/32", "2001:db8:1::/48"} {
+		trie.Insert(netip.MustParsePrefix(p), p)
+	}
+
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"10.9.9.9", "10.0.0.0/8"},
+		{"10.1.9.9", "10.1.0.0/16"},
+		{"10.1.2.3", "10.1.2.0/24"},
+		{"192.168.1.1", "192.168.1.1/32"},
+		{"::ffff:10.1.2.3", "10.1.2.0/24"},
+		{"2001:db8:2::1", "2001:db8::/32"},
+		{"2001:db8:1::1", "2001:db8:1::/48"},
+		{"192.168.1.2", ""},
+		{"11.0.0.1", ""},
+		{"2001:db9::1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, prefix, ok := trie.Lookup(netip.MustParseAddr(tt.addr))
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("Lookup(%s) = %s, want no match", tt.addr, prefix)
+				}
+				return
+			}
+			if !ok || got != tt.want || prefix.String() != tt.want {
+				t.Fatalf("Lookup(%s) = %q %s %v, want %q", tt.addr, got, prefix, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrieInsertOrder(t *testing.T) {
+	prefixes := []string{"10.0.0.0/8", "10.128.0.0/9", "10.0.0.0/16", "10.64.0.0/10"}
+	// probes[i] falls in prefixes[i] and none of the longer ones
+	probes := []string{"10.32.0.1", "10.200.0.1", "10.0.5.5", "10.100.0.1"}
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 3, 0, 2}, {2, 0, 3, 1}}
+
+	for _, order := range orders {
+		trie := NewTrie[string]()
+		for _, i := range order {
+			trie.Insert(netip.MustParsePrefix(prefixes[i]), prefixes[i])
+		}
+		if trie.Len() != len(prefixes) {
+			t.Errorf("order %v: Len() = %d, want %d", order, trie.Len(), len(prefixes))
+		}
+		for i, p := range prefixes {
+			if got, _, _ := trie.Lookup(netip.MustParseAddr(probes[i])); got != p {
+				t.Errorf("order %v: Lookup(%s) = %q, want %q", order, probes[i], got, p)
+			}
+		}
+	}
+}
+
+func TestTrieRemove(t *testing.T) {
+	trie := NewTrie[string]()
+	for _, p := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16"} {
+		trie.Insert(netip.MustParsePrefix(p), p)
+	}
+
+	if trie.Remove(netip.MustParsePrefix("10.3.0.0/16")) {
+		t.Fatal("Remove of an absent prefix reported true")
+	}
+	if !trie.Remove(netip.MustParsePrefix("10.1.0.0/16")) {
+		t.Fatal("Remove(10.1.0.0/16) = false")
+	}
+	if trie.Remove(netip.MustParsePrefix("10.1.0.0/16")) {
+		t.Fatal("second Remove(10.1.0.0/16) = true")
+	}
+	if got, _, _ := trie.Lookup(netip.MustParseAddr("10.1.0.1")); got != "10.0.0.0/8" {
+		t.Fatalf("Lookup after Remove = %q, want 10.0.0.0/8", got)
+	}
+	if got, _, _ := trie.Lookup(netip.MustParseAddr("10.2.0.1")); got != "10.2.0.0/16" {
+		t.Fatalf("Lookup of sibling after Remove = %q, want 10.2.0.0/16", got)
+	}
+	if trie.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", trie.Len())
+	}
+}
+
+func TestTrieLookupFunc(t *testing.T) {
+	trie := NewTrie[int]()
+	trie.Insert(netip.MustParsePrefix("10.0.0.0/8"), 1)
+	trie.Insert(netip.MustParsePrefix("10.1.0.0/16"), 2)
+	trie.Insert(netip.MustParsePrefix("10.1.2.0/24"), 3)
+
+	tests := []struct {
+		name  string
+		match func(int) bool
+		want  string
+	}{
+		{"all", func(int) bool { return true }, "10.1.2.0/24"},
+		{"skip longest", func(v int) bool { return v != 3 }, "10.1.0.0/16"},
+		{"only shortest", func(v int) bool { return v == 1 }, "10.0.0.0/8"},
+		{"none", func(int) bool { return false }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, prefix, ok := trie.LookupFunc(netip.MustParseAddr("10.1.2.3"), tt.match)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("LookupFunc = %s, want no match", prefix)
+				}
+				return
+			}
+			if !ok || prefix.String() != tt.want {
+				t.Fatalf("LookupFunc = %s %v, want %s", prefix, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"1.2.3.4", "1.2.3.4/32", false},
+		{" 1.2.3.0/24 ", "1.2.3.0/24", false},
+		{"1.2.3.4/24", "1.2.3.0/24", false},
+		{"::ffff:1.2.3.4", "1.2.3.4/32", false},
+		{"::ffff:1.2.3.0/120", "1.2.3.0/24", false},
+		{"2001:db8::1", "2001:db8::1/128", false},
+		{"not-an-ip", "", true},
+		{"1.2.3.4/33", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePrefix(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePrefix(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Fatalf("ParsePrefix(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"go.minekube.com/gate/pkg/edition/java/proxy"
+
+	"github.com/RMS-Server/RMS-Gate/internal/audit"
 )
 
 type Config struct {
@@ -46,6 +48,7 @@ type LoadBalancer struct {
 	mu      sync.RWMutex
 
 	history *HistoryManager
+	audit   *audit.Log
 	stopCh  chan struct{}
 }
 
@@ -64,6 +67,11 @@ func NewLoadBalancer(ctx context.Context, log logr.Logger, p *proxy.Proxy, cfg *
 	return lb
 }
 
+// SetAuditLog records backend state changes in the audit log
+func (lb *LoadBalancer) SetAuditLog(a *audit.Log) {
+	lb.audit = a
+}
+
 func (lb *LoadBalancer) Start() error {
 	if lb.cfg == nil || !lb.cfg.Enabled {
 		lb.log.Info("Load balancer disabled")
@@ -187,6 +195,8 @@ func (lb *LoadBalancer) checkAllBackends() {
 							"backend", backend.Addr,
 							"failCount", backend.FailCount(),
 							"error", err)
+						lb.audit.Change("load-balancer", "backend.unhealthy", audit.OutcomeOK,
+							"server", server.Name(), "backend", backend.Addr, "error", err)
 					}
 				}
 			} else {
@@ -207,6 +217,8 @@ func (lb *LoadBalancer) checkAllBackends() {
 							"latency", latency,
 							"trust", backend.TrustCoeff(),
 							"requiredSuccesses", lb.cfg.HealthCheck.HealthyAfterSuccesses)
+						lb.audit.Change("load-balancer", "backend.recovered", audit.OutcomeOK,
+							"server", server.Name(), "backend", backend.Addr)
 					}
 				} else {
 					backend.IncreaseTrust()
@@ -232,7 +244,8 @@ func (lb *LoadBalancer) GetAllServers() map[string]*ServerInfo {
 	return result
 }
 
-func (lb *LoadBalancer) DisableBackend(serverName, backendAddr string) bool {
+// DisableBackend disables a backend on request of by, the player or console recorded in the audit log
+func (lb *LoadBalancer) DisableBackend(serverName, backendAddr, by string) bool {
 	server := lb.GetServer(serverName)
 	if server == nil {
 		return false
@@ -241,14 +254,16 @@ func (lb *LoadBalancer) DisableBackend(serverName, backendAddr string) bool {
 	for _, b := range server.Backends() {
 		if b.Addr == backendAddr {
 			b.SetDisabled(true)
-			lb.log.Info("Backend disabled", "server", serverName, "backend", backendAddr)
+			lb.log.Info("Backend disabled", "server", serverName, "backend", backendAddr, "by", by)
+			lb.audit.Change(by, "backend.disabled", audit.OutcomeOK, "server", serverName, "backend", backendAddr)
 			return true
 		}
 	}
 	return false
 }
 
-func (lb *LoadBalancer) EnableBackend(serverName, backendAddr string) bool {
+// EnableBackend enables a backend on request of by, the player or console recorded in the audit log
+func (lb *LoadBalancer) EnableBackend(serverName, backendAddr, by string) bool {
 	server := lb.GetServer(serverName)
 	if server == nil {
 		return false
@@ -257,7 +272,8 @@ func (lb *LoadBalancer) EnableBackend(serverName, backendAddr string) bool {
 	for _, b := range server.Backends() {
 		if b.Addr == backendAddr {
 			b.SetDisabled(false)
-			lb.log.Info("Backend enabled", "server", serverName, "backend", backendAddr)
+			lb.log.Info("Backend enabled", "server", serverName, "backend", backendAddr, "by", by)
+			lb.audit.Change(by, "backend.enabled", audit.OutcomeOK, "server", serverName, "backend", backendAddr)
 			return true
 		}
 	}
@@ -0,0 +1,160 @@
+package login
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.minekube.com/common/minecraft/component"
+	"go.minekube.com/gate/pkg/edition/java/proxy"
+)
+
+// Attempt describes a player trying to log in
+type Attempt struct {
+	Player   proxy.Player
+	Username string
+	UUID     string
+	IP       string
+	// ASN and ASOrg are filled from the ASN database when enabled, 0 if unknown
+	ASN   uint32
+	ASOrg string
+}
+
+// Decision is the outcome of a single check
+type Decision struct {
+	Denied bool
+	// Reason is a short description for logs
+	Reason string
+	// Message is shown to the player when denied
+	Message component.Component
+}
+
+// Allow lets the attempt continue to the next check
+func Allow() Decision {
+	return Decision{}
+}
+
+// Deny rejects the attempt and stops the pipeline
+func Deny(reason string, msg component.Component) Decision {
+	return Decision{Denied: true, Reason: reason, Message: msg}
+}
+
+// LoginCheck is one step of the login pipeline
+type LoginCheck interface {
+	Name() string
+	Check(ctx context.Context, a *Attempt) Decision
+}
+
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context, a *Attempt) Decision
+}
+
+// NewCheck adapts a function to a LoginCheck
+func NewCheck(name string, fn func(ctx context.Context, a *Attempt) Decision) LoginCheck {
+	return &checkFunc{name: name, fn: fn}
+}
+
+func (c *checkFunc) Name() string {
+	return c.name
+}
+
+func (c *checkFunc) Check(ctx context.Context, a *Attempt) Decision {
+	return c.fn(ctx, a)
+}
+
+// Step records what one check decided and how long it took
+type Step struct {
+	Check    string
+	Decision Decision
+	Latency  time.Duration
+}
+
+// Outcome is the result of running the pipeline. DeniedBy is empty when allowed.
+type Outcome struct {
+	Decision Decision
+	DeniedBy string
+	Steps    []Step
+}
+
+// CheckStats summarizes the decisions of one check since startup
+type CheckStats struct {
+	Name       string
+	Passed     int64
+	Denied     int64
+	AvgLatency time.Duration
+}
+
+type stage struct {
+	check   LoginCheck
+	passed  atomic.Int64
+	denied  atomic.Int64
+	totalNs atomic.Int64
+}
+
+// Pipeline runs its checks in order and stops at the first deny
+type Pipeline struct {
+	log    logr.Logger
+	stages []*stage
+}
+
+func NewPipeline(log logr.Logger, checks []LoginCheck) *Pipeline {
+	stages := make([]*stage, len(checks))
+	for i, c := range checks {
+		stages[i] = &stage{check: c}
+	}
+	return &Pipeline{
+		log:    log.WithName("login"),
+		stages: stages,
+	}
+}
+
+// Run evaluates the attempt. Every step is logged at V(1); the rejecting check is logged at info level.
+func (p *Pipeline) Run(ctx context.Context, a *Attempt) Outcome {
+	var outcome Outcome
+	for _, s := range p.stages {
+		start := time.Now()
+		decision := s.check.Check(ctx, a)
+		latency := time.Since(start)
+
+		s.totalNs.Add(int64(latency))
+		outcome.Steps = append(outcome.Steps, Step{Check: s.check.Name(), Decision: decision, Latency: latency})
+		p.log.V(1).Info("Login check finished", "check", s.check.Name(), "username", a.Username,
+			"denied", decision.Denied, "latency", latency)
+
+		if decision.Denied {
+			s.denied.Add(1)
+			outcome.Decision = decision
+			outcome.DeniedBy = s.check.Name()
+			p.log.Info("Login rejected", "check", s.check.Name(), "username", a.Username, "uuid", a.UUID,
+				"reason", decision.Reason, "latency", latency)
+			return outcome
+		}
+		s.passed.Add(1)
+	}
+	return outcome
+}
+
+// Checks returns the names of the checks in evaluation order
+func (p *Pipeline) Checks() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.check.Name()
+	}
+	return names
+}
+
+// Stats returns per-check counters in evaluation order
+func (p *Pipeline) Stats() []CheckStats {
+	stats := make([]CheckStats, len(p.stages))
+	for i, s := range p.stages {
+		passed, denied := s.passed.Load(), s.denied.Load()
+		var avg time.Duration
+		if total := passed + denied; total > 0 {
+			avg = time.Duration(s.totalNs.Load() / total)
+		}
+		stats[i] = CheckStats{Name: s.check.Name(), Passed: passed, Denied: denied, AvgLatency: avg}
+	}
+	return stats
+}
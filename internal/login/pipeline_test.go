@@ -0,0 +1,79 @@
+package login
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"go.minekube.com/common/minecraft/component"
+)
+
+func TestPipelineRun(t *testing.T) {
+	allow := func(ctx context.Context, a *Attempt) Decision { return Allow() }
+	deny := func(reason string) func(ctx context.Context, a *Attempt) Decision {
+		return func(ctx context.Context, a *Attempt) Decision {
+			return Deny(reason, &component.Text{Content: "Denied: " + reason})
+		}
+	}
+
+	tests := []struct {
+		name         string
+		checks       []LoginCheck
+		wantDeniedBy string
+		wantReason   string
+		wantSteps    int
+	}{
+		{"no checks", nil, "", "", 0},
+		{"all pass", []LoginCheck{NewCheck("maintenance", allow), NewCheck("whitelist", allow)}, "", "", 2},
+		{"first deny stops", []LoginCheck{NewCheck("throttle", deny("rate")), NewCheck("whitelist", deny("whitelist"))}, "throttle", "rate", 1},
+		{"later deny", []LoginCheck{NewCheck("throttle", allow), NewCheck("asn", deny("hosting")), NewCheck("whitelist", allow)}, "asn", "hosting", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := NewPipeline(logr.Discard(), tt.checks).Run(t.Context(), &Attempt{Username: "Steve"})
+			if outcome.DeniedBy != tt.wantDeniedBy || outcome.Decision.Reason != tt.wantReason || len(outcome.Steps) != tt.wantSteps {
+				t.Fatalf("Run = denied by %q (%q) after %d steps, want %q (%q) after %d",
+					outcome.DeniedBy, outcome.Decision.Reason, len(outcome.Steps), tt.wantDeniedBy, tt.wantReason, tt.wantSteps)
+			}
+			if outcome.Decision.Denied != (tt.wantDeniedBy != "") {
+				t.Fatalf("Run decision = %+v, want denied %v", outcome.Decision, tt.wantDeniedBy != "")
+			}
+			if tt.wantDeniedBy != "" && outcome.Decision.Message == nil {
+				t.Fatal("a denial carries no message for the player")
+			}
+		})
+	}
+}
+
+func TestPipelineStats(t *testing.T) {
+	p := NewPipeline(logr.Discard(), []LoginCheck{
+		NewCheck("throttle", func(ctx context.Context, a *Attempt) Decision {
+			if a.IP == "1.2.3.4" {
+				return Deny("rate", &component.Text{Content: "Slow down"})
+			}
+			return Allow()
+		}),
+		NewCheck("whitelist", func(ctx context.Context, a *Attempt) Decision { return Allow() }),
+	})
+
+	for _, ip := range []string{"1.2.3.4", "5.6.7.8", "1.2.3.4", "9.9.9.9"} {
+		p.Run(t.Context(), &Attempt{Username: "Steve", IP: ip})
+	}
+
+	want := []CheckStats{
+		{Name: "throttle", Passed: 2, Denied: 2},
+		{Name: "whitelist", Passed: 2},
+	}
+	stats := p.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("Stats() = %+v, want %d checks", stats, len(want))
+	}
+	for i, w := range want {
+		if s := stats[i]; s.Name != w.Name || s.Passed != w.Passed || s.Denied != w.Denied || s.AvgLatency < 0 {
+			t.Fatalf("Stats()[%d] = %+v, want %+v", i, s, w)
+		}
+	}
+	if names := p.Checks(); len(names) != 2 || names[0] != "throttle" || names[1] != "whitelist" {
+		t.Fatalf("Checks() = %v", names)
+	}
+}
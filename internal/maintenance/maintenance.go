@@ -0,0 +1,123 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// State is the persisted maintenance state
+type State struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message"`
+	Until   time.Time `json:"until,omitzero"`
+	SetBy   string    `json:"setBy"`
+	SetAt   time.Time `json:"setAt"`
+}
+
+// Manager keeps the maintenance state and persists it across proxy restarts
+type Manager struct {
+	log  logr.Logger
+	path string
+
+	mu    sync.RWMutex
+	state State
+}
+
+func NewManager(log logr.Logger, dataDir string) *Manager {
+	m := &Manager{
+		log:  log.WithName("maintenance"),
+		path: filepath.Join(dataDir, "maintenance.json"),
+	}
+	m.load()
+	return m
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.log.Error(err, "Failed to read maintenance state", "path", m.path)
+		}
+		return
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		m.log.Error(err, "Failed to parse maintenance state", "path", m.path)
+		return
+	}
+
+	m.state = state
+	if state.Enabled {
+		m.log.Info("Maintenance mode restored", "message", state.Message, "until", state.Until)
+	}
+}
+
+// save must be called with mu held
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0644)
+}
+
+// Enable turns maintenance on. A zero until keeps it on until disabled.
+func (m *Manager) Enable(message string, until time.Time, by string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state = State{
+		Enabled: true,
+		Message: message,
+		Until:   until,
+		SetBy:   by,
+		SetAt:   time.Now(),
+	}
+	m.log.Info("Maintenance mode enabled", "message", message, "until", until, "by", by)
+	return m.save()
+}
+
+// Disable turns maintenance off
+func (m *Manager) Disable(by string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state = State{
+		SetBy: by,
+		SetAt: time.Now(),
+	}
+	m.log.Info("Maintenance mode disabled", "by", by)
+	return m.save()
+}
+
+// State returns the current state, switching maintenance off once its end time has passed
+func (m *Manager) State() State {
+	m.mu.RLock()
+	state := m.state
+	m.mu.RUnlock()
+
+	if state.Enabled && !state.Until.IsZero() && time.Now().After(state.Until) {
+		m.mu.Lock()
+		if m.state.Enabled && m.state.Until.Equal(state.Until) {
+			m.state = State{SetBy: "schedule", SetAt: time.Now()}
+			if err := m.save(); err != nil {
+				m.log.Error(err, "Failed to save maintenance state")
+			}
+			m.log.Info("Maintenance mode ended", "until", state.Until)
+		}
+		state = m.state
+		m.mu.Unlock()
+	}
+	return state
+}
+
+// Active reports whether maintenance mode is currently on
+func (m *Manager) Active() bool {
+	return m.State().Enabled
+}
@@ -0,0 +1,75 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestState(t *testing.T) {
+	tests := []struct {
+		name       string
+		until      time.Duration // 0 for no end time
+		wantActive bool
+		wantSetBy  string
+	}{
+		{"indefinite", 0, true, "Admin"},
+		{"scheduled end ahead", time.Hour, true, "Admin"},
+		{"scheduled end passed", -time.Second, false, "schedule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			m := NewManager(logr.Discard(), dir)
+
+			var until time.Time
+			if tt.until != 0 {
+				until = time.Now().Add(tt.until)
+			}
+			if err := m.Enable("Back soon", until, "Admin"); err != nil {
+				t.Fatal(err)
+			}
+
+			s := m.State()
+			if s.Enabled != tt.wantActive || s.SetBy != tt.wantSetBy || m.Active() != tt.wantActive {
+				t.Fatalf("State() = %+v, want enabled %v set by %s", s, tt.wantActive, tt.wantSetBy)
+			}
+
+			// The state, including a scheduled end, survives a restart
+			if got := NewManager(logr.Discard(), dir).State(); got.Enabled != tt.wantActive {
+				t.Fatalf("State() after restart = %+v, want enabled %v", got, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestDisable(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(logr.Discard(), dir)
+
+	if err := m.Enable("Back soon", time.Time{}, "Admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Disable("Mod"); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.State(); s.Enabled || s.Message != "" || s.SetBy != "Mod" {
+		t.Fatalf("State() after Disable = %+v", s)
+	}
+	if NewManager(logr.Discard(), dir).Active() {
+		t.Fatal("maintenance is active again after a restart")
+	}
+}
+
+func TestCorruptState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "maintenance.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if NewManager(logr.Discard(), dir).Active() {
+		t.Fatal("a corrupt state file turned maintenance on")
+	}
+}
@@ -3,9 +3,13 @@ package mcsmanager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -35,151 +39,332 @@ func NewClient(log logr.Logger, cfg *Config) *Client {
 	}
 }
 
-type instanceListResponse struct {
-	Status int `json:"status"`
-	Data   struct {
-		Data []struct {
-			InstanceUUID string `json:"instanceUuid"`
-			Status       int    `json:"status"`
-		} `json:"data"`
-	} `json:"data"`
+// ForDaemon returns a client for another daemon node of the same panel, sharing the connection
+// pool. An empty daemonID keeps the configured one.
+func (m *Client) ForDaemon(daemonID string) *Client {
+	if daemonID == "" || daemonID == m.daemonID {
+		return m
+	}
+	c := *m
+	c.daemonID = daemonID
+	c.log = m.log.WithValues("daemon", daemonID)
+	return &c
 }
 
-type apiResponse struct {
-	Status int    `json:"status"`
-	Data   any    `json:"data"`
-	Error  string `json:"err"`
-}
+// Error kinds, matched with errors.Is
+var (
+	// ErrTransport means the panel could not be reached or answered with something unreadable
+	ErrTransport = errors.New("transport failure")
+	// ErrAuth means the panel rejected the API key
+	ErrAuth = errors.New("authentication failed")
+	// ErrAPI means the panel understood the request but refused or failed it
+	ErrAPI = errors.New("api error")
 
-func (m *Client) StartInstance(ctx context.Context, instanceUUID string) (bool, error) {
-	url := fmt.Sprintf("%s/protected_instance/open?uuid=%s&daemonId=%s&apikey=%s",
-		m.baseURL, instanceUUID, m.daemonID, m.apiKey)
+	ErrInstanceNotFound = errors.New("instance not found")
+)
 
-	m.log.V(1).Info("Starting instance", "uuid", instanceUUID)
+// Error is returned by every Client method. Kind is ErrTransport, ErrAuth or ErrAPI.
+type Error struct {
+	Kind     error
+	Op       string
+	Instance string
+	// StatusCode is the HTTP status, 0 when no response was received
+	StatusCode int
+	Message    string
+	Err        error
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return false, err
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mcsmanager %s", e.Op)
+	if e.Instance != "" {
+		fmt.Fprintf(&b, " %s", e.Instance)
 	}
-
-	resp, err := m.client.Do(req)
-	if err != nil {
-		return false, err
+	fmt.Fprintf(&b, ": %v", e.Kind)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (HTTP %d)", e.StatusCode)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		m.log.Error(nil, "Failed to start instance", "uuid", instanceUUID, "status", resp.StatusCode)
-		return false, nil
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
 	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return false, err
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
 	}
+	return []error{e.Kind, e.Err}
+}
 
-	var result apiResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return false, err
+// InstanceState is the state MCSManager reports for an instance
+type InstanceState int
+
+const (
+	// StateUnknown is returned together with an error, or for codes this client does not know
+	StateUnknown InstanceState = -2
+	// StateBusy means the daemon is running another operation on the instance, e.g. an update
+	StateBusy     InstanceState = -1
+	StateStopped  InstanceState = 0
+	StateStopping InstanceState = 1
+	StateStarting InstanceState = 2
+	StateRunning  InstanceState = 3
+)
+
+func parseState(code int) InstanceState {
+	switch s := InstanceState(code); s {
+	case StateBusy, StateStopped, StateStopping, StateStarting, StateRunning:
+		return s
 	}
+	return StateUnknown
+}
 
-	if result.Error != "" {
-		m.log.Error(nil, "API error starting instance", "uuid", instanceUUID, "error", result.Error)
-		return false, nil
+func (s InstanceState) String() string {
+	switch s {
+	case StateBusy:
+		return "busy"
+	case StateStopped:
+		return "stopped"
+	case StateStopping:
+		return "stopping"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
 	}
+	return "unknown"
+}
+
+type instanceListResponse struct {
+	MaxPage int `json:"maxPage"`
+	Data    []struct {
+		InstanceUUID string `json:"instanceUuid"`
+		Status       int    `json:"status"`
+	} `json:"data"`
+}
 
-	m.log.Info("Successfully sent start command", "uuid", instanceUUID)
-	return true, nil
+type apiResponse struct {
+	Status int             `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"err"`
 }
 
-func (m *Client) StopInstance(ctx context.Context, instanceUUID string) (bool, error) {
-	url := fmt.Sprintf("%s/protected_instance/stop?uuid=%s&daemonId=%s&apikey=%s",
-		m.baseURL, instanceUUID, m.daemonID, m.apiKey)
+// InstanceDetail is the runtime information of an instance
+type InstanceDetail struct {
+	UUID     string
+	Name     string
+	State    InstanceState
+	Started  int     // number of times the instance was started by the panel
+	CPU      float64 // percent of one core
+	Memory   int64   // resident bytes
+	Uptime   time.Duration
+	Players  int // -1 when the panel could not query the server
+	MaxSlots int
+	Version  string
+}
+
+type instanceDetailResponse struct {
+	InstanceUUID string `json:"instanceUuid"`
+	Started      int    `json:"started"`
+	Status       int    `json:"status"`
+	Config       struct {
+		Nickname string `json:"nickname"`
+	} `json:"config"`
+	Info struct {
+		CurrentPlayers int    `json:"currentPlayers"`
+		MaxPlayers     int    `json:"maxPlayers"`
+		Version        string `json:"version"`
+	} `json:"info"`
+	ProcessInfo struct {
+		CPU     float64 `json:"cpu"`
+		Memory  float64 `json:"memory"`
+		Elapsed float64 `json:"elapsed"` // milliseconds
+	} `json:"processInfo"`
+}
+
+// get calls an API endpoint and decodes the data field of the response into out when it is not nil
+func (m *Client) get(ctx context.Context, op, instanceUUID, path string, params url.Values, out any) error {
+	fail := func(kind error, status int, msg string, err error) error {
+		return &Error{Kind: kind, Op: op, Instance: instanceUUID, StatusCode: status, Message: msg, Err: err}
+	}
 
-	m.log.V(1).Info("Stopping instance", "uuid", instanceUUID)
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("daemonId", m.daemonID)
+	params.Set("apikey", m.apiKey)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.baseURL+path+"?"+params.Encode(), nil)
 	if err != nil {
-		return false, err
+		return fail(ErrTransport, 0, "", err)
 	}
 
 	resp, err := m.client.Do(req)
 	if err != nil {
-		return false, err
+		// The request URL carries the API key, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fail(ErrTransport, 0, "", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		m.log.Error(nil, "Failed to stop instance", "uuid", instanceUUID, "status", resp.StatusCode)
-		return false, nil
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return false, err
+		return fail(ErrTransport, resp.StatusCode, "", err)
 	}
 
 	var result apiResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return false, err
+	jsonErr := json.Unmarshal(body, &result)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
+		result.Status == http.StatusUnauthorized || result.Status == http.StatusForbidden:
+		return fail(ErrAuth, resp.StatusCode, result.message(), nil)
+	case jsonErr != nil:
+		if resp.StatusCode != http.StatusOK {
+			return fail(ErrAPI, resp.StatusCode, "", nil)
+		}
+		return fail(ErrTransport, resp.StatusCode, "invalid response", jsonErr)
+	case resp.StatusCode != http.StatusOK || result.Error != "" || (result.Status != 0 && result.Status != http.StatusOK):
+		return fail(ErrAPI, resp.StatusCode, result.message(), nil)
 	}
 
-	if result.Error != "" {
-		m.log.Error(nil, "API error stopping instance", "uuid", instanceUUID, "error", result.Error)
-		return false, nil
+	if out != nil {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fail(ErrTransport, resp.StatusCode, "invalid response data", err)
+		}
 	}
+	return nil
+}
 
-	m.log.Info("Successfully sent stop command", "uuid", instanceUUID)
-	return true, nil
+// message returns the error text of a failed call, which the panel puts in err or as the data string
+func (r *apiResponse) message() string {
+	if r.Error != "" {
+		return r.Error
+	}
+	var msg string
+	if json.Unmarshal(r.Data, &msg) == nil {
+		return msg
+	}
+	return ""
 }
 
-// GetInstanceStatus returns instance status:
-// 0: stopped, 1: stopping, 2: starting, 3: running
-func (m *Client) GetInstanceStatus(ctx context.Context, instanceUUID string) (int, error) {
-	url := fmt.Sprintf("%s/service/remote_service_instances?daemonId=%s&page=1&page_size=100&apikey=%s",
-		m.baseURL, m.daemonID, m.apiKey)
+// instanceAction runs one of the protected_instance power actions
+func (m *Client) instanceAction(ctx context.Context, action, instanceUUID string) error {
+	m.log.V(1).Info("Sending instance action", "action", action, "uuid", instanceUUID)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	err := m.get(ctx, action, instanceUUID, "/protected_instance/"+action, url.Values{"uuid": {instanceUUID}}, nil)
 	if err != nil {
-		return 2, err
+		m.log.Error(err, "Instance action failed", "action", action, "uuid", instanceUUID)
+		return err
 	}
 
-	resp, err := m.client.Do(req)
-	if err != nil {
-		return 2, err
+	m.log.Info("Successfully sent instance action", "action", action, "uuid", instanceUUID)
+	return nil
+}
+
+func (m *Client) StartInstance(ctx context.Context, instanceUUID string) error {
+	return m.instanceAction(ctx, "open", instanceUUID)
+}
+
+// StopInstance asks the instance to shut down with its configured stop command
+func (m *Client) StopInstance(ctx context.Context, instanceUUID string) error {
+	return m.instanceAction(ctx, "stop", instanceUUID)
+}
+
+func (m *Client) RestartInstance(ctx context.Context, instanceUUID string) error {
+	return m.instanceAction(ctx, "restart", instanceUUID)
+}
+
+// KillInstance terminates the instance process without saving, for servers that no longer respond
+func (m *Client) KillInstance(ctx context.Context, instanceUUID string) error {
+	return m.instanceAction(ctx, "kill", instanceUUID)
+}
+
+// SendCommand writes a line to the instance console, e.g. "save-all"
+func (m *Client) SendCommand(ctx context.Context, instanceUUID, command string) error {
+	m.log.V(1).Info("Sending console command", "uuid", instanceUUID, "command", command)
+
+	params := url.Values{"uuid": {instanceUUID}, "command": {command}}
+	if err := m.get(ctx, "command", instanceUUID, "/protected_instance/command", params, nil); err != nil {
+		m.log.Error(err, "Failed to send console command", "uuid", instanceUUID, "command", command)
+		return err
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		m.log.Error(nil, "Failed to get instance status", "uuid", instanceUUID, "status", resp.StatusCode)
-		return 2, nil
+// GetOutput returns the last lines of the instance console output, all buffered lines when lines <= 0
+func (m *Client) GetOutput(ctx context.Context, instanceUUID string, lines int) ([]string, error) {
+	var output string
+	if err := m.get(ctx, "outputlog", instanceUUID, "/protected_instance/outputlog", url.Values{"uuid": {instanceUUID}}, &output); err != nil {
+		return nil, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 2, err
+	result := strings.Split(strings.TrimRight(strings.ReplaceAll(output, "\r\n", "\n"), "\n"), "\n")
+	if lines > 0 && len(result) > lines {
+		result = result[len(result)-lines:]
 	}
+	return result, nil
+}
 
-	var result instanceListResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 2, err
+// GetInstanceDetail returns the state and resource usage of an instance
+func (m *Client) GetInstanceDetail(ctx context.Context, instanceUUID string) (*InstanceDetail, error) {
+	var data instanceDetailResponse
+	if err := m.get(ctx, "detail", instanceUUID, "/instance", url.Values{"uuid": {instanceUUID}}, &data); err != nil {
+		return nil, err
 	}
 
-	for _, inst := range result.Data.Data {
-		if inst.InstanceUUID == instanceUUID {
-			m.log.V(1).Info("Instance status", "uuid", instanceUUID, "status", inst.Status)
-			return inst.Status, nil
+	return &InstanceDetail{
+		UUID:     data.InstanceUUID,
+		Name:     data.Config.Nickname,
+		State:    parseState(data.Status),
+		Started:  data.Started,
+		CPU:      data.ProcessInfo.CPU,
+		Memory:   int64(data.ProcessInfo.Memory),
+		Uptime:   time.Duration(data.ProcessInfo.Elapsed) * time.Millisecond,
+		Players:  data.Info.CurrentPlayers,
+		MaxSlots: data.Info.MaxPlayers,
+		Version:  data.Info.Version,
+	}, nil
+}
+
+// GetInstanceStatus returns the state of an instance. When the panel cannot tell, the state
+// is StateUnknown and the error says why; callers must not read it as a starting server.
+func (m *Client) GetInstanceStatus(ctx context.Context, instanceUUID string) (InstanceState, error) {
+	for page := 1; ; page++ {
+		params := url.Values{"page": {strconv.Itoa(page)}, "page_size": {"100"}}
+
+		var result instanceListResponse
+		if err := m.get(ctx, "status", instanceUUID, "/service/remote_service_instances", params, &result); err != nil {
+			m.log.Error(err, "Failed to get instance status", "uuid", instanceUUID)
+			return StateUnknown, err
+		}
+
+		for _, inst := range result.Data {
+			if inst.InstanceUUID == instanceUUID {
+				state := parseState(inst.Status)
+				m.log.V(1).Info("Instance status", "uuid", instanceUUID, "state", state, "code", inst.Status)
+				return state, nil
+			}
+		}
+		if page >= result.MaxPage {
+			break
 		}
 	}
 
 	m.log.Info("Instance not found in list", "uuid", instanceUUID)
-	return 2, nil
+	return StateUnknown, &Error{Kind: ErrAPI, Op: "status", Instance: instanceUUID, Err: ErrInstanceNotFound}
 }
 
 func (m *Client) IsInstanceRunning(ctx context.Context, instanceUUID string) (bool, error) {
-	status, err := m.GetInstanceStatus(ctx, instanceUUID)
+	state, err := m.GetInstanceStatus(ctx, instanceUUID)
 	if err != nil {
 		return false, err
 	}
-	return status == 3, nil
+	return state == StateRunning, nil
 }
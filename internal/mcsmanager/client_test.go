@@ -0,0 +1,244 @@
+package mcsmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const testAPIKey = "key"
+
+// newTestPanel fakes the MCSManager API for instances with the given status codes.
+// The instance list is served two entries per page.
+func newTestPanel(t *testing.T, statuses map[string]int) *httptest.Server {
+	uuids := make([]string, 0, len(statuses))
+	for uuid := range statuses {
+		uuids = append(uuids, uuid)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("apikey") != testAPIKey {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"status": 403, "data": "wrong api key"})
+			return
+		}
+
+		switch r.URL.Path {
+		case "/service/remote_service_instances":
+			page, _ := strconv.Atoi(q.Get("page"))
+			start, end := (page-1)*2, min(page*2, len(uuids))
+			var data []map[string]any
+			for _, uuid := range uuids[start:end] {
+				data = append(data, map[string]any{"instanceUuid": uuid, "status": statuses[uuid]})
+			}
+			maxPage := (len(uuids) + 1) / 2
+			json.NewEncoder(w).Encode(map[string]any{"status": 200, "data": map[string]any{"maxPage": maxPage, "data": data}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// serveJSON answers every request with status and body and records the last request URL
+func serveJSON(t *testing.T, status int, body string, last *atomic.Pointer[url.URL]) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if last != nil {
+			last.Store(r.URL)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return NewClient(logr.Discard(), &Config{BaseURL: srv.URL, APIKey: testAPIKey, DaemonID: "d0"})
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantKind    error
+		wantMessage string
+	}{
+		{"ok", 200, `{"status":200,"data":true}`, nil, ""},
+		{"http forbidden", 403, `{"status":403,"data":"wrong key"}`, ErrAuth, "wrong key"},
+		{"status unauthorized in body", 200, `{"status":401,"err":"no session"}`, ErrAuth, "no session"},
+		{"api error", 500, `{"status":500,"data":"instance is busy"}`, ErrAPI, "instance is busy"},
+		{"err field", 200, `{"status":200,"err":"bad uuid"}`, ErrAPI, "bad uuid"},
+		{"error page", 502, `<html>bad gateway</html>`, ErrAPI, ""},
+		{"garbage", 200, `not json`, ErrTransport, "invalid response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := serveJSON(t, tt.status, tt.body, nil)
+			err := c.StartInstance(context.Background(), "inst")
+			if tt.wantKind == nil {
+				if err != nil {
+					t.Fatalf("StartInstance = %v", err)
+				}
+				return
+			}
+
+			var e *Error
+			if !errors.As(err, &e) || !errors.Is(err, tt.wantKind) {
+				t.Fatalf("StartInstance = %v, want %v", err, tt.wantKind)
+			}
+			if e.Op != "open" || e.Instance != "inst" || e.StatusCode != tt.status || e.Message != tt.wantMessage {
+				t.Fatalf("error = %+v, want op open, instance inst, status %d, message %q", e, tt.status, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestClientTransportError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	c := NewClient(logr.Discard(), &Config{BaseURL: srv.URL, APIKey: "secret-key", DaemonID: "d0"})
+
+	err := c.StopInstance(context.Background(), "inst")
+	if !errors.Is(err, ErrTransport) {
+		t.Fatalf("StopInstance = %v, want ErrTransport", err)
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Fatalf("error leaks the API key: %v", err)
+	}
+}
+
+func TestInstanceActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       func(c *Client) error
+		wantPath   string
+		wantCmd    string
+		wantDaemon string
+	}{
+		{"start", func(c *Client) error { return c.StartInstance(context.Background(), "inst") }, "/protected_instance/open", "", "d0"},
+		{"stop", func(c *Client) error { return c.StopInstance(context.Background(), "inst") }, "/protected_instance/stop", "", "d0"},
+		{"restart", func(c *Client) error { return c.RestartInstance(context.Background(), "inst") }, "/protected_instance/restart", "", "d0"},
+		{"kill", func(c *Client) error { return c.KillInstance(context.Background(), "inst") }, "/protected_instance/kill", "", "d0"},
+		{"command", func(c *Client) error { return c.SendCommand(context.Background(), "inst", "save-all") }, "/protected_instance/command", "save-all", "d0"},
+		{"other daemon", func(c *Client) error { return c.ForDaemon("d1").StartInstance(context.Background(), "inst") }, "/protected_instance/open", "", "d1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last atomic.Pointer[url.URL]
+			c := serveJSON(t, 200, `{"status":200,"data":true}`, &last)
+			if err := tt.call(c); err != nil {
+				t.Fatal(err)
+			}
+
+			u := last.Load()
+			q := u.Query()
+			if u.Path != tt.wantPath || q.Get("uuid") != "inst" || q.Get("daemonId") != tt.wantDaemon ||
+				q.Get("apikey") != testAPIKey || q.Get("command") != tt.wantCmd {
+				t.Fatalf("request = %s, want %s for inst on %s", u, tt.wantPath, tt.wantDaemon)
+			}
+		})
+	}
+}
+
+func TestGetOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		lines  int
+		want   []string
+	}{
+		{"all lines", "a\nb\nc\n", 0, []string{"a", "b", "c"}},
+		{"last lines", "a\r\nb\r\nc\r\n", 2, []string{"b", "c"}},
+		{"fewer than asked", "a\n", 5, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]any{"status": 200, "data": tt.output})
+			c := serveJSON(t, 200, string(body), nil)
+
+			got, err := c.GetOutput(context.Background(), "inst", tt.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("GetOutput = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetInstanceDetail(t *testing.T) {
+	c := serveJSON(t, 200, `{"status":200,"data":{
+		"instanceUuid":"inst","started":4,"status":3,
+		"config":{"nickname":"Survival"},
+		"info":{"currentPlayers":7,"maxPlayers":20,"version":"1.21.1"},
+		"processInfo":{"cpu":12.5,"memory":2147483648,"elapsed":90000}
+	}}`, nil)
+
+	got, err := c.GetInstanceDetail(context.Background(), "inst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InstanceDetail{
+		UUID: "inst", Name: "Survival", State: StateRunning, Started: 4, CPU: 12.5, Memory: 2 << 30,
+		Uptime: 90 * time.Second, Players: 7, MaxSlots: 20, Version: "1.21.1",
+	}
+	if *got != want {
+		t.Fatalf("GetInstanceDetail = %+v, want %+v", *got, want)
+	}
+}
+
+func TestGetInstanceStatus(t *testing.T) {
+	panel := newTestPanel(t, map[string]int{
+		"busy": -1, "stopped": 0, "stopping": 1, "starting": 2, "running": 3, "odd": 7,
+	})
+	c := NewClient(logr.Discard(), &Config{BaseURL: panel.URL, APIKey: testAPIKey, DaemonID: "d0"})
+
+	tests := []struct {
+		uuid        string
+		want        InstanceState
+		wantRunning bool
+		wantErr     error
+	}{
+		{"busy", StateBusy, false, nil},
+		{"stopped", StateStopped, false, nil},
+		{"stopping", StateStopping, false, nil},
+		{"starting", StateStarting, false, nil},
+		{"running", StateRunning, true, nil},
+		{"odd", StateUnknown, false, nil},
+		{"missing", StateUnknown, false, ErrInstanceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uuid, func(t *testing.T) {
+			got, err := c.GetInstanceStatus(context.Background(), tt.uuid)
+			if got != tt.want || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("GetInstanceStatus(%s) = %v, %v, want %v, %v", tt.uuid, got, err, tt.want, tt.wantErr)
+			}
+			running, err := c.IsInstanceRunning(context.Background(), tt.uuid)
+			if running != tt.wantRunning || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("IsInstanceRunning(%s) = %v, %v", tt.uuid, running, err)
+			}
+		})
+	}
+}
+
+func TestGetInstanceStatusAuth(t *testing.T) {
+	panel := newTestPanel(t, map[string]int{"running": 3})
+	c := NewClient(logr.Discard(), &Config{BaseURL: panel.URL, APIKey: "wrong", DaemonID: "d0"})
+
+	// A rejected key must not look like a stopped or starting server
+	got, err := c.GetInstanceStatus(context.Background(), "running")
+	if got != StateUnknown || !errors.Is(err, ErrAuth) {
+		t.Fatalf("GetInstanceStatus = %v, %v, want unknown with ErrAuth", got, err)
+	}
+}
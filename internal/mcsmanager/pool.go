@@ -0,0 +1,78 @@
+package mcsmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultPanel is the name of the panel set by the top-level mcsManager settings
+const DefaultPanel = "default"
+
+var ErrUnknownPanel = errors.New("unknown MCSManager panel")
+
+// Instance locates an instance on a panel and daemon node
+type Instance struct {
+	// Panel is the panel name, DefaultPanel when empty
+	Panel string
+	// DaemonID is the daemon node, the panel's configured daemon when empty
+	DaemonID string
+	UUID     string
+}
+
+// Pool holds one client per panel
+type Pool struct {
+	log    logr.Logger
+	panels map[string]*Client
+}
+
+func NewPool(log logr.Logger, panels map[string]*Config) *Pool {
+	p := &Pool{
+		log:    log.WithName("mcsmanager"),
+		panels: make(map[string]*Client, len(panels)),
+	}
+	for name, cfg := range panels {
+		if cfg == nil || cfg.BaseURL == "" {
+			continue
+		}
+		p.panels[strings.ToLower(name)] = NewClient(log.WithValues("panel", name), cfg)
+	}
+	return p
+}
+
+// Client returns the client for the instance's panel and daemon
+func (p *Pool) Client(inst Instance) (*Client, error) {
+	panel := inst.Panel
+	if panel == "" {
+		panel = DefaultPanel
+	}
+	client, ok := p.panels[strings.ToLower(panel)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPanel, panel)
+	}
+	return client.ForDaemon(inst.DaemonID), nil
+}
+
+// Panels returns the number of configured panels
+func (p *Pool) Panels() int {
+	return len(p.panels)
+}
+
+// Validate looks up every instance on its panel and returns the error of each one that is
+// missing, on an unknown panel or could not be checked, keyed by server name
+func (p *Pool) Validate(ctx context.Context, instances map[string]Instance) map[string]error {
+	failed := make(map[string]error)
+	for server, inst := range instances {
+		client, err := p.Client(inst)
+		if err == nil {
+			_, err = client.GetInstanceStatus(ctx, inst.UUID)
+		}
+		if err != nil {
+			failed[server] = err
+		}
+	}
+	return failed
+}
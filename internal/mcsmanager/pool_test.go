@@ -0,0 +1,89 @@
+package mcsmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestPoolClient(t *testing.T) {
+	p := NewPool(logr.Discard(), map[string]*Config{
+		DefaultPanel: {BaseURL: "http://main", DaemonID: "d0"},
+		"EU":         {BaseURL: "http://eu", DaemonID: "eu0"},
+		"unset":      {DaemonID: "x"},
+		"nil":        nil,
+	})
+	if p.Panels() != 2 {
+		t.Fatalf("Panels() = %d, want 2 without the panels missing a URL", p.Panels())
+	}
+
+	tests := []struct {
+		name       string
+		inst       Instance
+		wantURL    string
+		wantDaemon string
+		wantErr    error
+	}{
+		{"default panel", Instance{UUID: "a"}, "http://main", "d0", nil},
+		{"named default", Instance{Panel: "Default", UUID: "a"}, "http://main", "d0", nil},
+		{"panel name case", Instance{Panel: "eu", UUID: "a"}, "http://eu", "eu0", nil},
+		{"other daemon", Instance{Panel: "eu", DaemonID: "eu1", UUID: "a"}, "http://eu", "eu1", nil},
+		{"unknown panel", Instance{Panel: "us", UUID: "a"}, "", "", ErrUnknownPanel},
+		{"panel without url", Instance{Panel: "unset", UUID: "a"}, "", "", ErrUnknownPanel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := p.Client(tt.inst)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Client(%+v) = %v, want %v", tt.inst, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.baseURL != tt.wantURL || c.daemonID != tt.wantDaemon {
+				t.Fatalf("Client(%+v) = %s daemon %s, want %s daemon %s", tt.inst, c.baseURL, c.daemonID, tt.wantURL, tt.wantDaemon)
+			}
+		})
+	}
+}
+
+func TestForDaemonSharesClient(t *testing.T) {
+	c := NewClient(logr.Discard(), &Config{BaseURL: "http://main", DaemonID: "d0"})
+
+	if c.ForDaemon("") != c || c.ForDaemon("d0") != c {
+		t.Fatal("ForDaemon with the configured daemon returned a copy")
+	}
+	other := c.ForDaemon("d1")
+	if other == c || other.daemonID != "d1" || c.daemonID != "d0" {
+		t.Fatalf("ForDaemon(d1) = daemon %s, original %s", other.daemonID, c.daemonID)
+	}
+	if other.client != c.client {
+		t.Fatal("ForDaemon does not share the HTTP client")
+	}
+}
+
+func TestPoolValidate(t *testing.T) {
+	panel := newTestPanel(t, map[string]int{"running": 3, "stopped": 0})
+	p := NewPool(logr.Discard(), map[string]*Config{
+		DefaultPanel: {BaseURL: panel.URL, APIKey: testAPIKey, DaemonID: "d0"},
+	})
+
+	failed := p.Validate(context.Background(), map[string]Instance{
+		"lobby":    {UUID: "running"},
+		"survival": {UUID: "stopped"},
+		"creative": {UUID: "missing"},
+		"eu":       {Panel: "eu", UUID: "running"},
+	})
+
+	if len(failed) != 2 {
+		t.Fatalf("Validate() = %v, want creative and eu", failed)
+	}
+	if !errors.Is(failed["creative"], ErrInstanceNotFound) {
+		t.Errorf("creative: %v, want ErrInstanceNotFound", failed["creative"])
+	}
+	if !errors.Is(failed["eu"], ErrUnknownPanel) {
+		t.Errorf("eu: %v, want ErrUnknownPanel", failed["eu"])
+	}
+}
@@ -0,0 +1,195 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RMS-Server/RMS-Gate/internal/audit"
+)
+
+var (
+	ErrElevationDisabled   = errors.New("elevation is disabled")
+	ErrElevationNotAllowed = errors.New("base level too low to elevate")
+	ErrElevationTooLong    = errors.New("elevation duration exceeds the maximum")
+	ErrReasonRequired      = errors.New("a reason is required to elevate")
+	ErrAlreadyElevated     = errors.New("already elevated")
+	ErrElevationCooldown   = errors.New("elevated too recently")
+)
+
+// ElevationConfig lets trusted players raise their own level for a bounded time
+type ElevationConfig struct {
+	Enabled bool
+	// MinLevel is the base level (API and overrides) a player needs to elevate
+	MinLevel int
+	// Level is the level held while elevated
+	Level int
+	// MaxDuration caps a single elevation
+	MaxDuration time.Duration
+	// Cooldown is how long after an elevation ends before the player may elevate again,
+	// so back-to-back elevations cannot stretch MaxDuration
+	Cooldown      time.Duration
+	RequireReason bool
+}
+
+// Elevation is an active temporary level raise
+type Elevation struct {
+	UUID      string
+	Username  string
+	Level     int
+	Reason    string
+	GrantedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (e *Elevation) Expired() bool {
+	return time.Now().After(e.ExpiresAt)
+}
+
+type elevations struct {
+	mu     sync.Mutex
+	active map[string]*Elevation // player key -> elevation
+	ended  map[string]time.Time  // player key -> end of the last elevation
+}
+
+// allow reports why the player may not elevate at now, if anything: an elevation is still
+// active, or the last one ended less than cooldown ago. The caller holds mu.
+func (el *elevations) allow(key string, now time.Time, cooldown time.Duration) error {
+	ended := el.ended[key]
+	if e, ok := el.active[key]; ok {
+		if now.Before(e.ExpiresAt) {
+			return ErrAlreadyElevated
+		}
+		ended = e.ExpiresAt
+	}
+	if now.Before(ended.Add(cooldown)) {
+		return ErrElevationCooldown
+	}
+	return nil
+}
+
+// Elevate raises the player to the configured level for duration. Elevations are kept in
+// memory only, so a proxy restart revokes them as well. A player cannot elevate again while
+// elevated or within the cooldown after the last elevation ended.
+func (p *Manager) Elevate(ctx context.Context, uuid, username string, duration time.Duration, reason string) (*Elevation, error) {
+	cfg := p.elevationCfg
+	switch {
+	case cfg == nil || !cfg.Enabled:
+		return nil, ErrElevationDisabled
+	case duration <= 0 || (cfg.MaxDuration > 0 && duration > cfg.MaxDuration):
+		return nil, ErrElevationTooLong
+	case cfg.RequireReason && strings.TrimSpace(reason) == "":
+		return nil, ErrReasonRequired
+	}
+	if p.BaseLevel(ctx, uuid, username) < cfg.MinLevel {
+		return nil, ErrElevationNotAllowed
+	}
+
+	now := time.Now()
+	e := &Elevation{
+		UUID:      uuid,
+		Username:  username,
+		Level:     cfg.Level,
+		Reason:    reason,
+		GrantedAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+
+	key := playerKey(uuid, username)
+	p.elevated.mu.Lock()
+	if err := p.elevated.allow(key, now, cfg.Cooldown); err != nil {
+		p.elevated.mu.Unlock()
+		return nil, err
+	}
+	p.elevated.active[key] = e
+	p.elevated.mu.Unlock()
+
+	p.log.Info("Player elevated", "player", username, "level", e.Level, "until", e.ExpiresAt, "reason", reason)
+	p.audit.Change(username, "elevation.granted", audit.OutcomeOK,
+		"level", e.Level, "seconds", int(duration.Seconds()), "reason", reason)
+	return e, nil
+}
+
+// RevokeElevation ends the elevation of the player with the given name before it expires
+func (p *Manager) RevokeElevation(username, by string) bool {
+	p.elevated.mu.Lock()
+	var revoked *Elevation
+	for key, e := range p.elevated.active {
+		if strings.EqualFold(e.Username, username) {
+			revoked = e
+			delete(p.elevated.active, key)
+			p.elevated.ended[key] = time.Now()
+			if e.Expired() {
+				p.elevated.ended[key] = e.ExpiresAt
+			}
+			break
+		}
+	}
+	p.elevated.mu.Unlock()
+
+	if revoked == nil || revoked.Expired() {
+		return false
+	}
+	p.log.Info("Elevation revoked", "player", revoked.Username, "by", by)
+	p.audit.Change(by, "elevation.revoked", audit.OutcomeOK, "player", revoked.Username)
+	return true
+}
+
+// Elevations returns the active elevations, soonest to expire first
+func (p *Manager) Elevations() []Elevation {
+	p.elevated.mu.Lock()
+	defer p.elevated.mu.Unlock()
+
+	result := make([]Elevation, 0, len(p.elevated.active))
+	for _, e := range p.elevated.active {
+		if !e.Expired() {
+			result = append(result, *e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ExpiresAt.Before(result[j].ExpiresAt)
+	})
+	return result
+}
+
+// elevationLevel returns the level of the player's active elevation, 0 if none
+func (p *Manager) elevationLevel(uuid, username string) int {
+	p.elevated.mu.Lock()
+	defer p.elevated.mu.Unlock()
+
+	if e, ok := p.elevated.active[playerKey(uuid, username)]; ok && !e.Expired() {
+		return e.Level
+	}
+	return 0
+}
+
+// expireElevations drops elapsed elevations so their end is logged and audited
+func (p *Manager) expireElevations() {
+	p.elevated.mu.Lock()
+	var expired []*Elevation
+	for key, e := range p.elevated.active {
+		if e.Expired() {
+			expired = append(expired, e)
+			delete(p.elevated.active, key)
+			p.elevated.ended[key] = e.ExpiresAt
+		}
+	}
+	var cooldown time.Duration
+	if p.elevationCfg != nil {
+		cooldown = p.elevationCfg.Cooldown
+	}
+	for key, ended := range p.elevated.ended {
+		if time.Since(ended) >= cooldown {
+			delete(p.elevated.ended, key)
+		}
+	}
+	p.elevated.mu.Unlock()
+
+	for _, e := range expired {
+		p.log.Info("Elevation expired", "player", e.Username)
+		p.audit.Change(e.Username, "elevation.expired", audit.OutcomeOK, "level", e.Level)
+	}
+}
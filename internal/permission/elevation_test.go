@@ -0,0 +1,157 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestElevate(t *testing.T) {
+	enabled := &ElevationConfig{Enabled: true, MinLevel: 2, Level: LevelAdmin + 1, MaxDuration: time.Hour, RequireReason: true}
+
+	tests := []struct {
+		name     string
+		cfg      *ElevationConfig
+		user     string
+		duration time.Duration
+		reason   string
+		wantErr  error
+	}{
+		{"elevated", enabled, "Mod", 10 * time.Minute, "fix lag", nil},
+		{"disabled", nil, "Mod", 10 * time.Minute, "fix lag", ErrElevationDisabled},
+		{"turned off", &ElevationConfig{Level: 4}, "Mod", 10 * time.Minute, "fix lag", ErrElevationDisabled},
+		{"base level too low", enabled, "Player", 10 * time.Minute, "fix lag", ErrElevationNotAllowed},
+		{"too long", enabled, "Mod", 2 * time.Hour, "fix lag", ErrElevationTooLong},
+		{"zero duration", enabled, "Mod", 0, "fix lag", ErrElevationTooLong},
+		{"reason required", enabled, "Mod", 10 * time.Minute, "  ", ErrReasonRequired},
+		{"reason optional", &ElevationConfig{Enabled: true, Level: 4}, "Player", 24 * time.Hour, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newPermissionAPI(t, testUser{Username: "Mod", Level: 2}, testUser{Username: "Player", Level: 0})
+			m := newTestManager(t, api, Config{Elevation: tt.cfg})
+			ctx := context.Background()
+			base := m.BaseLevel(ctx, "", tt.user)
+
+			e, err := m.Elevate(ctx, "", tt.user, tt.duration, tt.reason)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Elevate = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if got := m.GetPermissionLevel(ctx, "", tt.user); got != base {
+					t.Fatalf("level after failed elevation = %d, want %d", got, base)
+				}
+				return
+			}
+
+			if got := m.GetPermissionLevel(ctx, "", tt.user); got != e.Level {
+				t.Fatalf("GetPermissionLevel while elevated = %d, want %d", got, e.Level)
+			}
+			if got := m.BaseLevel(ctx, "", tt.user); got != base {
+				t.Fatalf("BaseLevel while elevated = %d, want %d", got, base)
+			}
+		})
+	}
+}
+
+func TestElevationDoesNotStack(t *testing.T) {
+	api := newPermissionAPI(t, testUser{Username: "Mod", Level: 2})
+	m := newTestManager(t, api, Config{Elevation: &ElevationConfig{Enabled: true, MinLevel: 2, Level: 4}})
+	ctx := context.Background()
+
+	if _, err := m.Elevate(ctx, "", "Mod", time.Hour, ""); err != nil {
+		t.Fatal(err)
+	}
+	// The elevated level must not count as the base level of the next elevation
+	m.elevationCfg = &ElevationConfig{Enabled: true, MinLevel: 4, Level: 10}
+	if _, err := m.Elevate(ctx, "", "Mod", time.Hour, ""); !errors.Is(err, ErrElevationNotAllowed) {
+		t.Fatalf("elevating from an elevated level = %v, want ErrElevationNotAllowed", err)
+	}
+}
+
+func TestRevokeAndExpireElevation(t *testing.T) {
+	api := newPermissionAPI(t, testUser{Username: "Mod", Level: 2}, testUser{Username: "Helper", Level: 2})
+	m := newTestManager(t, api, Config{Elevation: &ElevationConfig{Enabled: true, Level: 4}})
+	ctx := context.Background()
+
+	if _, err := m.Elevate(ctx, "", "Mod", time.Hour, ""); err != nil {
+		t.Fatal(err)
+	}
+	expiring, err := m.Elevate(ctx, "", "Helper", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Elevations(); len(got) != 2 {
+		t.Fatalf("Elevations() = %d entries, want 2", len(got))
+	}
+
+	if !m.RevokeElevation("mod", "Admin") {
+		t.Fatal("RevokeElevation = false")
+	}
+	if m.RevokeElevation("Mod", "Admin") {
+		t.Fatal("second RevokeElevation = true")
+	}
+	if got := m.GetPermissionLevel(ctx, "", "Mod"); got != 2 {
+		t.Fatalf("level after revoke = %d, want 2", got)
+	}
+
+	expiring.ExpiresAt = time.Now().Add(-time.Second)
+	if got := m.GetPermissionLevel(ctx, "", "Helper"); got != 2 {
+		t.Fatalf("level after expiry = %d, want 2", got)
+	}
+	if got := m.Elevations(); len(got) != 0 {
+		t.Fatalf("Elevations() after expiry = %+v, want none", got)
+	}
+	m.expireElevations()
+	if m.RevokeElevation("Helper", "Admin") {
+		t.Fatal("RevokeElevation of an expired elevation = true")
+	}
+}
+
+func TestElevationCooldown(t *testing.T) {
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		// end finishes the first elevation, nil leaves it active
+		end     func(m *Manager, e *Elevation)
+		wantErr error
+	}{
+		{"still active", time.Hour, nil, ErrAlreadyElevated},
+		{"just expired", time.Hour, func(m *Manager, e *Elevation) {
+			e.ExpiresAt = time.Now().Add(-time.Minute)
+		}, ErrElevationCooldown},
+		{"expired and dropped", time.Hour, func(m *Manager, e *Elevation) {
+			e.ExpiresAt = time.Now().Add(-time.Minute)
+			m.expireElevations()
+		}, ErrElevationCooldown},
+		{"revoked", time.Hour, func(m *Manager, e *Elevation) {
+			m.RevokeElevation("Mod", "Admin")
+		}, ErrElevationCooldown},
+		{"cooldown over", time.Hour, func(m *Manager, e *Elevation) {
+			e.ExpiresAt = time.Now().Add(-2 * time.Hour)
+			m.expireElevations()
+		}, nil},
+		{"no cooldown", 0, func(m *Manager, e *Elevation) {
+			e.ExpiresAt = time.Now().Add(-time.Second)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newPermissionAPI(t, testUser{Username: "Mod", Level: 2})
+			m := newTestManager(t, api, Config{Elevation: &ElevationConfig{Enabled: true, Level: 4, Cooldown: tt.cooldown}})
+			ctx := context.Background()
+
+			e, err := m.Elevate(ctx, "", "Mod", time.Hour, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.end != nil {
+				tt.end(m, e)
+			}
+			if _, err := m.Elevate(ctx, "", "Mod", time.Hour, ""); !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("second Elevate = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
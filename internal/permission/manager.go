@@ -3,130 +3,410 @@ package permission
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/RMS-Server/RMS-Gate/internal/audit"
+	"github.com/RMS-Server/RMS-Gate/internal/rmsapi"
 )
 
 const (
 	LevelAdmin = 3
+
+	maxResponseBytes = 1 << 20
 )
 
+type Config struct {
+	// CacheTTLSeconds is how old the cached levels may get before they are reported as stale
+	CacheTTLSeconds int
+	// RefreshIntervalSeconds is how often the background refresher reloads the list
+	RefreshIntervalSeconds int
+	// NegativeCacheSeconds is how long an unknown player is remembered before a miss refetches the list
+	NegativeCacheSeconds int
+	AdminCommands        []string
+	// PluginCommands are the roots of the plugin's own command trees. Unlike other commands
+	// they need admin when no command rule, admin command entry or granted node allows them.
+	PluginCommands []string
+	// CommandLevels maps command path patterns ("lb status", "lb *") to the minimum level
+	CommandLevels map[string]int
+	// Nodes maps extra permission nodes ("some.node", "some.prefix.*") to the minimum level
+	Nodes map[string]int
+	// VerifyResponses rejects permission lists without a valid signature, keeping the previous cache
+	VerifyResponses bool
+	// Overrides are local grants merged on top of the API levels, nil to disable
+	Overrides *Overrides
+	// Elevation configures /elevate, nil to disable
+	Elevation *ElevationConfig
+	// Audit records elevations, may be nil
+	Audit *audit.Log
+}
+
+// Manager caches permission levels from the RMS API. Players are looked up by UUID first and
+// by username as a fallback; a background refresher keeps the cache warm and the cached levels
+// keep being served while the API is unreachable.
 type Manager struct {
-	client        *http.Client
-	log           logr.Logger
-	baseURL       string
-	cache         map[string]int // username -> permission_level
-	cacheMu       sync.RWMutex
-	cacheExpiry   time.Time
-	cacheTTL      time.Duration
-	adminCommands []string
+	client          *rmsapi.Client
+	verifyResponses bool
+	log             logr.Logger
+	cacheTTL        time.Duration
+	refreshInterval time.Duration
+	negativeTTL     time.Duration
+	adminCommands   []string
+	pluginCommands  []string
+	commandRules    []commandRule
+	nodeLevels      map[string]int
+	overrides       *Overrides
+	elevationCfg    *ElevationConfig
+	elevated        elevations
+	audit           *audit.Log
+
+	// fetches coalesces concurrent refreshes into a single request
+	fetches singleflight.Group
+
+	cacheMu     sync.RWMutex
+	byUUID      map[string]int       // normalized uuid -> permission_level
+	byName      map[string]int       // lowercase username -> permission_level
+	unknown     map[string]time.Time // lookup key -> negative entry expiry
+	loaded      bool
+	lastRefresh time.Time
+	lastError   error
+}
+
+// Status describes the state of the permission cache
+type Status struct {
+	Users       int
+	Loaded      bool
+	LastRefresh time.Time
+	// Stale is set when the last successful refresh is older than the cache TTL
+	Stale     bool
+	LastError error
 }
 
 type permissionResponse struct {
 	Success bool `json:"success"`
 	Users   []struct {
+		UUID            string `json:"uuid"`
 		Username        string `json:"username"`
 		PermissionLevel int    `json:"permission_level"`
 	} `json:"users"`
 }
 
-func NewManager(log logr.Logger, baseURL string, cacheTTLSeconds int, adminCommands []string) *Manager {
+func NewManager(log logr.Logger, client *rmsapi.Client, cfg *Config) *Manager {
 	return &Manager{
-		client:        &http.Client{Timeout: 10 * time.Second},
-		log:           log.WithName("permission"),
-		baseURL:       strings.TrimSuffix(baseURL, "/"),
-		cache:         make(map[string]int),
-		cacheTTL:      time.Duration(cacheTTLSeconds) * time.Second,
-		adminCommands: adminCommands,
+		client:          client,
+		verifyResponses: cfg.VerifyResponses,
+		log:             log.WithName("permission"),
+		cacheTTL:        secondsOr(cfg.CacheTTLSeconds, 300),
+		refreshInterval: secondsOr(cfg.RefreshIntervalSeconds, 60),
+		negativeTTL:     secondsOr(cfg.NegativeCacheSeconds, 60),
+		adminCommands:   cfg.AdminCommands,
+		pluginCommands:  cfg.PluginCommands,
+		commandRules:    compileRules(withBuiltinLevels(cfg.CommandLevels)),
+		nodeLevels:      lowerKeys(cfg.Nodes),
+		overrides:       cfg.Overrides,
+		elevationCfg:    cfg.Elevation,
+		elevated:        elevations{active: make(map[string]*Elevation), ended: make(map[string]time.Time)},
+		audit:           cfg.Audit,
+		byUUID:          make(map[string]int),
+		byName:          make(map[string]int),
+		unknown:         make(map[string]time.Time),
+	}
+}
+
+func secondsOr(seconds, def int) time.Duration {
+	if seconds <= 0 {
+		seconds = def
 	}
+	return time.Duration(seconds) * time.Second
+}
+
+func lowerKeys(m map[string]int) map[string]int {
+	result := make(map[string]int, len(m))
+	for k, v := range m {
+		result[strings.ToLower(k)] = v
+	}
+	return result
+}
+
+// Start loads the permission list and keeps refreshing it in the background until ctx is done.
+// Elapsed elevations are swept every few seconds.
+func (p *Manager) Start(ctx context.Context) {
+	go func() {
+		_ = p.refresh(ctx)
+
+		ticker := time.NewTicker(p.refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = p.refresh(ctx)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.expireElevations()
+			}
+		}
+	}()
+}
+
+// refresh fetches the permission list, sharing one request between concurrent callers.
+// The fetch is detached from the caller's cancellation since other callers may be waiting on it.
+func (p *Manager) refresh(ctx context.Context) error {
+	_, err, _ := p.fetches.Do("permissions", func() (any, error) {
+		err := p.fetchPermissions(context.WithoutCancel(ctx))
+		if err != nil {
+			p.cacheMu.Lock()
+			p.lastError = err
+			loaded, age := p.loaded, time.Since(p.lastRefresh)
+			p.cacheMu.Unlock()
+
+			if loaded {
+				p.log.Error(err, "Failed to refresh permissions, serving cached levels", "age", age.Round(time.Second))
+			} else {
+				p.log.Error(err, "Failed to fetch permissions")
+			}
+		}
+		return nil, err
+	})
+	return err
 }
 
 func (p *Manager) fetchPermissions(ctx context.Context) error {
-	url := p.baseURL + "/api/mcdr/permission"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, resp, err := p.client.Send(ctx, http.MethodGet, "/api/mcdr/permission", nil)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("permission API returned status %d", resp.StatusCode)
+	}
 
-	resp, err := p.client.Do(req)
+	body, err := rmsapi.ReadBody(resp, maxResponseBytes)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+
+	if p.verifyResponses {
+		if err := p.client.VerifyResponse(req, resp, body); err != nil {
+			return fmt.Errorf("permission response rejected: %w", err)
+		}
+	}
 
 	var result permissionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(body, &result); err != nil {
 		return err
 	}
 
 	if !result.Success {
-		p.log.Info("Permission API returned success=false")
-		return nil
+		return errors.New("permission API returned success=false")
 	}
 
+	byUUID := make(map[string]int, len(result.Users))
+	byName := make(map[string]int, len(result.Users))
+	for _, user := range result.Users {
+		if id := normalizeUUID(user.UUID); id != "" {
+			byUUID[id] = user.PermissionLevel
+		}
+		if user.Username != "" {
+			byName[strings.ToLower(user.Username)] = user.PermissionLevel
+		}
+	}
+
+	now := time.Now()
 	p.cacheMu.Lock()
 	defer p.cacheMu.Unlock()
 
-	p.cache = make(map[string]int)
-	for _, user := range result.Users {
-		p.cache[strings.ToLower(user.Username)] = user.PermissionLevel
+	p.byUUID = byUUID
+	p.byName = byName
+	p.loaded = true
+	p.lastRefresh = now
+	p.lastError = nil
+	for key, expiry := range p.unknown {
+		if now.After(expiry) {
+			delete(p.unknown, key)
+		}
 	}
-	p.cacheExpiry = time.Now().Add(p.cacheTTL)
 
-	p.log.Info("Permission cache refreshed", "users", len(result.Users))
+	p.log.V(1).Info("Permission cache refreshed", "users", len(result.Users))
 	return nil
 }
 
-func (p *Manager) GetPermissionLevel(ctx context.Context, username string) int {
+// GetPermissionLevel returns the player's level from the RMS API raised by any local
+// override or active elevation, 0 for unknown players
+func (p *Manager) GetPermissionLevel(ctx context.Context, uuid, username string) int {
+	return max(p.BaseLevel(ctx, uuid, username), p.elevationLevel(uuid, username))
+}
+
+// BaseLevel is the player's level from the RMS API and local overrides, without elevation
+func (p *Manager) BaseLevel(ctx context.Context, uuid, username string) int {
+	level := p.APILevel(ctx, uuid, username)
+	if p.overrides != nil {
+		level = max(level, p.overrides.Effective(uuid, username).Level)
+	}
+	return level
+}
+
+// Overrides returns the local grants, nil when disabled
+func (p *Manager) Overrides() *Overrides {
+	return p.overrides
+}
+
+// APILevel returns the player's level from the RMS API alone. A miss refetches the list
+// once, after which the player is remembered as unknown for the negative cache TTL.
+func (p *Manager) APILevel(ctx context.Context, uuid, username string) int {
+	if level, ok := p.lookup(uuid, username); ok {
+		return level
+	}
+
+	key := playerKey(uuid, username)
 	p.cacheMu.RLock()
-	expired := time.Now().After(p.cacheExpiry)
-	level, exists := p.cache[strings.ToLower(username)]
+	expiry, negative := p.unknown[key]
 	p.cacheMu.RUnlock()
+	if negative && time.Now().Before(expiry) {
+		return 0
+	}
 
-	if expired || !exists {
-		if err := p.fetchPermissions(ctx); err != nil {
-			p.log.Error(err, "Failed to fetch permissions")
-			if exists {
-				return level
-			}
-			return 0
+	_ = p.refresh(ctx)
+	if level, ok := p.lookup(uuid, username); ok {
+		return level
+	}
+
+	p.cacheMu.Lock()
+	p.unknown[key] = time.Now().Add(p.negativeTTL)
+	p.cacheMu.Unlock()
+	return 0
+}
+
+func (p *Manager) lookup(uuid, username string) (int, bool) {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
+
+	if id := normalizeUUID(uuid); id != "" {
+		if level, ok := p.byUUID[id]; ok {
+			return level, true
 		}
+	}
+	level, ok := p.byName[strings.ToLower(username)]
+	return level, ok
+}
+
+// Status returns the cache size and freshness
+func (p *Manager) Status() Status {
+	p.cacheMu.RLock()
+	defer p.cacheMu.RUnlock()
 
-		p.cacheMu.RLock()
-		level = p.cache[strings.ToLower(username)]
-		p.cacheMu.RUnlock()
+	users := len(p.byName)
+	if len(p.byUUID) > users {
+		users = len(p.byUUID)
 	}
+	return Status{
+		Users:       users,
+		Loaded:      p.loaded,
+		LastRefresh: p.lastRefresh,
+		Stale:       !p.loaded || time.Since(p.lastRefresh) > p.cacheTTL,
+		LastError:   p.lastError,
+	}
+}
 
-	return level
+func playerKey(uuid, username string) string {
+	if id := normalizeUUID(uuid); id != "" {
+		return id
+	}
+	return "name:" + strings.ToLower(username)
 }
 
-func (p *Manager) IsAdmin(ctx context.Context, username string) bool {
-	return p.GetPermissionLevel(ctx, username) > LevelAdmin
+// normalizeUUID lowercases a UUID and strips dashes so both notations match
+func normalizeUUID(uuid string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(uuid), "-", ""))
+}
+
+func (p *Manager) IsAdmin(ctx context.Context, uuid, username string) bool {
+	return p.GetPermissionLevel(ctx, uuid, username) > LevelAdmin
 }
 
 func (p *Manager) IsAdminCommand(cmd string) bool {
-	cmdLower := strings.ToLower(strings.TrimPrefix(cmd, "/"))
-	parts := strings.Fields(cmdLower)
+	return containsCommand(p.adminCommands, cmd)
+}
+
+// isPluginCommand reports whether cmd belongs to one of the plugin's own command trees
+func (p *Manager) isPluginCommand(cmd string) bool {
+	return containsCommand(p.pluginCommands, cmd)
+}
+
+// containsCommand reports whether the root of cmd is in names
+func containsCommand(names []string, cmd string) bool {
+	parts := splitCommand(cmd)
 	if len(parts) == 0 {
 		return false
 	}
-	cmdName := parts[0]
-
-	for _, adminCmd := range p.adminCommands {
-		if strings.ToLower(adminCmd) == cmdName {
+	for _, name := range names {
+		if strings.ToLower(name) == parts[0] {
 			return true
 		}
 	}
 	return false
 }
 
-func (p *Manager) CanExecute(ctx context.Context, username, cmd string) bool {
-	if !p.IsAdminCommand(cmd) {
+// RequiredLevel returns the minimum level of the most specific command rule matching cmd.
+// ok is false when no rule matches.
+func (p *Manager) RequiredLevel(cmd string) (level int, pattern string, ok bool) {
+	words := splitCommand(cmd)
+	if len(words) == 0 {
+		return 0, "", false
+	}
+	for i := range p.commandRules {
+		if p.commandRules[i].matches(words) {
+			return p.commandRules[i].level, p.commandRules[i].pattern, true
+		}
+	}
+	return 0, "", false
+}
+
+// IsGated reports whether cmd is subject to a permission check: it matches a command rule,
+// is an admin command or belongs to the plugin's own commands.
+func (p *Manager) IsGated(cmd string) bool {
+	if _, _, ok := p.RequiredLevel(cmd); ok {
+		return true
+	}
+	return p.IsAdminCommand(cmd) || p.isPluginCommand(cmd)
+}
+
+// CanExecute checks cmd against the command rules. Commands without a rule keep the
+// AdminCommands behavior: admin commands and the plugin's own commands need more than
+// LevelAdmin, the rest are open. Override groups may also grant the command's node directly.
+func (p *Manager) CanExecute(ctx context.Context, uuid, username, cmd string) bool {
+	if p.grantsNode(uuid, username, CommandNode(cmd)) {
+		return true
+	}
+
+	if level, _, ok := p.RequiredLevel(cmd); ok {
+		if level <= 0 {
+			return true
+		}
+		return p.GetPermissionLevel(ctx, uuid, username) >= level
+	}
+
+	if !p.IsAdminCommand(cmd) && !p.isPluginCommand(cmd) {
 		return true
 	}
-	return p.IsAdmin(ctx, username)
+	return p.IsAdmin(ctx, uuid, username)
 }
@@ -0,0 +1,133 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/RMS-Server/RMS-Gate/internal/rmsapi"
+)
+
+type testUser struct {
+	UUID     string `json:"uuid"`
+	Username string `json:"username"`
+	Level    int    `json:"permission_level"`
+}
+
+// permissionAPI serves users as the RMS permission list and counts the requests
+type permissionAPI struct {
+	srv      *httptest.Server
+	users    atomic.Value // []testUser
+	requests atomic.Int32
+	down     atomic.Bool
+}
+
+func newPermissionAPI(t *testing.T, users ...testUser) *permissionAPI {
+	api := &permissionAPI{}
+	api.users.Store(users)
+	api.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.requests.Add(1)
+		if api.down.Load() || r.URL.Path != "/api/mcdr/permission" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "users": api.users.Load()})
+	}))
+	t.Cleanup(api.srv.Close)
+	return api
+}
+
+// newTestManager returns a manager backed by api, or by no endpoint at all when api is nil
+func newTestManager(t *testing.T, api *permissionAPI, cfg Config) *Manager {
+	var urls []string
+	if api != nil {
+		urls = []string{api.srv.URL}
+	}
+	client, err := rmsapi.NewClient(nil, rmsapi.NewPool(urls, nil, 1, time.Millisecond), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(logr.Discard(), client, &cfg)
+}
+
+func TestGetPermissionLevel(t *testing.T) {
+	api := newPermissionAPI(t,
+		testUser{UUID: "069a79f4-44e9-4726-a5be-fca90e38aaf5", Username: "Notch", Level: 4},
+		testUser{Username: "Steve", Level: 1},
+		testUser{UUID: "853c80ef3c3749fdaa49938b674adae6", Username: "jeb_", Level: 2},
+	)
+	m := newTestManager(t, api, Config{})
+
+	tests := []struct {
+		name     string
+		uuid     string
+		username string
+		want     int
+	}{
+		{"uuid with dashes", "069a79f4-44e9-4726-a5be-fca90e38aaf5", "Renamed", 4},
+		{"uuid without dashes", "069A79F444E94726A5BEFCA90E38AAF5", "", 4},
+		{"listed uuid matched with dashes", "853c80ef-3c37-49fd-aa49-938b674adae6", "", 2},
+		{"name fallback", "00000000-0000-0000-0000-000000000001", "steve", 1},
+		{"unknown", "00000000-0000-0000-0000-000000000002", "Herobrine", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.GetPermissionLevel(context.Background(), tt.uuid, tt.username); got != tt.want {
+				t.Fatalf("GetPermissionLevel(%q, %q) = %d, want %d", tt.uuid, tt.username, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	api := newPermissionAPI(t, testUser{Username: "Steve", Level: 1})
+	m := newTestManager(t, api, Config{NegativeCacheSeconds: 60})
+	ctx := context.Background()
+
+	m.GetPermissionLevel(ctx, "", "Steve")
+	if n := api.requests.Load(); n != 1 {
+		t.Fatalf("first lookup made %d requests, want 1", n)
+	}
+	m.GetPermissionLevel(ctx, "", "Herobrine")
+	m.GetPermissionLevel(ctx, "", "Herobrine")
+	if n := api.requests.Load(); n != 2 {
+		t.Fatalf("repeated miss made %d requests in total, want 2", n)
+	}
+
+	// A player added to the list later is found once the negative entry expires or a refresh runs
+	api.users.Store([]testUser{{Username: "Steve", Level: 1}, {Username: "Herobrine", Level: 2}})
+	if err := m.refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetPermissionLevel(ctx, "", "Herobrine"); got != 2 {
+		t.Fatalf("GetPermissionLevel after refresh = %d, want 2", got)
+	}
+}
+
+func TestCachedLevelsSurviveOutage(t *testing.T) {
+	api := newPermissionAPI(t, testUser{Username: "Notch", Level: 4})
+	m := newTestManager(t, api, Config{CacheTTLSeconds: 1})
+	ctx := context.Background()
+
+	if err := m.refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	api.down.Store(true)
+	if err := m.refresh(ctx); err == nil {
+		t.Fatal("refresh succeeded while the API is down")
+	}
+
+	if got := m.GetPermissionLevel(ctx, "", "Notch"); got != 4 {
+		t.Fatalf("GetPermissionLevel during outage = %d, want the cached 4", got)
+	}
+	s := m.Status()
+	if !s.Loaded || s.LastError == nil || s.Users != 1 {
+		t.Fatalf("Status() = %+v, want loaded with an error", s)
+	}
+}
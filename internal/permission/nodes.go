@@ -0,0 +1,80 @@
+package permission
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Permission nodes resolved from RMS levels, so Gate and other plugins can use HasPermission:
+//
+//	rmsgate.level.<n>          level >= n
+//	rmsgate.command.<a>.<b>    CanExecute("a b")
+//	gate.command.<name>        CanExecute("name"), for Gate's built-in commands
+//
+// plus the nodes configured in Config.Nodes ("some.node" or "some.prefix.*" -> min level)
+// and the nodes of the player's override groups.
+const (
+	NodeLevelPrefix   = "rmsgate.level."
+	NodeCommandPrefix = "rmsgate.command."
+
+	nodeGateCommandPrefix = "gate.command."
+)
+
+// CommandNode returns the permission node for a command path such as "lb status"
+func CommandNode(path string) string {
+	return NodeCommandPrefix + strings.Join(splitCommand(path), ".")
+}
+
+// HasNode resolves a permission node for the player. ok is false for nodes the
+// manager does not know, so the caller can fall back to other permission sources.
+func (p *Manager) HasNode(ctx context.Context, uuid, username, node string) (allowed, ok bool) {
+	node = strings.ToLower(node)
+	if p.grantsNode(uuid, username, node) {
+		return true, true
+	}
+
+	switch {
+	case strings.HasPrefix(node, NodeLevelPrefix):
+		required, err := strconv.Atoi(strings.TrimPrefix(node, NodeLevelPrefix))
+		if err != nil {
+			return false, false
+		}
+		return p.GetPermissionLevel(ctx, uuid, username) >= required, true
+	case strings.HasPrefix(node, NodeCommandPrefix):
+		path := strings.ReplaceAll(strings.TrimPrefix(node, NodeCommandPrefix), ".", " ")
+		return p.CanExecute(ctx, uuid, username, path), true
+	case strings.HasPrefix(node, nodeGateCommandPrefix):
+		path := strings.ReplaceAll(strings.TrimPrefix(node, nodeGateCommandPrefix), ".", " ")
+		return p.CanExecute(ctx, uuid, username, path), true
+	}
+
+	if required, found := p.nodeLevel(node); found {
+		return p.GetPermissionLevel(ctx, uuid, username) >= required, true
+	}
+	return false, false
+}
+
+// grantsNode reports whether the player's override groups grant node
+func (p *Manager) grantsNode(uuid, username, node string) bool {
+	if p.overrides == nil {
+		return false
+	}
+	return matchNode(p.overrides.Effective(uuid, username).Nodes, node)
+}
+
+// nodeLevel finds the configured level of node, preferring exact entries over the longest "prefix.*"
+func (p *Manager) nodeLevel(node string) (int, bool) {
+	if level, ok := p.nodeLevels[node]; ok {
+		return level, true
+	}
+	for i := strings.LastIndex(node, "."); i > 0; i = strings.LastIndex(node[:i], ".") {
+		if level, ok := p.nodeLevels[node[:i]+".*"]; ok {
+			return level, true
+		}
+	}
+	if level, ok := p.nodeLevels["*"]; ok {
+		return level, true
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package permission
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHasNode(t *testing.T) {
+	api := newPermissionAPI(t, testUser{Username: "Mod", Level: 2}, testUser{Username: "Player", Level: 0})
+	m := newTestManager(t, api, Config{
+		AdminCommands:  []string{"send"},
+		PluginCommands: []string{"lb"},
+		CommandLevels:  map[string]int{"lb status": 2},
+		Nodes: map[string]int{
+			"Plugin.Reload":   2,
+			"plugin.admin.*":  4,
+			"plugin.admin.ro": 1,
+		},
+		Overrides: newTestOverrides(t, `{
+			"groups": {"event": {"level": 0, "nodes": ["plugin.admin.*", "custom.node"]}},
+			"players": [{"username": "Player", "grants": [{"group": "event"}]}]
+		}`),
+	})
+
+	tests := []struct {
+		user        string
+		node        string
+		wantAllowed bool
+		wantOK      bool
+	}{
+		{"Mod", "rmsgate.level.2", true, true},
+		{"Mod", "rmsgate.level.3", false, true},
+		{"Mod", "rmsgate.level.x", false, false},
+		{"Mod", "rmsgate.command.lb.status", true, true},
+		{"Player", "rmsgate.command.lb.status", false, true},
+		{"Mod", "gate.command.send", false, true},
+		{"Mod", "gate.command.glist", true, true},
+		{"Mod", "plugin.reload", true, true},
+		{"Mod", "plugin.admin.ro", true, true},
+		{"Mod", "plugin.admin.kick", false, true},
+		{"Mod", "unknown.node", false, false},
+		// nodes granted by an override group apply regardless of level
+		{"Player", "plugin.admin.kick", true, true},
+		{"Player", "custom.node", true, true},
+		{"Player", "plugin.reload", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.node, func(t *testing.T) {
+			allowed, ok := m.HasNode(context.Background(), "", tt.user, tt.node)
+			if allowed != tt.wantAllowed || ok != tt.wantOK {
+				t.Fatalf("HasNode(%s, %s) = %v, %v, want %v, %v", tt.user, tt.node, allowed, ok, tt.wantAllowed, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCommandNode(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"lb", "rmsgate.command.lb"},
+		{"/LB  Status", "rmsgate.command.lb.status"},
+		{"rmsgate maintenance on", "rmsgate.command.rmsgate.maintenance.on"},
+	}
+	for _, tt := range tests {
+		if got := CommandNode(tt.path); got != tt.want {
+			t.Errorf("CommandNode(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+var ErrUnknownGroup = errors.New("unknown permission group")
+
+// Group is a named role. A group's level is the highest level of itself and the groups it
+// inherits from, and its nodes are the union of theirs.
+type Group struct {
+	Level    int      `json:"level"`
+	Inherits []string `json:"inherits,omitempty"`
+	Nodes    []string `json:"nodes,omitempty"`
+}
+
+// Grant gives a player a group or a plain level, until ExpiresAt when set
+type Grant struct {
+	Group     string    `json:"group,omitempty"`
+	Level     int       `json:"level,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+	GrantedBy string    `json:"grantedBy,omitempty"`
+	GrantedAt time.Time `json:"grantedAt,omitzero"`
+}
+
+// Expired reports whether the grant has passed its expiry time
+func (g *Grant) Expired() bool {
+	return !g.ExpiresAt.IsZero() && time.Now().After(g.ExpiresAt)
+}
+
+// PlayerOverride holds the local grants of one player
+type PlayerOverride struct {
+	Username string   `json:"username"`
+	UUID     string   `json:"uuid,omitempty"`
+	Grants   []*Grant `json:"grants"`
+}
+
+// Effective is what a player's active grants add on top of the RMS API level
+type Effective struct {
+	Level int
+	Nodes []string
+}
+
+type overridesFile struct {
+	Groups  map[string]*Group `json:"groups"`
+	Players []*PlayerOverride `json:"players"`
+}
+
+// Overrides reads local permission grants from a JSON file, reloads it when edited by hand
+// and writes it back when grants are changed through commands. Grants only ever raise a
+// player's level, so they work as emergency access while the RMS API is unreachable.
+type Overrides struct {
+	log  logr.Logger
+	path string
+
+	mu      sync.RWMutex
+	groups  map[string]*Group
+	players []*PlayerOverride
+	modTime time.Time
+}
+
+func NewOverrides(ctx context.Context, log logr.Logger, path string, reloadInterval time.Duration) *Overrides {
+	o := &Overrides{
+		log:    log.WithName("permission-overrides"),
+		path:   path,
+		groups: make(map[string]*Group),
+	}
+	o.reload()
+
+	if reloadInterval <= 0 {
+		reloadInterval = 5 * time.Second
+	}
+	go o.watch(ctx, reloadInterval)
+	return o
+}
+
+func (o *Overrides) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.PurgeExpired()
+
+			info, err := os.Stat(o.path)
+			if err != nil {
+				continue
+			}
+			o.mu.RLock()
+			changed := !info.ModTime().Equal(o.modTime)
+			o.mu.RUnlock()
+			if changed {
+				o.reload()
+			}
+		}
+	}
+}
+
+func (o *Overrides) reload() {
+	info, err := os.Stat(o.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			o.log.Error(err, "Permission overrides file not readable", "path", o.path)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		o.log.Error(err, "Failed to read permission overrides", "path", o.path)
+		return
+	}
+
+	var of overridesFile
+	if err := json.Unmarshal(data, &of); err != nil {
+		// Keep serving the previous grants until the file is fixed
+		o.log.Error(err, "Failed to parse permission overrides", "path", o.path)
+		o.mu.Lock()
+		o.modTime = info.ModTime()
+		o.mu.Unlock()
+		return
+	}
+
+	groups := make(map[string]*Group, len(of.Groups))
+	for name, g := range of.Groups {
+		if g != nil {
+			groups[strings.ToLower(name)] = g
+		}
+	}
+	players := make([]*PlayerOverride, 0, len(of.Players))
+	for _, p := range of.Players {
+		if p != nil && (p.Username != "" || p.UUID != "") {
+			players = append(players, p)
+		}
+	}
+
+	o.mu.Lock()
+	o.groups = groups
+	o.players = players
+	o.modTime = info.ModTime()
+	o.mu.Unlock()
+
+	o.log.Info("Permission overrides loaded", "path", o.path, "groups", len(groups), "players", len(players))
+}
+
+// save must be called with mu held
+func (o *Overrides) save() error {
+	data, err := json.MarshalIndent(overridesFile{Groups: o.groups, Players: o.players}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(o.path, data, 0644); err != nil {
+		return err
+	}
+	// Our own write is not a hand edit, skip reloading it
+	if info, err := os.Stat(o.path); err == nil {
+		o.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// find must be called with mu held
+func (o *Overrides) find(uuid, username string) *PlayerOverride {
+	if id := normalizeUUID(uuid); id != "" {
+		for _, p := range o.players {
+			if normalizeUUID(p.UUID) == id {
+				return p
+			}
+		}
+	}
+	for _, p := range o.players {
+		if strings.EqualFold(p.Username, username) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Effective merges the player's unexpired grants
+func (o *Overrides) Effective(uuid, username string) Effective {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var eff Effective
+	p := o.find(uuid, username)
+	if p == nil {
+		return eff
+	}
+	for _, g := range p.Grants {
+		if g.Expired() {
+			continue
+		}
+		level, nodes := g.Level, []string(nil)
+		if g.Group != "" {
+			groupLevel, groupNodes := o.resolve(g.Group, map[string]bool{})
+			level = max(level, groupLevel)
+			nodes = groupNodes
+		}
+		eff.Level = max(eff.Level, level)
+		eff.Nodes = append(eff.Nodes, nodes...)
+	}
+	return eff
+}
+
+// resolve walks a group and its parents, must be called with mu held. Cycles are cut at the first repeat.
+func (o *Overrides) resolve(name string, seen map[string]bool) (int, []string) {
+	name = strings.ToLower(name)
+	g, ok := o.groups[name]
+	if !ok || seen[name] {
+		return 0, nil
+	}
+	seen[name] = true
+
+	level := g.Level
+	nodes := append([]string(nil), g.Nodes...)
+	for _, parent := range g.Inherits {
+		parentLevel, parentNodes := o.resolve(parent, seen)
+		level = max(level, parentLevel)
+		nodes = append(nodes, parentNodes...)
+	}
+	return level, nodes
+}
+
+// GroupLevel returns the effective level of a group including inherited ones
+func (o *Overrides) GroupLevel(name string) (int, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if _, ok := o.groups[strings.ToLower(name)]; !ok {
+		return 0, false
+	}
+	level, _ := o.resolve(name, map[string]bool{})
+	return level, true
+}
+
+// GroupNodes returns the nodes of a group including inherited ones
+func (o *Overrides) GroupNodes(name string) []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	_, nodes := o.resolve(name, map[string]bool{})
+	return nodes
+}
+
+// Groups returns the defined group names, sorted
+func (o *Overrides) Groups() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	names := make([]string, 0, len(o.groups))
+	for name := range o.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Grant adds a group or level grant to a player, replacing an existing grant of the same group or level
+func (o *Overrides) Grant(uuid, username string, grant Grant) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if grant.Group != "" {
+		grant.Group = strings.ToLower(grant.Group)
+		if _, ok := o.groups[grant.Group]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownGroup, grant.Group)
+		}
+	}
+	grant.GrantedAt = time.Now()
+
+	p := o.find(uuid, username)
+	if p == nil {
+		p = &PlayerOverride{Username: username}
+		o.players = append(o.players, p)
+	}
+	if uuid != "" {
+		p.UUID = uuid
+	}
+	if username != "" {
+		p.Username = username
+	}
+
+	grants := p.Grants[:0]
+	for _, g := range p.Grants {
+		if !sameTarget(g, &grant) {
+			grants = append(grants, g)
+		}
+	}
+	p.Grants = append(grants, &grant)
+
+	o.log.Info("Permission override granted", "player", p.Username, "group", grant.Group, "level", grant.Level,
+		"expiresAt", grant.ExpiresAt, "by", grant.GrantedBy)
+	return o.save()
+}
+
+// Revoke removes the player's grants of a group or level; an empty group and zero level remove all of them
+func (o *Overrides) Revoke(uuid, username, group string, level int) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p := o.find(uuid, username)
+	if p == nil {
+		return 0, nil
+	}
+
+	target := &Grant{Group: strings.ToLower(group), Level: level}
+	all := group == "" && level == 0
+	grants := p.Grants[:0]
+	removed := 0
+	for _, g := range p.Grants {
+		if all || sameTarget(g, target) {
+			removed++
+			continue
+		}
+		grants = append(grants, g)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	p.Grants = grants
+	o.dropEmpty()
+
+	o.log.Info("Permission override revoked", "player", p.Username, "group", group, "level", level, "removed", removed)
+	return removed, o.save()
+}
+
+// Player returns a copy of the player's override, nil if there is none
+func (o *Overrides) Player(uuid, username string) *PlayerOverride {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	p := o.find(uuid, username)
+	if p == nil {
+		return nil
+	}
+	cp := &PlayerOverride{Username: p.Username, UUID: p.UUID, Grants: make([]*Grant, len(p.Grants))}
+	for i, g := range p.Grants {
+		grant := *g
+		cp.Grants[i] = &grant
+	}
+	return cp
+}
+
+// PurgeExpired drops expired grants and writes the file back if anything changed
+func (o *Overrides) PurgeExpired() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	purged := 0
+	for _, p := range o.players {
+		grants := p.Grants[:0]
+		for _, g := range p.Grants {
+			if g.Expired() {
+				o.log.Info("Permission override expired", "player", p.Username, "group", g.Group, "level", g.Level)
+				purged++
+				continue
+			}
+			grants = append(grants, g)
+		}
+		p.Grants = grants
+	}
+	if purged == 0 {
+		return
+	}
+	o.dropEmpty()
+	if err := o.save(); err != nil {
+		o.log.Error(err, "Failed to save permission overrides", "path", o.path)
+	}
+}
+
+// dropEmpty removes players without grants, must be called with mu held
+func (o *Overrides) dropEmpty() {
+	players := o.players[:0]
+	for _, p := range o.players {
+		if len(p.Grants) > 0 {
+			players = append(players, p)
+		}
+	}
+	o.players = players
+}
+
+func sameTarget(a, b *Grant) bool {
+	if a.Group != "" || b.Group != "" {
+		return strings.EqualFold(a.Group, b.Group)
+	}
+	return a.Level == b.Level
+}
+
+// matchNode reports whether node is granted by one of the given nodes, exactly or through "prefix.*"
+func matchNode(granted []string, node string) bool {
+	for _, g := range granted {
+		g = strings.ToLower(g)
+		if g == node || g == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, ".*"); ok && strings.HasPrefix(node, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const testOverrides = `{
+  "groups": {
+    "helper": {"level": 1, "nodes": ["rmsgate.command.wl.list"]},
+    "Moderator": {"level": 2, "inherits": ["helper"], "nodes": ["rmsgate.command.lb.*"]},
+    "event": {"level": 0, "inherits": ["moderator", "missing"], "nodes": ["event.host"]},
+    "loop-a": {"level": 1, "inherits": ["loop-b"]},
+    "loop-b": {"level": 3, "inherits": ["loop-a"]}
+  },
+  "players": [
+    {"username": "Steve", "grants": [{"group": "helper"}]},
+    {"username": "Alex", "uuid": "853c80ef-3c37-49fd-aa49-938b674adae6", "grants": [{"level": 2}, {"group": "event"}]},
+    {"username": "Old", "grants": [{"level": 4, "expiresAt": "2000-01-01T00:00:00Z"}]}
+  ]
+}`
+
+func newTestOverrides(t *testing.T, content string) *Overrides {
+	path := filepath.Join(t.TempDir(), "permission-overrides.json")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewOverrides(t.Context(), logr.Discard(), path, time.Hour)
+}
+
+func TestGroupInheritance(t *testing.T) {
+	o := newTestOverrides(t, testOverrides)
+
+	tests := []struct {
+		group     string
+		wantLevel int
+		wantOK    bool
+		wantNodes []string
+	}{
+		{"helper", 1, true, []string{"rmsgate.command.wl.list"}},
+		{"moderator", 2, true, []string{"rmsgate.command.lb.*", "rmsgate.command.wl.list"}},
+		{"EVENT", 2, true, []string{"event.host", "rmsgate.command.lb.*", "rmsgate.command.wl.list"}},
+		{"loop-a", 3, true, nil},
+		{"missing", 0, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			level, ok := o.GroupLevel(tt.group)
+			if level != tt.wantLevel || ok != tt.wantOK {
+				t.Fatalf("GroupLevel(%s) = %d, %v, want %d, %v", tt.group, level, ok, tt.wantLevel, tt.wantOK)
+			}
+			nodes := o.GroupNodes(tt.group)
+			slices.Sort(nodes)
+			if !slices.Equal(nodes, tt.wantNodes) {
+				t.Fatalf("GroupNodes(%s) = %v, want %v", tt.group, nodes, tt.wantNodes)
+			}
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	o := newTestOverrides(t, testOverrides)
+
+	tests := []struct {
+		name      string
+		uuid      string
+		username  string
+		wantLevel int
+		wantNodes int
+	}{
+		{"group grant", "", "steve", 1, 1},
+		{"level and group grants", "", "Alex", 2, 3},
+		{"matched by uuid after rename", "853c80ef3c3749fdaa49938b674adae6", "NotAlex", 2, 3},
+		{"expired grant ignored", "", "Old", 0, 0},
+		{"no override", "", "Herobrine", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eff := o.Effective(tt.uuid, tt.username)
+			if eff.Level != tt.wantLevel || len(eff.Nodes) != tt.wantNodes {
+				t.Fatalf("Effective(%q, %q) = %+v, want level %d with %d nodes", tt.uuid, tt.username, eff, tt.wantLevel, tt.wantNodes)
+			}
+		})
+	}
+}
+
+func TestGrantRevoke(t *testing.T) {
+	o := newTestOverrides(t, `{"groups": {"helper": {"level": 1}, "mod": {"level": 2}}}`)
+
+	if err := o.Grant("", "Steve", Grant{Group: "nope"}); !errors.Is(err, ErrUnknownGroup) {
+		t.Fatalf("Grant of an unknown group = %v, want ErrUnknownGroup", err)
+	}
+	if err := o.Grant("", "Steve", Grant{Group: "Helper", GrantedBy: "console"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Grant("", "Steve", Grant{Group: "helper", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Grant("uuid-1", "Steve", Grant{Level: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := o.Player("", "steve")
+	if p == nil || len(p.Grants) != 2 || p.UUID != "uuid-1" {
+		t.Fatalf("Player() = %+v, want two grants with the UUID recorded", p)
+	}
+	if eff := o.Effective("", "Steve"); eff.Level != 3 {
+		t.Fatalf("Effective level = %d, want 3", eff.Level)
+	}
+
+	// Grants survive a reload from disk
+	reloaded := NewOverrides(t.Context(), logr.Discard(), o.path, time.Hour)
+	if eff := reloaded.Effective("uuid-1", ""); eff.Level != 3 {
+		t.Fatalf("Effective level after reload = %d, want 3", eff.Level)
+	}
+
+	if n, err := o.Revoke("", "Steve", "HELPER", 0); n != 1 || err != nil {
+		t.Fatalf("Revoke(helper) = %d, %v, want 1", n, err)
+	}
+	if n, err := o.Revoke("", "Steve", "mod", 0); n != 0 || err != nil {
+		t.Fatalf("Revoke of a group not granted = %d, %v, want 0", n, err)
+	}
+	if n, err := o.Revoke("", "Steve", "", 0); n != 1 || err != nil {
+		t.Fatalf("Revoke all = %d, %v, want 1", n, err)
+	}
+	if p := o.Player("", "Steve"); p != nil {
+		t.Fatalf("player without grants kept: %+v", p)
+	}
+}
+
+func TestPurgeExpiredGrants(t *testing.T) {
+	o := newTestOverrides(t, testOverrides)
+
+	o.PurgeExpired()
+	if p := o.Player("", "Old"); p != nil {
+		t.Fatalf("player with only expired grants kept: %+v", p)
+	}
+	if p := o.Player("", "Steve"); p == nil {
+		t.Fatal("player with active grants dropped")
+	}
+}
+
+func TestOverridesRaiseLevel(t *testing.T) {
+	api := newPermissionAPI(t, testUser{Username: "Steve", Level: 2}, testUser{Username: "Alex", Level: 0})
+	m := newTestManager(t, api, Config{
+		PluginCommands: []string{"lb", "wl"},
+		Overrides:      newTestOverrides(t, testOverrides),
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		user string
+		want int
+	}{
+		// API level 2 is kept over the helper group's 1
+		{"Steve", 2},
+		{"Alex", 2},
+		{"Herobrine", 0},
+	}
+	for _, tt := range tests {
+		if got := m.BaseLevel(ctx, "", tt.user); got != tt.want {
+			t.Errorf("BaseLevel(%s) = %d, want %d", tt.user, got, tt.want)
+		}
+	}
+
+	// Group nodes open commands below their level
+	if !m.CanExecute(ctx, "", "Steve", "wl list") {
+		t.Error("helper node does not open /wl list")
+	}
+	if !m.CanExecute(ctx, "", "Alex", "lb disable a b") {
+		t.Error("inherited lb.* node does not open /lb disable")
+	}
+	if m.CanExecute(ctx, "", "Steve", "lb disable a b") {
+		t.Error("helper opens /lb disable")
+	}
+}
@@ -0,0 +1,84 @@
+package permission
+
+import (
+	"sort"
+	"strings"
+)
+
+// commandRule maps a command path pattern to the minimum level required.
+// Patterns match command prefixes word by word and "*" matches any single word,
+// so "lb" covers every /lb command while "lb *" needs a subcommand.
+type commandRule struct {
+	pattern  string
+	words    []string
+	level    int
+	literals int
+}
+
+// builtinLevels are command rules the plugin's own commands need on every config.
+// A configured rule with the same pattern replaces the built-in one.
+var builtinLevels = map[string]int{
+	// Elevate checks the elevation MinLevel itself
+	"elevate": 0,
+	// Listing and ending other players' elevations is for admins only
+	"elevate list":   LevelAdmin + 1,
+	"elevate revoke": LevelAdmin + 1,
+}
+
+// withBuiltinLevels returns the configured levels plus the built-in ones they do not set
+func withBuiltinLevels(levels map[string]int) map[string]int {
+	result := make(map[string]int, len(levels)+len(builtinLevels))
+	for pattern, level := range builtinLevels {
+		result[pattern] = level
+	}
+	for pattern, level := range levels {
+		result[strings.Join(splitCommand(pattern), " ")] = level
+	}
+	return result
+}
+
+func compileRules(levels map[string]int) []commandRule {
+	rules := make([]commandRule, 0, len(levels))
+	for pattern, level := range levels {
+		words := splitCommand(pattern)
+		if len(words) == 0 {
+			continue
+		}
+		literals := 0
+		for _, w := range words {
+			if w != "*" {
+				literals++
+			}
+		}
+		rules = append(rules, commandRule{pattern: strings.Join(words, " "), words: words, level: level, literals: literals})
+	}
+
+	// Most specific first: more literal words, then longer patterns
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].literals != rules[j].literals {
+			return rules[i].literals > rules[j].literals
+		}
+		if len(rules[i].words) != len(rules[j].words) {
+			return len(rules[i].words) > len(rules[j].words)
+		}
+		return rules[i].pattern < rules[j].pattern
+	})
+	return rules
+}
+
+func (r *commandRule) matches(cmd []string) bool {
+	if len(cmd) < len(r.words) {
+		return false
+	}
+	for i, w := range r.words {
+		if w != "*" && w != cmd[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCommand lowercases a command line and splits it into words without the leading slash
+func splitCommand(cmd string) []string {
+	return strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(cmd), "/")))
+}
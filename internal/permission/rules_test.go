@@ -0,0 +1,118 @@
+package permission
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequiredLevel(t *testing.T) {
+	m := newTestManager(t, nil, Config{CommandLevels: map[string]int{
+		"lb":           2,
+		"lb *":         3,
+		"lb status":    1,
+		"* status":     5,
+		"/WL  Add":     2,
+		"elevate list": 2,
+	}})
+
+	tests := []struct {
+		cmd         string
+		wantLevel   int
+		wantPattern string
+		wantOK      bool
+	}{
+		{"lb", 2, "lb", true},
+		{"lb disable a b", 3, "lb *", true},
+		{"lb status", 1, "lb status", true},
+		{"/LB Status lobby", 1, "lb status", true},
+		{"rmsgate status", 5, "* status", true},
+		{"wl add Steve", 2, "wl add", true},
+		{"wl remove Steve", 0, "", false},
+		// built-in levels, unless the config sets the same pattern
+		{"elevate 10m", 0, "elevate", true},
+		{"elevate revoke Steve", LevelAdmin + 1, "elevate revoke", true},
+		{"elevate list", 2, "elevate list", true},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			level, pattern, ok := m.RequiredLevel(tt.cmd)
+			if level != tt.wantLevel || pattern != tt.wantPattern || ok != tt.wantOK {
+				t.Fatalf("RequiredLevel(%q) = %d, %q, %v, want %d, %q, %v",
+					tt.cmd, level, pattern, ok, tt.wantLevel, tt.wantPattern, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCanExecute(t *testing.T) {
+	api := newPermissionAPI(t,
+		testUser{Username: "Admin", Level: LevelAdmin + 1},
+		testUser{Username: "Mod", Level: 2},
+		testUser{Username: "Player", Level: 0},
+	)
+	m := newTestManager(t, api, Config{
+		AdminCommands:  []string{"send"},
+		PluginCommands: []string{"lb", "wl", "elevate"},
+		CommandLevels:  map[string]int{"lb status": 2, "wl list": 0},
+	})
+
+	tests := []struct {
+		user string
+		cmd  string
+		want bool
+	}{
+		// commands of other plugins without a rule are open
+		{"Player", "glist", true},
+		{"Player", "send Steve lobby", false},
+		{"Admin", "send Steve lobby", true},
+		// plugin commands are admin only unless a rule opens them
+		{"Player", "lb", false},
+		{"Mod", "lb disable a b", false},
+		{"Admin", "lb disable a b", true},
+		{"Mod", "lb status", true},
+		{"Player", "lb status", false},
+		{"Player", "wl list", true},
+		{"Player", "wl add Steve", false},
+		// elevate is open by its built-in rule, listing is not
+		{"Player", "elevate 10m", true},
+		{"Mod", "elevate list", false},
+		{"Admin", "elevate list", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.cmd, func(t *testing.T) {
+			if got := m.CanExecute(context.Background(), "", tt.user, tt.cmd); got != tt.want {
+				t.Fatalf("CanExecute(%s, %q) = %v, want %v", tt.user, tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsGated(t *testing.T) {
+	m := newTestManager(t, newPermissionAPI(t), Config{
+		AdminCommands:  []string{"send"},
+		PluginCommands: []string{"lb"},
+		CommandLevels:  map[string]int{"msg *": 1},
+	})
+
+	tests := []struct {
+		cmd  string
+		want bool
+	}{
+		{"send Steve lobby", true},
+		{"/LB status", true},
+		{"msg Steve hi", true},
+		{"elevate 10m", true},
+		{"msg", false},
+		{"login hunter2", false},
+		{"tell Steve hi", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			if got := m.IsGated(tt.cmd); got != tt.want {
+				t.Fatalf("IsGated(%q) = %v, want %v", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
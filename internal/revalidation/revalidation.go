@@ -0,0 +1,179 @@
+package revalidation
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.minekube.com/gate/pkg/edition/java/proxy"
+
+	"github.com/RMS-Server/RMS-Gate/internal/whitelist"
+)
+
+type Config struct {
+	IntervalSeconds  int
+	BatchSize        int
+	BatchDelayMillis int
+	ListenAddr       string
+	Token            string
+}
+
+// CheckFunc re-runs the whitelist check for an online player
+type CheckFunc func(ctx context.Context, player proxy.Player) whitelist.Result
+
+// RevokeFunc is called for players whose whitelist access was revoked
+type RevokeFunc func(player proxy.Player, result whitelist.Result)
+
+// Manager periodically re-checks online players and removes those who are no longer whitelisted
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	log    logr.Logger
+	proxy  *proxy.Proxy
+	cfg    *Config
+	check  CheckFunc
+	revoke RevokeFunc
+
+	// runMu prevents overlapping full passes
+	runMu sync.Mutex
+}
+
+func NewManager(ctx context.Context, log logr.Logger, p *proxy.Proxy, cfg *Config, check CheckFunc, revoke RevokeFunc) *Manager {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Manager{
+		ctx:    ctx,
+		cancel: cancel,
+		log:    log.WithName("revalidation"),
+		proxy:  p,
+		cfg:    cfg,
+		check:  check,
+		revoke: revoke,
+	}
+}
+
+// Start launches the periodic loop and, if configured, the trigger endpoint
+func (m *Manager) Start() {
+	go m.loop()
+
+	if m.cfg.ListenAddr != "" {
+		if m.cfg.Token == "" {
+			m.log.Error(nil, "Revalidation endpoint requires a token, not starting it", "addr", m.cfg.ListenAddr)
+		} else {
+			go m.serve()
+		}
+	}
+}
+
+func (m *Manager) loop() {
+	interval := time.Duration(m.cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.log.Info("Started periodic revalidation", "interval", interval, "batchSize", m.cfg.BatchSize)
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.RevalidateAll()
+		}
+	}
+}
+
+// RevalidateAll re-checks every online player in batches, pausing between batches
+// so that a full proxy does not flood the whitelist API
+func (m *Manager) RevalidateAll() {
+	if !m.runMu.TryLock() {
+		m.log.V(1).Info("Previous revalidation pass still running, skipping")
+		return
+	}
+	defer m.runMu.Unlock()
+
+	players := m.proxy.Players()
+	batchSize := m.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	delay := time.Duration(m.cfg.BatchDelayMillis) * time.Millisecond
+
+	revoked := 0
+	for start := 0; start < len(players); start += batchSize {
+		end := start + batchSize
+		if end > len(players) {
+			end = len(players)
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, player := range players[start:end] {
+			wg.Add(1)
+			go func(p proxy.Player) {
+				defer wg.Done()
+				if m.revalidate(p) {
+					mu.Lock()
+					revoked++
+					mu.Unlock()
+				}
+			}(player)
+		}
+		wg.Wait()
+
+		if end < len(players) && delay > 0 {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	m.log.V(1).Info("Revalidation pass finished", "players", len(players), "revoked", revoked)
+}
+
+// RevalidateUUID immediately re-checks the online player with the given UUID.
+// It returns false if no such player is online.
+func (m *Manager) RevalidateUUID(uuid string) (whitelist.Result, bool) {
+	want := normalizeUUID(uuid)
+	for _, p := range m.proxy.Players() {
+		if normalizeUUID(p.ID().String()) != want {
+			continue
+		}
+		result := m.check(m.ctx, p)
+		if result.Status == whitelist.NotInWhitelist {
+			m.log.Info("Whitelist revoked, disconnecting player", "player", p.Username(), "uuid", uuid, "trigger", "endpoint")
+			m.revoke(p, result)
+		}
+		return result, true
+	}
+	return whitelist.Result{}, false
+}
+
+// revalidate re-checks one player and reports whether they were removed
+func (m *Manager) revalidate(p proxy.Player) bool {
+	result := m.check(m.ctx, p)
+	switch result.Status {
+	case whitelist.NotInWhitelist:
+		m.log.Info("Whitelist revoked, disconnecting player", "player", p.Username(), "uuid", p.ID().String(), "reason", result.Reason)
+		m.revoke(p, result)
+		return true
+	case whitelist.ServerError:
+		// Keep players online while the API is unavailable
+		m.log.V(1).Info("Revalidation check failed, keeping player", "player", p.Username())
+	}
+	return false
+}
+
+func (m *Manager) Shutdown() {
+	m.log.Info("Shutting down revalidation")
+	m.cancel()
+}
+
+func normalizeUUID(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, "-", ""))
+}
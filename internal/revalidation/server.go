@@ -0,0 +1,83 @@
+package revalidation
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RMS-Server/RMS-Gate/internal/whitelist"
+)
+
+type revalidateRequest struct {
+	UUID string `json:"uuid"`
+}
+
+type revalidateResponse struct {
+	Online bool   `json:"online"`
+	Status string `json:"status,omitempty"`
+}
+
+// serve runs the endpoint the RMS API calls to revalidate a player right after a change
+func (m *Manager) serve() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/revalidate", m.handleRevalidate)
+
+	srv := &http.Server{
+		Addr:              m.cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-m.ctx.Done()
+		_ = srv.Close()
+	}()
+
+	m.log.Info("Revalidation endpoint listening", "addr", m.cfg.ListenAddr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		m.log.Error(err, "Revalidation endpoint stopped", "addr", m.cfg.ListenAddr)
+	}
+}
+
+func (m *Manager) handleRevalidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(m.cfg.Token)) != 1 {
+		m.log.Info("Rejected unauthenticated revalidation request", "remote", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req revalidateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.UUID == "" {
+		http.Error(w, "expected JSON body with uuid", http.StatusBadRequest)
+		return
+	}
+
+	result, online := m.RevalidateUUID(req.UUID)
+	resp := revalidateResponse{Online: online}
+	if online {
+		resp.Status = statusName(result.Status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func statusName(status whitelist.CheckResult) string {
+	switch status {
+	case whitelist.Allowed:
+		return "allowed"
+	case whitelist.NotInWhitelist:
+		return "denied"
+	default:
+		return "error"
+	}
+}
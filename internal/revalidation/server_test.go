@@ -0,0 +1,43 @@
+package revalidation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestHandleRevalidateRejects(t *testing.T) {
+	m := &Manager{log: logr.Discard(), cfg: &Config{Token: "s3cret"}}
+
+	tests := []struct {
+		name       string
+		method     string
+		auth       string
+		body       string
+		wantStatus int
+	}{
+		{"wrong method", http.MethodGet, "Bearer s3cret", "", http.StatusMethodNotAllowed},
+		{"no token", http.MethodPost, "", `{"uuid":"abc"}`, http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "Bearer nope", `{"uuid":"abc"}`, http.StatusUnauthorized},
+		{"token prefix", http.MethodPost, "Bearer s3cre", `{"uuid":"abc"}`, http.StatusUnauthorized},
+		{"not json", http.MethodPost, "Bearer s3cret", "abc", http.StatusBadRequest},
+		{"missing uuid", http.MethodPost, "Bearer s3cret", `{}`, http.StatusBadRequest},
+		{"oversized body", http.MethodPost, "Bearer s3cret", `{"uuid":"` + strings.Repeat("a", 5000) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/revalidate", strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			m.handleRevalidate(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
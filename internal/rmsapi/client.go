@@ -0,0 +1,247 @@
+package rmsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Header names used for request signing and response verification
+const (
+	HeaderTimestamp = "X-RMS-Timestamp"
+	HeaderNonce     = "X-RMS-Nonce"
+	HeaderSignature = "X-RMS-Signature"
+)
+
+// maxClockSkew bounds how old a signed response may be
+const maxClockSkew = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("response is not signed")
+	ErrBadSignature     = errors.New("response signature mismatch")
+	ErrStaleResponse    = errors.New("response timestamp outside allowed skew")
+)
+
+// AuthConfig configures how outgoing RMS API requests are authenticated.
+// All mechanisms are optional and can be combined.
+type AuthConfig struct {
+	// BearerToken is sent in the Authorization header
+	BearerToken string
+	// HMACSecret signs every request with a timestamp and nonce
+	HMACSecret string
+	// CAFile is a PEM bundle used instead of the system roots
+	CAFile string
+	// ClientCertFile and ClientKeyFile enable mTLS
+	ClientCertFile string
+	ClientKeyFile  string
+	// ResponseSecret verifies signed responses, defaults to HMACSecret
+	ResponseSecret string
+}
+
+// Client sends authenticated requests to the RMS API endpoints of its pool
+type Client struct {
+	http *http.Client
+	cfg  AuthConfig
+	pool *Pool
+}
+
+// NewClient builds a client for the given auth settings. timeout applies to each
+// attempt, so a hanging node does not use up the time for failover.
+func NewClient(cfg *AuthConfig, pool *Pool, timeout time.Duration) (*Client, error) {
+	if cfg == nil {
+		cfg = &AuthConfig{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile != "" || cfg.ClientCertFile != "" {
+		tlsCfg, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+
+	return &Client{
+		http: &http.Client{Timeout: timeout, Transport: transport},
+		cfg:  *cfg,
+		pool: pool,
+	}, nil
+}
+
+func buildTLSConfig(cfg *AuthConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// NewRequest creates a request with authentication headers and, if configured, an HMAC signature
+func (c *Client) NewRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.BearerToken)
+	}
+
+	if c.cfg.HMACSecret != "" {
+		nonce, err := newNonce()
+		if err != nil {
+			return nil, err
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderNonce, nonce)
+		req.Header.Set(HeaderSignature, sign(c.cfg.HMACSecret, method, req.URL.RequestURI(), timestamp, nonce, body))
+	}
+	return req, nil
+}
+
+// Do sends the request
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.http.Do(req)
+}
+
+// Send sends the request to the pool's endpoints in order until one answers
+// without a transport error or 5xx status. The returned request is the one that
+// produced the response, for use with VerifyResponse.
+func (c *Client) Send(ctx context.Context, method, path string, body []byte) (*http.Request, *http.Response, error) {
+	candidates := c.pool.Candidates()
+	if len(candidates) == 0 {
+		return nil, nil, errors.New("no RMS API endpoints configured")
+	}
+
+	var lastErr error
+	for _, e := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+
+		req, err := c.NewRequest(ctx, method, e.URL+path, body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := c.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			c.pool.RecordSuccess(e)
+			return req, resp, nil
+		}
+
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("%s returned status %d", e.URL, resp.StatusCode)
+		}
+		lastErr = err
+		// Our own context ending says nothing about the node's health
+		if ctx.Err() == nil {
+			c.pool.RecordFailure(e, err)
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = ctx.Err()
+	}
+	return nil, nil, fmt.Errorf("all RMS API endpoints failed: %w", lastErr)
+}
+
+// Pool returns the endpoint pool
+func (c *Client) Pool() *Pool {
+	return c.pool
+}
+
+// VerifyResponse checks the signature of a response body. The signature covers the
+// response timestamp, the request nonce and the body, so responses cannot be replayed.
+func (c *Client) VerifyResponse(req *http.Request, resp *http.Response, body []byte) error {
+	secret := c.cfg.ResponseSecret
+	if secret == "" {
+		secret = c.cfg.HMACSecret
+	}
+	if secret == "" {
+		return errors.New("no response secret configured")
+	}
+
+	signature := resp.Header.Get(HeaderSignature)
+	timestamp := resp.Header.Get(HeaderTimestamp)
+	if signature == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleResponse
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return ErrStaleResponse
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s", timestamp, req.Header.Get(HeaderNonce), bodyHash(body))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ReadBody reads a bounded response body
+func ReadBody(resp *http.Response, limit int64) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// sign computes the request signature:
+// hex(HMAC-SHA256(secret, METHOD \n URI \n timestamp \n nonce \n hex(SHA256(body))))
+func sign(secret, method, uri, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, uri, timestamp, nonce, bodyHash(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func bodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,218 @@
+package rmsapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// hexHMAC is an independent implementation of the documented signature format
+func hexHMAC(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for i, p := range parts {
+		if i > 0 {
+			io.WriteString(mac, "\n")
+		}
+		io.WriteString(mac, p)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sha256Hex(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestNewRequestSigning(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        AuthConfig
+		body       []byte
+		wantBearer string
+		wantSigned bool
+	}{
+		{name: "no auth"},
+		{name: "bearer only", cfg: AuthConfig{BearerToken: "tok"}, wantBearer: "Bearer tok"},
+		{name: "hmac without body", cfg: AuthConfig{HMACSecret: "s3cret"}, wantSigned: true},
+		{name: "hmac with body", cfg: AuthConfig{HMACSecret: "s3cret"}, body: []byte(`{"a":1}`), wantSigned: true},
+		{name: "bearer and hmac", cfg: AuthConfig{BearerToken: "tok", HMACSecret: "s3cret"}, body: []byte("x"), wantBearer: "Bearer tok", wantSigned: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(&tt.cfg, NewPool(nil, nil, 1, 0), time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := c.NewRequest(context.Background(), http.MethodPost, "http://api.example/api/check?name=Steve", tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := req.Header.Get("Authorization"); got != tt.wantBearer {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantBearer)
+			}
+			if got := req.Header.Get("Content-Type"); (got != "") != (tt.body != nil) {
+				t.Errorf("Content-Type = %q with body %q", got, tt.body)
+			}
+
+			sig, ts, nonce := req.Header.Get(HeaderSignature), req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderNonce)
+			if !tt.wantSigned {
+				if sig != "" || ts != "" || nonce != "" {
+					t.Fatalf("unsigned request carries signature headers: %v", req.Header)
+				}
+				return
+			}
+			if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+				t.Fatalf("timestamp %q is not unix seconds", ts)
+			}
+			if len(nonce) != 32 {
+				t.Fatalf("nonce %q, want 32 hex characters", nonce)
+			}
+			want := hexHMAC(tt.cfg.HMACSecret, http.MethodPost, "/api/check?name=Steve", ts, nonce, sha256Hex(tt.body))
+			if sig != want {
+				t.Fatalf("signature = %s, want %s", sig, want)
+			}
+		})
+	}
+}
+
+func TestNewRequestNonceUnique(t *testing.T) {
+	c, err := NewClient(&AuthConfig{HMACSecret: "s"}, NewPool(nil, nil, 1, 0), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for range 50 {
+		req, err := c.NewRequest(context.Background(), http.MethodGet, "http://api.example/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := req.Header.Get(HeaderNonce)
+		if seen[nonce] {
+			t.Fatalf("nonce %s repeated", nonce)
+		}
+		seen[nonce] = true
+	}
+}
+
+func TestVerifyResponse(t *testing.T) {
+	const nonce = "0123456789abcdef0123456789abcdef"
+	body := []byte(`{"success":true}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+
+	signed := func(secret, ts string) map[string]string {
+		return map[string]string{
+			HeaderTimestamp: ts,
+			HeaderSignature: hexHMAC(secret, ts, nonce, sha256Hex(body)),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     AuthConfig
+		headers map[string]string
+		body    []byte
+		nonce   string
+		wantErr error
+		// anyErr accepts any error, for failures without a sentinel
+		anyErr bool
+	}{
+		{name: "valid", cfg: AuthConfig{HMACSecret: "req"}, headers: signed("req", now)},
+		{name: "response secret preferred", cfg: AuthConfig{HMACSecret: "req", ResponseSecret: "resp"}, headers: signed("resp", now)},
+		{name: "request secret rejected when response secret set", cfg: AuthConfig{HMACSecret: "req", ResponseSecret: "resp"}, headers: signed("req", now), wantErr: ErrBadSignature},
+		{name: "no secret", headers: signed("req", now), anyErr: true},
+		{name: "unsigned", cfg: AuthConfig{HMACSecret: "req"}, wantErr: ErrMissingSignature},
+		{name: "missing timestamp", cfg: AuthConfig{HMACSecret: "req"}, headers: map[string]string{HeaderSignature: "abc"}, wantErr: ErrMissingSignature},
+		{name: "wrong secret", cfg: AuthConfig{HMACSecret: "req"}, headers: signed("other", now), wantErr: ErrBadSignature},
+		{name: "tampered body", cfg: AuthConfig{HMACSecret: "req"}, headers: signed("req", now), body: []byte(`{"success":false}`), wantErr: ErrBadSignature},
+		{name: "replayed for another request", cfg: AuthConfig{HMACSecret: "req"}, headers: signed("req", now), nonce: "ffffffffffffffffffffffffffffffff", wantErr: ErrBadSignature},
+		{name: "stale", cfg: AuthConfig{HMACSecret: "req"}, headers: signed("req", stale), wantErr: ErrStaleResponse},
+		{name: "future", cfg: AuthConfig{HMACSecret: "req"}, headers: signed("req", future), wantErr: ErrStaleResponse},
+		{name: "bad timestamp", cfg: AuthConfig{HMACSecret: "req"}, headers: map[string]string{HeaderTimestamp: "soon", HeaderSignature: "abc"}, wantErr: ErrStaleResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(&tt.cfg, NewPool(nil, nil, 1, 0), time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/mcdr/permission", nil)
+			req.Header.Set(HeaderNonce, nonce)
+			if tt.nonce != "" {
+				req.Header.Set(HeaderNonce, tt.nonce)
+			}
+			resp := &http.Response{Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			got := body
+			if tt.body != nil {
+				got = tt.body
+			}
+
+			err = c.VerifyResponse(req, resp, got)
+			switch {
+			case tt.anyErr:
+				if err == nil {
+					t.Fatal("VerifyResponse succeeded, want an error")
+				}
+			case !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil):
+				t.Fatalf("VerifyResponse = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSignedRoundTrip has a server check the request signature and sign its response,
+// the way the RMS API does
+func TestSignedRoundTrip(t *testing.T) {
+	const secret = "shared"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, nonce := r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce)
+		if r.Header.Get(HeaderSignature) != hexHMAC(secret, r.Method, r.URL.RequestURI(), ts, nonce, sha256Hex(body)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		out := []byte(fmt.Sprintf(`{"echo":%q}`, body))
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		w.Header().Set(HeaderTimestamp, now)
+		w.Header().Set(HeaderSignature, hexHMAC(secret, now, nonce, sha256Hex(out)))
+		w.Write(out)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(&AuthConfig{HMACSecret: secret}, NewPool([]string{srv.URL}, nil, 1, time.Minute), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, resp, err := c.Send(context.Background(), http.MethodPost, "/api/check", []byte(`{"name":"Steve"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("server rejected the request signature: %d", resp.StatusCode)
+	}
+
+	body, err := ReadBody(resp, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.VerifyResponse(req, resp, body); err != nil {
+		t.Fatalf("VerifyResponse = %v", err)
+	}
+}
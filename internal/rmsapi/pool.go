@@ -0,0 +1,141 @@
+package rmsapi
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Endpoint is one RMS API node with a small health state
+type Endpoint struct {
+	URL      string
+	Priority int
+
+	failCount     atomic.Int32
+	cooldownUntil atomic.Int64 // unix nano, 0 when not cooling down
+	lastError     atomic.Value // string
+}
+
+// EndpointStatus is a snapshot of an endpoint for display
+type EndpointStatus struct {
+	URL       string
+	Priority  int
+	Active    bool
+	Failures  int
+	Cooldown  time.Duration
+	LastError string
+}
+
+// Pool holds the API endpoints ordered by priority. Endpoints that fail
+// failThreshold times in a row are skipped for the cooldown period.
+type Pool struct {
+	endpoints     []*Endpoint
+	failThreshold int32
+	cooldown      time.Duration
+
+	active   *Endpoint
+	activeMu sync.RWMutex
+}
+
+// NewPool creates a pool from base URLs and priorities. Lower priorities are tried first,
+// equal priorities keep their configured order.
+func NewPool(urls []string, priorities []int, failThreshold int, cooldown time.Duration) *Pool {
+	if failThreshold < 1 {
+		failThreshold = 1
+	}
+
+	endpoints := make([]*Endpoint, 0, len(urls))
+	for i, url := range urls {
+		e := &Endpoint{URL: strings.TrimSuffix(url, "/")}
+		if i < len(priorities) {
+			e.Priority = priorities[i]
+		}
+		endpoints = append(endpoints, e)
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].Priority < endpoints[j].Priority
+	})
+
+	p := &Pool{
+		endpoints:     endpoints,
+		failThreshold: int32(failThreshold),
+		cooldown:      cooldown,
+	}
+	if len(endpoints) > 0 {
+		p.active = endpoints[0]
+	}
+	return p
+}
+
+// Candidates returns the endpoints to try in order: available endpoints by priority,
+// followed by cooling down ones so a fully failed pool is still retried.
+func (p *Pool) Candidates() []*Endpoint {
+	now := time.Now().UnixNano()
+	available := make([]*Endpoint, 0, len(p.endpoints))
+	var cooling []*Endpoint
+	for _, e := range p.endpoints {
+		if e.cooldownUntil.Load() > now {
+			cooling = append(cooling, e)
+		} else {
+			available = append(available, e)
+		}
+	}
+	return append(available, cooling...)
+}
+
+// RecordSuccess resets the endpoint's failure state and makes it the active node
+func (p *Pool) RecordSuccess(e *Endpoint) {
+	e.failCount.Store(0)
+	e.cooldownUntil.Store(0)
+
+	p.activeMu.Lock()
+	p.active = e
+	p.activeMu.Unlock()
+}
+
+// RecordFailure counts a failure and starts the cooldown once the threshold is reached.
+// It reports whether the endpoint entered cooldown.
+func (p *Pool) RecordFailure(e *Endpoint, err error) bool {
+	if err != nil {
+		e.lastError.Store(err.Error())
+	}
+	if e.failCount.Add(1) < p.failThreshold {
+		return false
+	}
+	e.failCount.Store(0)
+	e.cooldownUntil.Store(time.Now().Add(p.cooldown).UnixNano())
+	return true
+}
+
+// Active returns the endpoint that served the last successful request
+func (p *Pool) Active() *Endpoint {
+	p.activeMu.RLock()
+	defer p.activeMu.RUnlock()
+	return p.active
+}
+
+// Status returns a snapshot of all endpoints in priority order
+func (p *Pool) Status() []EndpointStatus {
+	active := p.Active()
+	now := time.Now()
+
+	status := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		s := EndpointStatus{
+			URL:      e.URL,
+			Priority: e.Priority,
+			Active:   e == active,
+			Failures: int(e.failCount.Load()),
+		}
+		if until := time.Unix(0, e.cooldownUntil.Load()); until.After(now) {
+			s.Cooldown = until.Sub(now)
+		}
+		if msg, ok := e.lastError.Load().(string); ok {
+			s.LastError = msg
+		}
+		status = append(status, s)
+	}
+	return status
+}
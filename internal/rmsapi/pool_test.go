@@ -0,0 +1,184 @@
+package rmsapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPoolOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		urls       []string
+		priorities []int
+		want       []string
+	}{
+		{"no priorities keeps order", []string{"http://a", "http://b/"}, nil, []string{"http://a", "http://b"}},
+		{"lower priority first", []string{"http://a", "http://b", "http://c"}, []int{2, 0, 1}, []string{"http://b", "http://c", "http://a"}},
+		{"equal priorities stable", []string{"http://a", "http://b", "http://c"}, []int{1, 0, 1}, []string{"http://b", "http://a", "http://c"}},
+		{"missing priorities default to zero", []string{"http://a", "http://b"}, []int{5}, []string{"http://b", "http://a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool(tt.urls, tt.priorities, 1, time.Minute)
+			got := urls(p.Candidates())
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("Candidates() = %v, want %v", got, tt.want)
+			}
+			if p.Active().URL != tt.want[0] {
+				t.Fatalf("Active() = %s, want %s", p.Active().URL, tt.want[0])
+			}
+		})
+	}
+}
+
+func TestPoolCooldown(t *testing.T) {
+	p := NewPool([]string{"http://a", "http://b"}, nil, 2, time.Minute)
+	a := p.Candidates()[0]
+
+	if p.RecordFailure(a, nil) {
+		t.Fatal("first failure started the cooldown, threshold is 2")
+	}
+	if got := urls(p.Candidates()); got[0] != "http://a" {
+		t.Fatalf("Candidates() after one failure = %v", got)
+	}
+	if !p.RecordFailure(a, errTest("boom")) {
+		t.Fatal("second failure did not start the cooldown")
+	}
+	// Cooling endpoints stay at the end so a fully failed pool is still retried
+	if got := urls(p.Candidates()); strings.Join(got, ",") != "http://b,http://a" {
+		t.Fatalf("Candidates() during cooldown = %v", got)
+	}
+
+	status := p.Status()
+	if status[0].Cooldown <= 0 || status[0].LastError != "boom" {
+		t.Fatalf("Status()[0] = %+v, want cooldown and last error", status[0])
+	}
+
+	p.RecordSuccess(a)
+	if got := urls(p.Candidates()); got[0] != "http://a" {
+		t.Fatalf("Candidates() after success = %v", got)
+	}
+	if p.Status()[0].Cooldown != 0 || !p.Status()[0].Active {
+		t.Fatalf("Status()[0] after success = %+v", p.Status()[0])
+	}
+}
+
+func TestSendFailover(t *testing.T) {
+	type node struct {
+		status int
+		hang   bool
+	}
+	tests := []struct {
+		name       string
+		nodes      []node
+		wantNode   int // index of the node that answers, -1 for an error
+		wantStatus int
+	}{
+		{"first healthy", []node{{status: 200}, {status: 200}}, 0, 200},
+		{"skip 5xx", []node{{status: 503}, {status: 200}}, 1, 200},
+		{"client errors are answers", []node{{status: 404}, {status: 200}}, 0, 404},
+		{"skip timeout", []node{{hang: true}, {status: 200}}, 1, 200},
+		{"all failed", []node{{status: 500}, {status: 502}}, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addrs []string
+			hits := make([]int, len(tt.nodes))
+			for i, n := range tt.nodes {
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					hits[i]++
+					if n.hang {
+						<-r.Context().Done()
+						return
+					}
+					w.WriteHeader(n.status)
+				}))
+				defer srv.Close()
+				addrs = append(addrs, srv.URL)
+			}
+
+			pool := NewPool(addrs, nil, 1, time.Minute)
+			c, err := NewClient(nil, pool, 200*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, resp, err := c.Send(context.Background(), http.MethodGet, "/api/ping", nil)
+			if tt.wantNode < 0 {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("Send succeeded, want an error")
+				}
+				for i, h := range hits {
+					if h != 1 {
+						t.Fatalf("node %d was tried %d times, want 1", i, h)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := pool.Active().URL; got != addrs[tt.wantNode] {
+				t.Fatalf("Active() = %s, want node %d", got, tt.wantNode)
+			}
+			// A failed node cools down with a threshold of 1 and is tried last next time
+			if tt.wantNode > 0 && pool.Candidates()[0].URL != addrs[tt.wantNode] {
+				t.Fatalf("failed node is still tried first: %v", urls(pool.Candidates()))
+			}
+		})
+	}
+}
+
+func TestSendNoEndpoints(t *testing.T) {
+	c, err := NewClient(nil, NewPool(nil, nil, 1, time.Minute), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Send(context.Background(), http.MethodGet, "/", nil); err == nil {
+		t.Fatal("Send with an empty pool succeeded")
+	}
+}
+
+func TestSendCanceledKeepsHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	pool := NewPool([]string{srv.URL}, nil, 1, time.Minute)
+	c, err := NewClient(nil, pool, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.Send(ctx, http.MethodGet, "/", nil); err == nil {
+		t.Fatal("Send succeeded after the context ended")
+	}
+	if s := pool.Status()[0]; s.Cooldown != 0 || s.Failures != 0 {
+		t.Fatalf("our own cancellation counted against the node: %+v", s)
+	}
+}
+
+type errTest string
+
+func (e errTest) Error() string { return string(e) }
+
+func urls(endpoints []*Endpoint) []string {
+	result := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		result[i] = e.URL
+	}
+	return result
+}
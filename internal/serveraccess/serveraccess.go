@@ -0,0 +1,83 @@
+package serveraccess
+
+import (
+	"strings"
+)
+
+// Rule restricts a server to players at MinLevel or above and to the listed members.
+// A player passes when either condition grants access; an empty rule allows everyone.
+type Rule struct {
+	MinLevel int
+	// Members are usernames or UUIDs, with or without dashes
+	Members []string
+}
+
+type compiledRule struct {
+	minLevel int
+	members  map[string]struct{}
+}
+
+// Policy decides which players may join which servers
+type Policy struct {
+	rules map[string]*compiledRule
+}
+
+func NewPolicy(rules map[string]*Rule) *Policy {
+	p := &Policy{rules: make(map[string]*compiledRule, len(rules))}
+	for server, rule := range rules {
+		if rule == nil || (rule.MinLevel <= 0 && len(rule.Members) == 0) {
+			continue
+		}
+		cr := &compiledRule{minLevel: rule.MinLevel, members: make(map[string]struct{}, len(rule.Members))}
+		for _, m := range rule.Members {
+			if key := memberKey(m); key != "" {
+				cr.members[key] = struct{}{}
+			}
+		}
+		p.rules[strings.ToLower(server)] = cr
+	}
+	return p
+}
+
+// Restricted reports whether the server has an access rule
+func (p *Policy) Restricted(server string) bool {
+	_, ok := p.rules[strings.ToLower(server)]
+	return ok
+}
+
+// Servers returns the number of restricted servers
+func (p *Policy) Servers() int {
+	return len(p.rules)
+}
+
+// Allowed reports whether the player may join the server. level is only called for
+// restricted servers whose member list does not already admit the player.
+func (p *Policy) Allowed(server, uuid, username string, level func() int) bool {
+	rule, ok := p.rules[strings.ToLower(server)]
+	if !ok {
+		return true
+	}
+	if rule.isMember(uuid, username) {
+		return true
+	}
+	return rule.minLevel > 0 && level() >= rule.minLevel
+}
+
+func (r *compiledRule) isMember(uuid, username string) bool {
+	if key := memberKey(uuid); key != "" {
+		if _, ok := r.members[key]; ok {
+			return true
+		}
+	}
+	_, ok := r.members[memberKey(username)]
+	return ok
+}
+
+// memberKey lowercases names and strips the dashes of UUIDs so both notations match
+func memberKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 36 && strings.Count(s, "-") == 4 {
+		s = strings.ReplaceAll(s, "-", "")
+	}
+	return s
+}
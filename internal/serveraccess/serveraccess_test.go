@@ -0,0 +1,70 @@
+package serveraccess
+
+import "testing"
+
+func TestAllowed(t *testing.T) {
+	p := NewPolicy(map[string]*Rule{
+		"Admin":   {MinLevel: 4},
+		"event":   {Members: []string{"Steve", "853C80EF-3C37-49FD-AA49-938B674ADAE6", " "}},
+		"staff":   {MinLevel: 3, Members: []string{"Alex"}},
+		"open":    {},
+		"missing": nil,
+	})
+
+	tests := []struct {
+		name     string
+		server   string
+		uuid     string
+		username string
+		level    int
+		want     bool
+	}{
+		{"unrestricted server", "lobby", "", "Anyone", 0, true},
+		{"empty rule ignored", "open", "", "Anyone", 0, true},
+		{"nil rule ignored", "missing", "", "Anyone", 0, true},
+		{"level below", "admin", "", "Mod", 3, false},
+		{"level reached", "ADMIN", "", "Owner", 4, true},
+		{"member by name", "event", "", "steve", 0, true},
+		{"member by uuid with dashes", "event", "853c80ef-3c37-49fd-aa49-938b674adae6", "Renamed", 0, true},
+		{"member by uuid without dashes", "event", "853c80ef3c3749fdaa49938b674adae6", "Renamed", 0, true},
+		{"not a member", "event", "", "Alex", 10, false},
+		{"blank name is no member", "event", "", "", 0, false},
+		{"member without level", "staff", "", "Alex", 0, true},
+		{"level without membership", "staff", "", "Mod", 3, true},
+		{"neither", "staff", "", "Player", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Allowed(tt.server, tt.uuid, tt.username, func() int { return tt.level }); got != tt.want {
+				t.Fatalf("Allowed(%s, %s) = %v, want %v", tt.server, tt.username, got, tt.want)
+			}
+		})
+	}
+
+	if p.Servers() != 3 {
+		t.Fatalf("Servers() = %d, want 3", p.Servers())
+	}
+}
+
+func TestLevelOnlyLookedUpWhenNeeded(t *testing.T) {
+	p := NewPolicy(map[string]*Rule{"event": {Members: []string{"Steve"}}, "admin": {MinLevel: 4}})
+
+	tests := []struct {
+		server   string
+		username string
+		want     bool
+	}{
+		{"lobby", "Steve", false},
+		{"event", "Steve", false},
+		// a member-only rule never admits by level
+		{"event", "Alex", false},
+		{"admin", "Alex", true},
+	}
+	for _, tt := range tests {
+		called := false
+		p.Allowed(tt.server, "", tt.username, func() int { called = true; return 0 })
+		if called != tt.want {
+			t.Errorf("Allowed(%s, %s) looked up the level: %v, want %v", tt.server, tt.username, called, tt.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package throttle
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+)
+
+// idleTTL is how long an IP without attempts or ban is remembered
+const idleTTL = 10 * time.Minute
+
+type Config struct {
+	PerIPPerMinute int
+	PerIPBurst     int
+	GlobalPerSec   int
+	GlobalBurst    int
+	// BanThreshold rejected attempts within BanWindow ban the IP for BanDuration; 0 disables auto-ban
+	BanThreshold int
+	BanWindow    time.Duration
+	BanDuration  time.Duration
+	// MaxInFlight caps concurrent whitelist checks; 0 disables the cap
+	MaxInFlight  int
+	InFlightWait time.Duration
+}
+
+type Verdict int
+
+const (
+	Allowed Verdict = iota
+	RateLimited
+	GlobalLimited
+	Banned
+)
+
+type ipState struct {
+	limiter     *rate.Limiter
+	lastSeen    time.Time
+	violations  int
+	windowStart time.Time
+	bannedUntil time.Time
+}
+
+// Offender is an IP that was recently rate limited or banned
+type Offender struct {
+	IP          string
+	Violations  int
+	BannedUntil time.Time
+}
+
+type Stats struct {
+	Allowed     int64
+	Throttled   int64
+	BanRejects  int64
+	BusyRejects int64
+	AutoBans    int64
+	InFlight    int
+	TrackedIPs  int
+}
+
+// Limiter throttles login attempts per IP and globally, and bounds concurrent checks
+type Limiter struct {
+	ctx    context.Context
+	log    logr.Logger
+	cfg    *Config
+	global *rate.Limiter
+
+	ips   map[string]*ipState
+	ipsMu sync.Mutex
+
+	inFlight chan struct{}
+
+	allowed     atomic.Int64
+	throttled   atomic.Int64
+	banRejects  atomic.Int64
+	busyRejects atomic.Int64
+	autoBans    atomic.Int64
+}
+
+func NewLimiter(ctx context.Context, log logr.Logger, cfg *Config) *Limiter {
+	l := &Limiter{
+		ctx: ctx,
+		log: log.WithName("throttle"),
+		cfg: cfg,
+		ips: make(map[string]*ipState),
+	}
+	if cfg.GlobalPerSec > 0 {
+		l.global = rate.NewLimiter(rate.Limit(cfg.GlobalPerSec), max(cfg.GlobalBurst, 1))
+	}
+	if cfg.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+	go l.cleanupLoop()
+	return l
+}
+
+// Allow records a login attempt from ip and reports whether it may proceed
+func (l *Limiter) Allow(ip string) (Verdict, time.Time) {
+	now := time.Now()
+
+	l.ipsMu.Lock()
+	st := l.ips[ip]
+	if st == nil {
+		st = &ipState{}
+		if l.cfg.PerIPPerMinute > 0 {
+			st.limiter = rate.NewLimiter(rate.Limit(float64(l.cfg.PerIPPerMinute)/60), max(l.cfg.PerIPBurst, 1))
+		}
+		l.ips[ip] = st
+	}
+	st.lastSeen = now
+
+	if now.Before(st.bannedUntil) {
+		until := st.bannedUntil
+		l.ipsMu.Unlock()
+		l.banRejects.Add(1)
+		return Banned, until
+	}
+
+	if st.limiter != nil && !st.limiter.AllowN(now, 1) {
+		until := l.recordViolation(ip, st, now)
+		l.ipsMu.Unlock()
+		l.throttled.Add(1)
+		if !until.IsZero() {
+			return Banned, until
+		}
+		return RateLimited, time.Time{}
+	}
+	l.ipsMu.Unlock()
+
+	if l.global != nil && !l.global.AllowN(now, 1) {
+		l.throttled.Add(1)
+		return GlobalLimited, time.Time{}
+	}
+
+	l.allowed.Add(1)
+	return Allowed, time.Time{}
+}
+
+// recordViolation counts a rejected attempt and bans the IP once the threshold is hit.
+// It returns the ban expiry, or zero if the IP was not banned. Caller holds ipsMu.
+func (l *Limiter) recordViolation(ip string, st *ipState, now time.Time) time.Time {
+	if l.cfg.BanThreshold <= 0 {
+		st.violations++
+		return time.Time{}
+	}
+	if now.Sub(st.windowStart) > l.cfg.BanWindow {
+		st.windowStart = now
+		st.violations = 0
+	}
+	st.violations++
+	if st.violations < l.cfg.BanThreshold {
+		return time.Time{}
+	}
+
+	st.bannedUntil = now.Add(l.cfg.BanDuration)
+	st.violations = 0
+	l.autoBans.Add(1)
+	l.log.Info("IP auto-banned for exceeding login rate", "ip", ip, "until", st.bannedUntil)
+	return st.bannedUntil
+}
+
+// Acquire takes an in-flight slot, waiting up to InFlightWait. The returned
+// release func must be called when the check is done; ok is false if no slot was free.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), ok bool) {
+	if l.inFlight == nil {
+		return func() {}, true
+	}
+
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, true
+	default:
+	}
+
+	timer := time.NewTimer(l.cfg.InFlightWait)
+	defer timer.Stop()
+	select {
+	case l.inFlight <- struct{}{}:
+		return func() { <-l.inFlight }, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	l.busyRejects.Add(1)
+	return nil, false
+}
+
+// Unban lifts an auto-ban early
+func (l *Limiter) Unban(ip string) bool {
+	l.ipsMu.Lock()
+	defer l.ipsMu.Unlock()
+
+	st, ok := l.ips[ip]
+	if !ok || !time.Now().Before(st.bannedUntil) {
+		return false
+	}
+	st.bannedUntil = time.Time{}
+	st.violations = 0
+	return true
+}
+
+// Offenders returns banned IPs and IPs with violations in the current window, banned first
+func (l *Limiter) Offenders() []Offender {
+	now := time.Now()
+
+	l.ipsMu.Lock()
+	var offenders []Offender
+	for ip, st := range l.ips {
+		banned := now.Before(st.bannedUntil)
+		if !banned && st.violations == 0 {
+			continue
+		}
+		o := Offender{IP: ip, Violations: st.violations}
+		if banned {
+			o.BannedUntil = st.bannedUntil
+		}
+		offenders = append(offenders, o)
+	}
+	l.ipsMu.Unlock()
+
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].BannedUntil.IsZero() != offenders[j].BannedUntil.IsZero() {
+			return !offenders[i].BannedUntil.IsZero()
+		}
+		return offenders[i].Violations > offenders[j].Violations
+	})
+	return offenders
+}
+
+func (l *Limiter) Stats() Stats {
+	l.ipsMu.Lock()
+	tracked := len(l.ips)
+	l.ipsMu.Unlock()
+
+	return Stats{
+		Allowed:     l.allowed.Load(),
+		Throttled:   l.throttled.Load(),
+		BanRejects:  l.banRejects.Load(),
+		BusyRejects: l.busyRejects.Load(),
+		AutoBans:    l.autoBans.Load(),
+		InFlight:    len(l.inFlight),
+		TrackedIPs:  tracked,
+	}
+}
+
+func (l *Limiter) Config() *Config {
+	return l.cfg
+}
+
+func (l *Limiter) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
+
+func (l *Limiter) cleanup() {
+	now := time.Now()
+
+	l.ipsMu.Lock()
+	defer l.ipsMu.Unlock()
+
+	for ip, st := range l.ips {
+		if now.Sub(st.lastSeen) > idleTTL && !now.Before(st.bannedUntil) {
+			delete(l.ips, ip)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func newTestLimiter(t *testing.T, cfg *Config) *Limiter {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewLimiter(ctx, logr.Discard(), cfg)
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		ips  []string
+		want []Verdict
+	}{
+		{
+			name: "unlimited",
+			ips:  []string{"1.1.1.1", "1.1.1.1", "1.1.1.1"},
+			want: []Verdict{Allowed, Allowed, Allowed},
+		},
+		{
+			name: "per ip burst",
+			cfg:  Config{PerIPPerMinute: 1, PerIPBurst: 2},
+			ips:  []string{"1.1.1.1", "1.1.1.1", "1.1.1.1", "2.2.2.2"},
+			want: []Verdict{Allowed, Allowed, RateLimited, Allowed},
+		},
+		{
+			name: "zero burst allows one",
+			cfg:  Config{PerIPPerMinute: 1},
+			ips:  []string{"1.1.1.1", "1.1.1.1"},
+			want: []Verdict{Allowed, RateLimited},
+		},
+		{
+			name: "global limit spans ips",
+			cfg:  Config{GlobalPerSec: 1, GlobalBurst: 2},
+			ips:  []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"},
+			want: []Verdict{Allowed, Allowed, GlobalLimited},
+		},
+		{
+			name: "auto ban at threshold",
+			cfg:  Config{PerIPPerMinute: 1, PerIPBurst: 1, BanThreshold: 2, BanWindow: time.Minute, BanDuration: time.Hour},
+			ips:  []string{"1.1.1.1", "1.1.1.1", "1.1.1.1", "1.1.1.1", "2.2.2.2"},
+			want: []Verdict{Allowed, RateLimited, Banned, Banned, Allowed},
+		},
+		{
+			name: "no ban without threshold",
+			cfg:  Config{PerIPPerMinute: 1, PerIPBurst: 1, BanWindow: time.Minute, BanDuration: time.Hour},
+			ips:  []string{"1.1.1.1", "1.1.1.1", "1.1.1.1", "1.1.1.1"},
+			want: []Verdict{Allowed, RateLimited, RateLimited, RateLimited},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(t, &tt.cfg)
+			for i, ip := range tt.ips {
+				got, until := l.Allow(ip)
+				if got != tt.want[i] {
+					t.Fatalf("attempt %d from %s = %v, want %v", i, ip, got, tt.want[i])
+				}
+				if (got == Banned) != !until.IsZero() {
+					t.Fatalf("attempt %d from %s: verdict %v with ban expiry %v", i, ip, got, until)
+				}
+			}
+		})
+	}
+}
+
+func TestUnban(t *testing.T) {
+	l := newTestLimiter(t, &Config{PerIPPerMinute: 1, PerIPBurst: 1, BanThreshold: 2, BanWindow: time.Minute, BanDuration: time.Hour})
+
+	l.Allow("1.1.1.1")
+	l.Allow("1.1.1.1")
+	if v, _ := l.Allow("1.1.1.1"); v != Banned {
+		t.Fatalf("third attempt = %v, want Banned", v)
+	}
+	if got := l.Offenders(); len(got) != 1 || got[0].IP != "1.1.1.1" || got[0].BannedUntil.IsZero() {
+		t.Fatalf("Offenders() = %+v, want 1.1.1.1 banned", got)
+	}
+
+	if !l.Unban("1.1.1.1") {
+		t.Fatal("Unban of a banned IP = false")
+	}
+	if l.Unban("1.1.1.1") {
+		t.Fatal("second Unban = true")
+	}
+	if l.Unban("9.9.9.9") {
+		t.Fatal("Unban of an unknown IP = true")
+	}
+	// The rate limit still applies, but the violation count starts over
+	if v, _ := l.Allow("1.1.1.1"); v != RateLimited {
+		t.Fatalf("attempt after Unban = %v, want RateLimited", v)
+	}
+	if s := l.Stats(); s.AutoBans != 1 || s.Allowed != 1 {
+		t.Fatalf("Stats() = %+v, want 1 auto-ban and 1 allowed", s)
+	}
+}
+
+func TestOffendersOrder(t *testing.T) {
+	l := newTestLimiter(t, &Config{PerIPPerMinute: 1, PerIPBurst: 1, BanThreshold: 3, BanWindow: time.Minute, BanDuration: time.Hour})
+
+	attempts := map[string]int{"1.1.1.1": 2, "2.2.2.2": 3, "3.3.3.3": 4, "4.4.4.4": 1}
+	for ip, n := range attempts {
+		for range n {
+			l.Allow(ip)
+		}
+	}
+
+	got := l.Offenders()
+	want := []string{"3.3.3.3", "2.2.2.2", "1.1.1.1"}
+	if len(got) != len(want) {
+		t.Fatalf("Offenders() = %+v, want %v", got, want)
+	}
+	for i, ip := range want {
+		if got[i].IP != ip {
+			t.Fatalf("Offenders()[%d] = %s, want %s (all: %+v)", i, got[i].IP, ip, got)
+		}
+	}
+	if got[0].BannedUntil.IsZero() {
+		t.Fatalf("banned IP listed without expiry: %+v", got[0])
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		attempts int
+		want     int
+	}{
+		{"unbounded", Config{}, 5, 5},
+		{"capped", Config{MaxInFlight: 2, InFlightWait: 10 * time.Millisecond}, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(t, &tt.cfg)
+
+			var releases []func()
+			for range tt.attempts {
+				if release, ok := l.Acquire(context.Background()); ok {
+					releases = append(releases, release)
+				}
+			}
+			if len(releases) != tt.want {
+				t.Fatalf("acquired %d slots, want %d", len(releases), tt.want)
+			}
+			if busy := int64(tt.attempts - tt.want); l.Stats().BusyRejects != busy {
+				t.Fatalf("BusyRejects = %d, want %d", l.Stats().BusyRejects, busy)
+			}
+
+			for _, release := range releases {
+				release()
+			}
+			if _, ok := l.Acquire(context.Background()); !ok {
+				t.Fatal("Acquire after release failed")
+			}
+		})
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	l := newTestLimiter(t, &Config{MaxInFlight: 1, InFlightWait: time.Second})
+
+	release, ok := l.Acquire(context.Background())
+	if !ok {
+		t.Fatal("first Acquire failed")
+	}
+	time.AfterFunc(20*time.Millisecond, release)
+
+	if _, ok := l.Acquire(context.Background()); !ok {
+		t.Fatal("Acquire did not get the slot released while waiting")
+	}
+}
@@ -0,0 +1,168 @@
+package whitelist
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Approval is the last positive whitelist decision recorded for a player
+type Approval struct {
+	UUID       string
+	Username   string
+	ApprovedAt time.Time
+}
+
+// GraceCache persists the last positive decision per UUID so that players
+// approved recently can still log in while the whitelist API is unreachable
+type GraceCache struct {
+	log logr.Logger
+	// mu also orders database writes, so a Forget is never overtaken by an earlier save
+	mu     sync.RWMutex
+	db     *sql.DB
+	dbPath string
+	grace  time.Duration
+
+	// In-memory cache for fast reads
+	cache map[string]*Approval
+}
+
+func NewGraceCache(log logr.Logger, dataDir string, grace time.Duration) *GraceCache {
+	dbPath := filepath.Join(dataDir, "whitelist_cache.db")
+	gc := &GraceCache{
+		log:    log.WithName("grace-cache"),
+		dbPath: dbPath,
+		grace:  grace,
+		cache:  make(map[string]*Approval),
+	}
+	gc.initDB()
+	gc.loadFromDB()
+	return gc
+}
+
+func (gc *GraceCache) initDB() {
+	db, err := sql.Open("sqlite3", gc.dbPath)
+	if err != nil {
+		gc.log.Error(err, "Failed to open grace cache database, approvals will not survive a restart", "path", gc.dbPath)
+		return
+	}
+	gc.db = db
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS approvals (
+			uuid TEXT PRIMARY KEY,
+			username TEXT NOT NULL,
+			approved_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		gc.log.Error(err, "Failed to create grace cache table", "path", gc.dbPath)
+	}
+}
+
+func (gc *GraceCache) loadFromDB() {
+	if gc.db == nil {
+		return
+	}
+
+	rows, err := gc.db.Query(`SELECT uuid, username, approved_at FROM approvals`)
+	if err != nil {
+		gc.log.Error(err, "Failed to load grace cache")
+		return
+	}
+	defer rows.Close()
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	for rows.Next() {
+		var uuid, username string
+		var approvedAt int64
+		if err := rows.Scan(&uuid, &username, &approvedAt); err != nil {
+			continue
+		}
+		gc.cache[uuid] = &Approval{
+			UUID:       uuid,
+			Username:   username,
+			ApprovedAt: time.UnixMilli(approvedAt),
+		}
+	}
+}
+
+// RecordApproval stores a positive decision for the player
+func (gc *GraceCache) RecordApproval(uuid, username string) {
+	approval := &Approval{
+		UUID:       uuid,
+		Username:   username,
+		ApprovedAt: time.Now(),
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.cache[uuid] = approval
+	gc.saveApproval(approval)
+}
+
+// saveApproval writes a to the database. The caller holds mu.
+func (gc *GraceCache) saveApproval(a *Approval) {
+	if gc.db == nil {
+		return
+	}
+
+	_, err := gc.db.Exec(`
+		INSERT OR REPLACE INTO approvals (uuid, username, approved_at)
+		VALUES (?, ?, ?)
+	`, a.UUID, a.Username, a.ApprovedAt.UnixMilli())
+	if err != nil {
+		gc.log.Error(err, "Failed to save approval", "uuid", a.UUID, "player", a.Username)
+	}
+}
+
+// Forget drops the cached decision, e.g. after the API explicitly denied the player
+func (gc *GraceCache) Forget(uuid string) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if _, exists := gc.cache[uuid]; !exists {
+		return
+	}
+	delete(gc.cache, uuid)
+	if gc.db == nil {
+		return
+	}
+	if _, err := gc.db.Exec(`DELETE FROM approvals WHERE uuid = ?`, uuid); err != nil {
+		gc.log.Error(err, "Failed to delete approval", "uuid", uuid)
+	}
+}
+
+// Lookup returns the cached approval if it is still within the grace window
+func (gc *GraceCache) Lookup(uuid string) (*Approval, bool) {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	approval, ok := gc.cache[uuid]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(approval.ApprovedAt) > gc.grace {
+		return approval, false
+	}
+	return approval, true
+}
+
+// Grace returns the configured grace window
+func (gc *GraceCache) Grace() time.Duration {
+	return gc.grace
+}
+
+// Close closes the database connection
+func (gc *GraceCache) Close() error {
+	if gc.db != nil {
+		return gc.db.Close()
+	}
+	return nil
+}
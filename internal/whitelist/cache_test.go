@@ -0,0 +1,85 @@
+package whitelist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestGraceCacheLookup(t *testing.T) {
+	tests := []struct {
+		name      string
+		grace     time.Duration
+		age       time.Duration
+		wantFound bool
+		wantOK    bool
+	}{
+		{"fresh approval", time.Hour, time.Minute, true, true},
+		{"expired approval", time.Hour, 2 * time.Hour, true, false},
+		{"no approval", time.Hour, -1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gc := NewGraceCache(logr.Discard(), t.TempDir(), tt.grace)
+			defer gc.Close()
+			if tt.age >= 0 {
+				gc.cache["uuid-1"] = &Approval{UUID: "uuid-1", Username: "Steve", ApprovedAt: time.Now().Add(-tt.age)}
+			}
+
+			approval, ok := gc.Lookup("uuid-1")
+			if (approval != nil) != tt.wantFound || ok != tt.wantOK {
+				t.Fatalf("Lookup = %+v, %v, want found %v ok %v", approval, ok, tt.wantFound, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGraceCachePersistence(t *testing.T) {
+	dir := t.TempDir()
+	gc := NewGraceCache(logr.Discard(), dir, time.Hour)
+	approvedAt := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	gc.saveApproval(&Approval{UUID: "uuid-1", Username: "Steve", ApprovedAt: approvedAt})
+	gc.Close()
+
+	reopened := NewGraceCache(logr.Discard(), dir, time.Hour)
+	defer reopened.Close()
+	approval, ok := reopened.Lookup("uuid-1")
+	if !ok || approval.Username != "Steve" || !approval.ApprovedAt.Equal(approvedAt) {
+		t.Fatalf("Lookup after reopen = %+v, %v", approval, ok)
+	}
+
+	reopened.Forget("uuid-1")
+	if _, ok := reopened.Lookup("uuid-1"); ok {
+		t.Fatal("Lookup found a forgotten approval")
+	}
+}
+
+func TestGraceCacheWriteOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		forget    bool
+		wantFound bool
+	}{
+		{"approval saved", false, true},
+		// a revocation right after an approval must not leave it on disk
+		{"forget after approval", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			gc := NewGraceCache(logr.Discard(), dir, time.Hour)
+			gc.RecordApproval("uuid-1", "Steve")
+			if tt.forget {
+				gc.Forget("uuid-1")
+			}
+			gc.Close()
+
+			reopened := NewGraceCache(logr.Discard(), dir, time.Hour)
+			defer reopened.Close()
+			if _, ok := reopened.Lookup("uuid-1"); ok != tt.wantFound {
+				t.Fatalf("Lookup after reopen found %v, want %v", ok, tt.wantFound)
+			}
+		})
+	}
+}
@@ -1,12 +1,8 @@
 package whitelist
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"net/http"
-	"strings"
-	"time"
+	"sync/atomic"
 
 	"github.com/go-logr/logr"
 )
@@ -17,65 +13,160 @@ const (
 	Allowed CheckResult = iota
 	NotInWhitelist
 	ServerError
+	// NoDecision is returned by providers that do not know the player,
+	// so the next provider in the chain is asked
+	NoDecision
 )
 
+// Source tells whether a decision came from the live providers or the offline cache
+type Source int
+
+const (
+	SourceLive Source = iota
+	SourceCache
+)
+
+func (s Source) String() string {
+	if s == SourceCache {
+		return "cache"
+	}
+	return "live"
+}
+
 type Checker struct {
-	client *http.Client
-	log    logr.Logger
+	provider Provider
+	log      logr.Logger
+	cache    *GraceCache
+	sessions *SessionCache
+
+	liveAllowed  atomic.Int64
+	liveDenied   atomic.Int64
+	liveErrors   atomic.Int64
+	cacheAllowed atomic.Int64
+	cacheDenied  atomic.Int64
+}
+
+// CheckStats counts login decisions by outcome and source
+type CheckStats struct {
+	LiveAllowed  int64
+	LiveDenied   int64
+	LiveErrors   int64
+	CacheAllowed int64
+	CacheDenied  int64
 }
 
-func NewChecker(log logr.Logger) *Checker {
+// NewChecker creates a whitelist checker. cache may be nil to disable the offline grace period.
+func NewChecker(log logr.Logger, provider Provider, cache *GraceCache) *Checker {
 	return &Checker{
-		client: &http.Client{},
-		log:    log,
+		provider: provider,
+		log:      log,
+		cache:    cache,
+		sessions: NewSessionCache(),
 	}
 }
 
-type whitelistRequest struct {
-	Username   string `json:"username"`
-	UUID       string `json:"uuid"`
-	ServerTier int    `json:"serverTier"`
-}
+// Check asks the providers about the player. When they fail and the player
+// was approved within the grace window, the cached approval is used instead.
+func (w *Checker) Check(ctx context.Context, req Request) Result {
+	result := w.provider.Check(ctx, req)
+	if result.Status == NoDecision {
+		result.Status = NotInWhitelist
+	}
+
+	switch result.Status {
+	case Allowed:
+		w.liveAllowed.Add(1)
+		if w.cache != nil {
+			w.cache.RecordApproval(req.UUID, req.Username)
+		}
+		return result
+	case NotInWhitelist:
+		w.liveDenied.Add(1)
+		if w.cache != nil {
+			w.cache.Forget(req.UUID)
+		}
+		return result
+	}
 
-func (w *Checker) Check(ctx context.Context, username, uuid, baseURL string, timeoutSeconds, serverTier int) CheckResult {
-	reqBody := whitelistRequest{
-		Username:   username,
-		UUID:       uuid,
-		ServerTier: serverTier,
+	w.liveErrors.Add(1)
+	if w.cache == nil {
+		return Result{Status: ServerError}
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		w.log.Error(err, "Failed to marshal request")
-		return ServerError
+	approval, ok := w.cache.Lookup(req.UUID)
+	if ok {
+		w.cacheAllowed.Add(1)
+		w.log.Info("Whitelist API unavailable, allowing from offline cache",
+			"username", req.Username, "uuid", req.UUID, "approvedAt", approval.ApprovedAt)
+		return Result{Status: Allowed, Source: SourceCache}
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
-	defer cancel()
+	w.cacheDenied.Add(1)
+	if approval != nil {
+		w.log.Info("Whitelist API unavailable, cached approval expired",
+			"username", req.Username, "uuid", req.UUID, "approvedAt", approval.ApprovedAt, "grace", w.cache.Grace())
+	} else {
+		w.log.Info("Whitelist API unavailable, no cached approval", "username", req.Username, "uuid", req.UUID)
+	}
+	return Result{Status: ServerError, Source: SourceCache}
+}
+
+// CheckTier checks the player against a per-server tier. Definite decisions are
+// cached for the rest of the session; the offline grace cache is not consulted.
+func (w *Checker) CheckTier(ctx context.Context, req Request) Result {
+	if result, ok := w.sessions.Get(req.UUID, req.Tier); ok {
+		return result
+	}
+
+	result := w.provider.Check(ctx, req)
+	if result.Status == NoDecision {
+		result.Status = NotInWhitelist
+	}
+	if result.Status != ServerError {
+		w.sessions.Set(req.UUID, req.Tier, result)
+	}
+	return result
+}
 
-	apiURL := strings.TrimSuffix(baseURL, "/") + "/api/whitelist"
-	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		w.log.Error(err, "Failed to create request")
-		return ServerError
+// Revalidate re-checks an online player. It does not count as a login and never
+// falls back to the grace cache; a denial also clears the player's cached decisions.
+func (w *Checker) Revalidate(ctx context.Context, req Request) Result {
+	result := w.provider.Check(ctx, req)
+	if result.Status == NoDecision {
+		result.Status = NotInWhitelist
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := w.client.Do(req)
-	if err != nil {
-		w.log.Error(err, "Whitelist check failed", "username", username, "uuid", uuid)
-		return ServerError
+	if result.Status == NotInWhitelist {
+		if w.cache != nil {
+			w.cache.Forget(req.UUID)
+		}
+		w.sessions.Forget(req.UUID)
 	}
-	defer resp.Body.Close()
+	return result
+}
 
-	w.log.Info("Whitelist check response", "username", username, "uuid", uuid, "status", resp.StatusCode)
+// ForgetSession drops the per-tier decisions of a player who left the proxy
+func (w *Checker) ForgetSession(uuid string) {
+	w.sessions.Forget(uuid)
+}
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return Allowed
-	case http.StatusForbidden:
-		return NotInWhitelist
-	default:
-		return ServerError
+// Stats returns a snapshot of the decision counters
+func (w *Checker) Stats() CheckStats {
+	return CheckStats{
+		LiveAllowed:  w.liveAllowed.Load(),
+		LiveDenied:   w.liveDenied.Load(),
+		LiveErrors:   w.liveErrors.Load(),
+		CacheAllowed: w.cacheAllowed.Load(),
+		CacheDenied:  w.cacheDenied.Load(),
 	}
 }
+
+// GraceCache returns the offline cache, or nil if disabled
+func (w *Checker) GraceCache() *GraceCache {
+	return w.cache
+}
+
+// Provider returns the provider chain used for decisions
+func (w *Checker) Provider() Provider {
+	return w.provider
+}
@@ -0,0 +1,150 @@
+package whitelist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// fakeProvider answers every check with result and counts the calls
+type fakeProvider struct {
+	name   string
+	result Result
+	calls  int
+}
+
+func (f *fakeProvider) Name() string {
+	if f.name == "" {
+		return "fake"
+	}
+	return f.name
+}
+
+func (f *fakeProvider) Check(ctx context.Context, req Request) Result {
+	f.calls++
+	return f.result
+}
+
+func TestCheckerCheck(t *testing.T) {
+	steve := Request{Username: "Steve", UUID: "uuid-1"}
+
+	tests := []struct {
+		name       string
+		withCache  bool
+		approvedAt time.Duration // age of a cached approval, 0 for none
+		status     CheckResult
+		wantStatus CheckResult
+		wantSource Source
+		wantStats  CheckStats
+	}{
+		{"allowed", true, 0, Allowed, Allowed, SourceLive, CheckStats{LiveAllowed: 1}},
+		{"denied", true, time.Minute, NotInWhitelist, NotInWhitelist, SourceLive, CheckStats{LiveDenied: 1}},
+		{"no decision denies", false, 0, NoDecision, NotInWhitelist, SourceLive, CheckStats{LiveDenied: 1}},
+		{"error without cache", false, 0, ServerError, ServerError, SourceLive, CheckStats{LiveErrors: 1}},
+		{"error with fresh approval", true, time.Minute, ServerError, Allowed, SourceCache, CheckStats{LiveErrors: 1, CacheAllowed: 1}},
+		{"error with expired approval", true, 2 * time.Hour, ServerError, ServerError, SourceCache, CheckStats{LiveErrors: 1, CacheDenied: 1}},
+		{"error without approval", true, 0, ServerError, ServerError, SourceCache, CheckStats{LiveErrors: 1, CacheDenied: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cache *GraceCache
+			if tt.withCache {
+				cache = NewGraceCache(logr.Discard(), t.TempDir(), time.Hour)
+				defer cache.Close()
+				if tt.approvedAt > 0 {
+					cache.cache[steve.UUID] = &Approval{UUID: steve.UUID, Username: steve.Username, ApprovedAt: time.Now().Add(-tt.approvedAt)}
+				}
+			}
+			w := NewChecker(logr.Discard(), &fakeProvider{result: Result{Status: tt.status}}, cache)
+
+			result := w.Check(t.Context(), steve)
+			if result.Status != tt.wantStatus || result.Source != tt.wantSource {
+				t.Fatalf("Check = %+v, want status %d from %s", result, tt.wantStatus, tt.wantSource)
+			}
+			if got := w.Stats(); got != tt.wantStats {
+				t.Fatalf("Stats() = %+v, want %+v", got, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestCheckerGraceCacheFollowsDecisions(t *testing.T) {
+	steve := Request{Username: "Steve", UUID: "uuid-1"}
+	cache := NewGraceCache(logr.Discard(), t.TempDir(), time.Hour)
+	defer cache.Close()
+	provider := &fakeProvider{result: Result{Status: Allowed}}
+	w := NewChecker(logr.Discard(), provider, cache)
+
+	w.Check(t.Context(), steve)
+	if _, ok := cache.Lookup(steve.UUID); !ok {
+		t.Fatal("an allowed login was not recorded in the grace cache")
+	}
+
+	provider.result = Result{Status: NotInWhitelist}
+	w.Check(t.Context(), steve)
+	if _, ok := cache.Lookup(steve.UUID); ok {
+		t.Fatal("a denied login kept its grace cache approval")
+	}
+}
+
+func TestCheckerCheckTier(t *testing.T) {
+	provider := &fakeProvider{result: Result{Status: ServerError}}
+	w := NewChecker(logr.Discard(), provider, nil)
+	req := Request{Username: "Steve", UUID: "uuid-1", Tier: 2}
+
+	// Errors are not cached for the session
+	w.CheckTier(t.Context(), req)
+	provider.result = Result{Status: Allowed}
+	if got := w.CheckTier(t.Context(), req); got.Status != Allowed || provider.calls != 2 {
+		t.Fatalf("CheckTier after an error = %+v with %d calls, want a new live check", got, provider.calls)
+	}
+
+	// Definite decisions are reused per tier until the session ends
+	provider.result = Result{Status: NotInWhitelist}
+	if got := w.CheckTier(t.Context(), req); got.Status != Allowed || provider.calls != 2 {
+		t.Fatalf("CheckTier = %+v with %d calls, want the cached decision", got, provider.calls)
+	}
+	if got := w.CheckTier(t.Context(), Request{UUID: "uuid-1", Tier: 3}); got.Status != NotInWhitelist {
+		t.Fatalf("CheckTier for another tier = %+v, want a live denial", got)
+	}
+
+	w.ForgetSession("uuid-1")
+	if got := w.CheckTier(t.Context(), req); got.Status != NotInWhitelist {
+		t.Fatalf("CheckTier after ForgetSession = %+v, want a live denial", got)
+	}
+}
+
+func TestCheckerRevalidate(t *testing.T) {
+	steve := Request{Username: "Steve", UUID: "uuid-1", Tier: 1}
+	cache := NewGraceCache(logr.Discard(), t.TempDir(), time.Hour)
+	defer cache.Close()
+	cache.cache[steve.UUID] = &Approval{UUID: steve.UUID, Username: steve.Username, ApprovedAt: time.Now()}
+
+	provider := &fakeProvider{result: Result{Status: ServerError}}
+	w := NewChecker(logr.Discard(), provider, cache)
+	w.sessions.Set(steve.UUID, steve.Tier, Result{Status: Allowed})
+
+	// An outage neither falls back to the grace cache nor clears anything
+	if got := w.Revalidate(t.Context(), steve); got.Status != ServerError {
+		t.Fatalf("Revalidate during an outage = %+v, want ServerError", got)
+	}
+	if _, ok := cache.Lookup(steve.UUID); !ok {
+		t.Fatal("an outage dropped the grace cache approval")
+	}
+
+	provider.result = Result{Status: NoDecision}
+	if got := w.Revalidate(t.Context(), steve); got.Status != NotInWhitelist {
+		t.Fatalf("Revalidate = %+v, want NotInWhitelist", got)
+	}
+	if _, ok := cache.Lookup(steve.UUID); ok {
+		t.Fatal("a denial kept the grace cache approval")
+	}
+	if _, ok := w.sessions.Get(steve.UUID, steve.Tier); ok {
+		t.Fatal("a denial kept the session decision")
+	}
+	if w.Stats() != (CheckStats{}) {
+		t.Fatalf("Revalidate counted as a login: %+v", w.Stats())
+	}
+}
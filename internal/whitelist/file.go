@@ -0,0 +1,124 @@
+package whitelist
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// whitelistFile is the layout of a JSON or YAML whitelist file
+type whitelistFile struct {
+	Players []*Entry `json:"players" yaml:"players"`
+}
+
+// FileProvider reads whitelisted players from a JSON or YAML file and reloads
+// it whenever the file changes
+type FileProvider struct {
+	log  logr.Logger
+	path string
+
+	mu      sync.RWMutex
+	index   *entryIndex
+	count   int
+	modTime time.Time
+}
+
+func NewFileProvider(ctx context.Context, log logr.Logger, path string, reloadInterval time.Duration) *FileProvider {
+	f := &FileProvider{
+		log:   log.WithName("whitelist-file"),
+		path:  path,
+		index: newEntryIndex(nil),
+	}
+	f.reload()
+
+	if reloadInterval <= 0 {
+		reloadInterval = 5 * time.Second
+	}
+	go f.watch(ctx, reloadInterval)
+	return f
+}
+
+func (f *FileProvider) Name() string {
+	return "file"
+}
+
+func (f *FileProvider) Check(ctx context.Context, req Request) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return decide(f.index.lookup(req), req)
+}
+
+// Count returns the number of loaded entries
+func (f *FileProvider) Count() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.count
+}
+
+func (f *FileProvider) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(f.path)
+			if err != nil {
+				continue
+			}
+			f.mu.RLock()
+			changed := !info.ModTime().Equal(f.modTime)
+			f.mu.RUnlock()
+			if changed {
+				f.reload()
+			}
+		}
+	}
+}
+
+func (f *FileProvider) reload() {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		f.log.Error(err, "Whitelist file not readable", "path", f.path)
+		return
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		f.log.Error(err, "Failed to read whitelist file", "path", f.path)
+		return
+	}
+
+	var wf whitelistFile
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &wf)
+	default:
+		err = json.Unmarshal(data, &wf)
+	}
+	if err != nil {
+		// Keep serving the previous entries until the file is fixed
+		f.log.Error(err, "Failed to parse whitelist file", "path", f.path)
+		f.mu.Lock()
+		f.modTime = info.ModTime()
+		f.mu.Unlock()
+		return
+	}
+
+	f.mu.Lock()
+	f.index = newEntryIndex(wf.Players)
+	f.count = len(wf.Players)
+	f.modTime = info.ModTime()
+	f.mu.Unlock()
+
+	f.log.Info("Whitelist file loaded", "path", f.path, "players", len(wf.Players))
+}
@@ -0,0 +1,80 @@
+package whitelist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestFileProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		wantCount int
+	}{
+		{"json", "whitelist.json", `{"players": [{"username": "Steve"}, {"username": "Alex", "tier": 1}]}`, 2},
+		{"yaml", "whitelist.yaml", "players:\n  - username: Steve\n  - username: Alex\n    tier: 1\n", 2},
+		{"yml", "whitelist.yml", "players:\n  - username: Steve\n", 1},
+		{"broken", "whitelist.json", `{"players": [`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			f := NewFileProvider(t.Context(), logr.Discard(), path, time.Hour)
+
+			if f.Count() != tt.wantCount {
+				t.Fatalf("Count() = %d, want %d", f.Count(), tt.wantCount)
+			}
+			want := Allowed
+			if tt.wantCount == 0 {
+				want = NoDecision
+			}
+			if got := f.Check(t.Context(), Request{Username: "STEVE"}); got.Status != want {
+				t.Fatalf("Check(STEVE) = %+v, want status %d", got, want)
+			}
+		})
+	}
+}
+
+func TestFileProviderReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whitelist.json")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write(`{"players": [{"username": "Steve"}]}`, start)
+	f := NewFileProvider(t.Context(), logr.Discard(), path, 10*time.Millisecond)
+
+	// A broken edit keeps the previous entries
+	write(`{"players": [`, start.Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if got := f.Check(t.Context(), Request{Username: "Steve"}); got.Status != Allowed {
+		t.Fatalf("Check after a broken edit = %+v, want the previous entries", got)
+	}
+
+	write(`{"players": [{"username": "Alex"}]}`, start.Add(2*time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for f.Check(t.Context(), Request{Username: "Alex"}).Status != Allowed {
+		if time.Now().After(deadline) {
+			t.Fatal("the changed file was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := f.Check(t.Context(), Request{Username: "Steve"}); got.Status != NoDecision {
+		t.Fatalf("Check(Steve) after reload = %+v, want NoDecision", got)
+	}
+}
@@ -0,0 +1,118 @@
+package whitelist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-logr/logr"
+
+	"github.com/RMS-Server/RMS-Gate/internal/rmsapi"
+)
+
+// maxResponseBytes bounds how much of a response body is read
+const maxResponseBytes = 64 * 1024
+
+// ContextFields selects which optional login details are sent to the API,
+// so IPs are not shared with APIs that do not need them
+type ContextFields struct {
+	IP          bool
+	Protocol    bool
+	VirtualHost bool
+	OnlineMode  bool
+	ASN         bool
+}
+
+// HTTPProvider asks the RMS whitelist API, failing over between the client's endpoints
+type HTTPProvider struct {
+	client *rmsapi.Client
+	log    logr.Logger
+	fields ContextFields
+}
+
+func NewHTTPProvider(log logr.Logger, client *rmsapi.Client, fields ContextFields) *HTTPProvider {
+	return &HTTPProvider{
+		client: client,
+		log:    log,
+		fields: fields,
+	}
+}
+
+type whitelistRequest struct {
+	Username    string `json:"username"`
+	UUID        string `json:"uuid"`
+	ServerTier  int    `json:"serverTier"`
+	IP          string `json:"ip,omitempty"`
+	Protocol    int    `json:"protocolVersion,omitempty"`
+	VirtualHost string `json:"virtualHost,omitempty"`
+	OnlineMode  *bool  `json:"onlineMode,omitempty"`
+	ASN         uint32 `json:"asn,omitempty"`
+	ASOrg       string `json:"asOrg,omitempty"`
+}
+
+func (h *HTTPProvider) Name() string {
+	return "http"
+}
+
+func (h *HTTPProvider) Check(ctx context.Context, r Request) Result {
+	reqBody := whitelistRequest{
+		Username:   r.Username,
+		UUID:       r.UUID,
+		ServerTier: r.Tier,
+	}
+	if h.fields.IP {
+		reqBody.IP = r.IP
+	}
+	if h.fields.Protocol {
+		reqBody.Protocol = r.Protocol
+	}
+	if h.fields.VirtualHost {
+		reqBody.VirtualHost = r.VirtualHost
+	}
+	if h.fields.OnlineMode {
+		onlineMode := r.OnlineMode
+		reqBody.OnlineMode = &onlineMode
+	}
+	if h.fields.ASN {
+		reqBody.ASN = r.ASN
+		reqBody.ASOrg = r.ASOrg
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		h.log.Error(err, "Failed to marshal request")
+		return Result{Status: ServerError}
+	}
+
+	_, resp, err := h.client.Send(ctx, http.MethodPost, "/api/whitelist", jsonData)
+	if err != nil {
+		h.log.Error(err, "Whitelist check failed", "username", r.Username, "uuid", r.UUID)
+		return Result{Status: ServerError}
+	}
+	defer resp.Body.Close()
+
+	h.log.Info("Whitelist check response", "username", r.Username, "uuid", r.UUID, "status", resp.StatusCode)
+
+	var result Result
+	switch resp.StatusCode {
+	case http.StatusOK:
+		result.Status = Allowed
+	case http.StatusForbidden:
+		result.Status = NotInWhitelist
+	case http.StatusUnauthorized:
+		h.log.Error(nil, "Whitelist API rejected our credentials, check apiAuth", "status", resp.StatusCode)
+		return Result{Status: ServerError}
+	default:
+		return Result{Status: ServerError}
+	}
+
+	body, err := rmsapi.ReadBody(resp, maxResponseBytes)
+	if err != nil {
+		h.log.V(1).Info("Failed to read whitelist response body", "username", r.Username, "error", err)
+		return result
+	}
+	if err := parseResponseBody(body, &result); err != nil {
+		h.log.V(1).Info("Ignoring unparseable whitelist response body", "username", r.Username, "error", err)
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package whitelist
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/RMS-Server/RMS-Gate/internal/rmsapi"
+)
+
+// newTestHTTPProvider returns a provider asking a test server that runs handler
+func newTestHTTPProvider(t *testing.T, fields ContextFields, handler http.HandlerFunc) *HTTPProvider {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := rmsapi.NewClient(&rmsapi.AuthConfig{}, rmsapi.NewPool([]string{srv.URL}, nil, 1, 0), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHTTPProvider(logr.Discard(), client, fields)
+}
+
+func TestHTTPProviderStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus CheckResult
+		wantReason string
+	}{
+		{"allowed", http.StatusOK, "", Allowed, ""},
+		{"denied", http.StatusForbidden, "", NotInWhitelist, ""},
+		{"denied with details", http.StatusForbidden, `{"reason":"tier","requiredTier":2}`, NotInWhitelist, "tier"},
+		{"unparsable body still decides", http.StatusForbidden, "Forbidden", NotInWhitelist, ""},
+		{"bad credentials", http.StatusUnauthorized, `{"reason":"banned"}`, ServerError, ""},
+		{"not found", http.StatusNotFound, "", ServerError, ""},
+		{"server error", http.StatusBadGateway, "", ServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHTTPProvider(t, ContextFields{}, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/api/whitelist" {
+					t.Errorf("request %s %s, want POST /api/whitelist", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			result := h.Check(t.Context(), Request{Username: "Steve", UUID: "uuid-1", Tier: 2})
+			if result.Status != tt.wantStatus || result.Reason != tt.wantReason {
+				t.Fatalf("Check = %+v, want status %d reason %q", result, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	client, err := rmsapi.NewClient(&rmsapi.AuthConfig{}, rmsapi.NewPool([]string{srv.URL}, nil, 1, 0), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := NewHTTPProvider(logr.Discard(), client, ContextFields{}).Check(t.Context(), Request{Username: "Steve"}); result.Status != ServerError {
+		t.Fatalf("Check against a closed server = %+v, want ServerError", result)
+	}
+}
+
+func TestHTTPProviderContextFields(t *testing.T) {
+	req := Request{
+		Username:    "Steve",
+		UUID:        "uuid-1",
+		Tier:        2,
+		IP:          "1.2.3.4",
+		Protocol:    767,
+		VirtualHost: "play.example.com",
+		OnlineMode:  false,
+		ASN:         13335,
+		ASOrg:       "Cloudflare",
+	}
+
+	tests := []struct {
+		name   string
+		fields ContextFields
+		want   map[string]any
+	}{
+		{"none", ContextFields{}, map[string]any{}},
+		{"ip", ContextFields{IP: true}, map[string]any{"ip": "1.2.3.4"}},
+		{"protocol and host", ContextFields{Protocol: true, VirtualHost: true}, map[string]any{"protocolVersion": 767.0, "virtualHost": "play.example.com"}},
+		{"offline mode is still sent", ContextFields{OnlineMode: true}, map[string]any{"onlineMode": false}},
+		{"asn", ContextFields{ASN: true}, map[string]any{"asn": 13335.0, "asOrg": "Cloudflare"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			h := newTestHTTPProvider(t, tt.fields, func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("request body: %v", err)
+				}
+			})
+			if result := h.Check(t.Context(), req); result.Status != Allowed {
+				t.Fatalf("Check = %+v, want Allowed", result)
+			}
+
+			// The player is always identified
+			base := map[string]any{"username": "Steve", "uuid": "uuid-1", "serverTier": 2.0}
+			if len(got) != len(base)+len(tt.want) {
+				t.Fatalf("request body = %v, want %v plus %v", got, base, tt.want)
+			}
+			for _, want := range []map[string]any{base, tt.want} {
+				for key, value := range want {
+					if got[key] != value {
+						t.Fatalf("request %s = %v, want %v", key, got[key], value)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package whitelist
+
+import (
+	"strings"
+	"time"
+)
+
+// Entry is a locally whitelisted player
+type Entry struct {
+	Username string `json:"username" yaml:"username"`
+	UUID     string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	// Tier is the highest tier the player may join, 0 for any tier
+	Tier int `json:"tier,omitempty" yaml:"tier,omitempty"`
+	// ExpiresAt ends the entry's validity, zero for permanent entries
+	ExpiresAt time.Time `json:"expiresAt,omitzero" yaml:"expiresAt,omitempty"`
+}
+
+// Expired reports whether the entry has passed its expiry time
+func (e *Entry) Expired() bool {
+	return !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt)
+}
+
+// entryIndex looks up local entries by UUID or lowercase username
+type entryIndex struct {
+	byUUID map[string]*Entry
+	byName map[string]*Entry
+}
+
+func newEntryIndex(entries []*Entry) *entryIndex {
+	idx := &entryIndex{
+		byUUID: make(map[string]*Entry, len(entries)),
+		byName: make(map[string]*Entry, len(entries)),
+	}
+	for _, e := range entries {
+		idx.add(e)
+	}
+	return idx
+}
+
+func (idx *entryIndex) add(e *Entry) {
+	if e.UUID != "" {
+		idx.byUUID[strings.ToLower(e.UUID)] = e
+	}
+	if e.Username != "" {
+		idx.byName[strings.ToLower(e.Username)] = e
+	}
+}
+
+func (idx *entryIndex) lookup(req Request) *Entry {
+	if req.UUID != "" {
+		if e, ok := idx.byUUID[strings.ToLower(req.UUID)]; ok {
+			return e
+		}
+	}
+	return idx.byName[strings.ToLower(req.Username)]
+}
+
+// decide turns a local entry into a decision for the requested tier
+func decide(e *Entry, req Request) Result {
+	if e == nil || e.Expired() {
+		return Result{Status: NoDecision}
+	}
+	if e.Tier > 0 && req.Tier > e.Tier {
+		return Result{Status: NotInWhitelist, Reason: "tier", RequiredTier: req.Tier}
+	}
+	return Result{Status: Allowed}
+}
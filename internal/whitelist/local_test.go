@@ -0,0 +1,38 @@
+package whitelist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	idx := newEntryIndex([]*Entry{
+		{Username: "Steve", UUID: "UUID-1"},
+		{Username: "Alex", Tier: 2},
+		{Username: "Old", ExpiresAt: time.Now().Add(-time.Minute)},
+		{Username: "Guest", ExpiresAt: time.Now().Add(time.Hour)},
+	})
+
+	tests := []struct {
+		name       string
+		req        Request
+		wantStatus CheckResult
+		wantReason string
+	}{
+		{"by name ignores case", Request{Username: "steve"}, Allowed, ""},
+		{"by uuid after rename", Request{Username: "Renamed", UUID: "uuid-1"}, Allowed, ""},
+		{"unknown", Request{Username: "Herobrine"}, NoDecision, ""},
+		{"tier within limit", Request{Username: "Alex", Tier: 2}, Allowed, ""},
+		{"tier above limit", Request{Username: "Alex", Tier: 3}, NotInWhitelist, "tier"},
+		{"expired entry", Request{Username: "Old"}, NoDecision, ""},
+		{"temporary entry", Request{Username: "Guest"}, Allowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := decide(idx.lookup(tt.req), tt.req)
+			if result.Status != tt.wantStatus || result.Reason != tt.wantReason {
+				t.Fatalf("decide(%+v) = %+v, want status %d reason %q", tt.req, result, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
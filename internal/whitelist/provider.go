@@ -0,0 +1,72 @@
+package whitelist
+
+import (
+	"context"
+	"strings"
+)
+
+// Request describes the player being checked
+type Request struct {
+	Username string
+	UUID     string
+	Tier     int
+
+	// Connection details, only forwarded to the API when enabled in ContextFields
+	IP          string
+	Protocol    int
+	VirtualHost string
+	OnlineMode  bool
+	// ASN and ASOrg come from the local ASN database, 0 if unknown
+	ASN   uint32
+	ASOrg string
+}
+
+// Provider decides whether a player is whitelisted. Providers that do not
+// know the player return NoDecision so the next provider can be asked.
+type Provider interface {
+	Name() string
+	Check(ctx context.Context, req Request) Result
+}
+
+// Chain asks its providers in order and returns the first definite decision
+type Chain struct {
+	providers []Provider
+}
+
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{providers: providers}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, " -> ")
+}
+
+// Check returns the first Allowed or NotInWhitelist result. If no provider
+// decides, a provider error wins over the default deny so the grace cache can apply.
+func (c *Chain) Check(ctx context.Context, req Request) Result {
+	failed := false
+	for _, p := range c.providers {
+		result := p.Check(ctx, req)
+		switch result.Status {
+		case Allowed, NotInWhitelist:
+			result.Provider = p.Name()
+			return result
+		case ServerError:
+			failed = true
+		}
+	}
+
+	if failed {
+		return Result{Status: ServerError}
+	}
+	return Result{Status: NotInWhitelist}
+}
+
+// Providers returns the providers of the chain in evaluation order
+func (c *Chain) Providers() []Provider {
+	return c.providers
+}
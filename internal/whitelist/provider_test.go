@@ -0,0 +1,46 @@
+package whitelist
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []CheckResult
+		wantStatus   CheckResult
+		wantProvider string
+		wantCalls    int
+	}{
+		{"first decides", []CheckResult{Allowed, NotInWhitelist}, Allowed, "p0", 1},
+		{"skips no decision", []CheckResult{NoDecision, NotInWhitelist}, NotInWhitelist, "p1", 2},
+		{"skips errors", []CheckResult{ServerError, Allowed}, Allowed, "p1", 2},
+		{"error wins over default deny", []CheckResult{NoDecision, ServerError}, ServerError, "", 2},
+		{"nobody decides", []CheckResult{NoDecision, NoDecision}, NotInWhitelist, "", 2},
+		{"empty chain", nil, NotInWhitelist, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var providers []Provider
+			var fakes []*fakeProvider
+			for i, status := range tt.statuses {
+				f := &fakeProvider{name: fmt.Sprintf("p%d", i), result: Result{Status: status}}
+				fakes = append(fakes, f)
+				providers = append(providers, f)
+			}
+
+			result := NewChain(providers...).Check(t.Context(), Request{Username: "Steve"})
+			if result.Status != tt.wantStatus || result.Provider != tt.wantProvider {
+				t.Fatalf("Check = %+v, want status %d from %q", result, tt.wantStatus, tt.wantProvider)
+			}
+			calls := 0
+			for _, f := range fakes {
+				calls += f.calls
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("Check asked %d providers, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package whitelist
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Result is a whitelist decision together with the optional details returned by the API
+type Result struct {
+	Status CheckResult
+	Source Source
+	// Provider is the name of the provider that made the decision
+	Provider string
+
+	// Reason is a short deny reason, e.g. "banned" or "tier"
+	Reason string
+	// BanUntil is set when the player is banned until a point in time
+	BanUntil time.Time
+	// Message replaces the configured kick text when set
+	Message string
+	// RequiredTier is the tier the player needs to join, 0 if not reported
+	RequiredTier int
+}
+
+// whitelistResponse is the optional JSON body of a whitelist API response.
+// Older APIs return an empty body, which leaves every field at its zero value.
+type whitelistResponse struct {
+	Reason       string          `json:"reason"`
+	BanUntil     json.RawMessage `json:"banUntil"`
+	Message      string          `json:"message"`
+	RequiredTier int             `json:"requiredTier"`
+}
+
+// parseResponseBody fills the result details from the response body, ignoring bodies it cannot parse
+func parseResponseBody(body []byte, result *Result) error {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+
+	var resp whitelistResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+
+	result.Reason = resp.Reason
+	result.Message = resp.Message
+	result.RequiredTier = resp.RequiredTier
+	result.BanUntil = parseTimestamp(resp.BanUntil)
+	return nil
+}
+
+// parseTimestamp accepts an RFC 3339 string or a unix timestamp in seconds or milliseconds
+func parseTimestamp(raw json.RawMessage) time.Time {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+		raw = json.RawMessage(s)
+	}
+
+	n, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	// Values this large can only be milliseconds
+	if n > 1e11 {
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
@@ -0,0 +1,69 @@
+package whitelist
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want time.Time
+	}{
+		{"missing", ``, time.Time{}},
+		{"null", `null`, time.Time{}},
+		{"rfc3339", `"2025-06-01T12:00:00Z"`, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{"seconds", `1748779200`, time.Unix(1748779200, 0)},
+		{"milliseconds", `1748779200123`, time.UnixMilli(1748779200123)},
+		{"seconds as string", `"1748779200"`, time.Unix(1748779200, 0)},
+		{"zero", `0`, time.Time{}},
+		{"negative", `-5`, time.Time{}},
+		{"garbage", `"next week"`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTimestamp(json.RawMessage(tt.raw)); !got.Equal(tt.want) {
+				t.Fatalf("parseTimestamp(%s) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResponseBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Result
+		wantErr bool
+	}{
+		{"empty body", "", Result{Status: NotInWhitelist}, false},
+		{"whitespace body", " \n", Result{Status: NotInWhitelist}, false},
+		{
+			name: "ban",
+			body: `{"reason":"banned","banUntil":1748779200,"message":"Griefing"}`,
+			want: Result{Status: NotInWhitelist, Reason: "banned", BanUntil: time.Unix(1748779200, 0), Message: "Griefing"},
+		},
+		{
+			name: "tier",
+			body: `{"reason":"tier","requiredTier":3}`,
+			want: Result{Status: NotInWhitelist, Reason: "tier", RequiredTier: 3},
+		},
+		{"unknown fields ignored", `{"reason":"banned","extra":true}`, Result{Status: NotInWhitelist, Reason: "banned"}, false},
+		{"not json", "Forbidden", Result{Status: NotInWhitelist}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Result{Status: NotInWhitelist}
+			err := parseResponseBody([]byte(tt.body), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResponseBody(%q) error = %v, want error %v", tt.body, err, tt.wantErr)
+			}
+			if got.Status != tt.want.Status || got.Reason != tt.want.Reason || got.Message != tt.want.Message ||
+				got.RequiredTier != tt.want.RequiredTier || !got.BanUntil.Equal(tt.want.BanUntil) {
+				t.Fatalf("parseResponseBody(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package whitelist
+
+import "sync"
+
+// SessionCache remembers per-tier decisions for online players so that
+// server switches do not hit the whitelist API every time
+type SessionCache struct {
+	mu        sync.RWMutex
+	decisions map[string]map[int]Result // uuid -> tier -> result
+}
+
+func NewSessionCache() *SessionCache {
+	return &SessionCache{
+		decisions: make(map[string]map[int]Result),
+	}
+}
+
+func (s *SessionCache) Get(uuid string, tier int) (Result, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tiers, ok := s.decisions[uuid]
+	if !ok {
+		return Result{}, false
+	}
+	result, ok := tiers[tier]
+	return result, ok
+}
+
+func (s *SessionCache) Set(uuid string, tier int, result Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tiers, ok := s.decisions[uuid]
+	if !ok {
+		tiers = make(map[int]Result)
+		s.decisions[uuid] = tiers
+	}
+	tiers[tier] = result
+}
+
+// Forget drops all decisions for the player, called when the session ends
+func (s *SessionCache) Forget(uuid string) {
+	s.mu.Lock()
+	delete(s.decisions, uuid)
+	s.mu.Unlock()
+}
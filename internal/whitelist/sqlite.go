@@ -0,0 +1,236 @@
+package whitelist
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// StoredEntry is a whitelist entry managed from inside the proxy
+type StoredEntry struct {
+	Entry
+	// Guest marks temporary passes handed out with /wl guest
+	Guest   bool
+	AddedBy string
+	AddedAt time.Time
+}
+
+// SQLiteProvider keeps whitelisted players in a local SQLite database
+type SQLiteProvider struct {
+	log    logr.Logger
+	mu     sync.RWMutex
+	db     *sql.DB
+	dbPath string
+
+	// In-memory cache for fast reads, keyed by lowercase username
+	entries map[string]*StoredEntry
+	index   *entryIndex
+}
+
+func NewSQLiteProvider(log logr.Logger, dbPath string) *SQLiteProvider {
+	p := &SQLiteProvider{
+		log:     log.WithName("whitelist-sqlite"),
+		dbPath:  dbPath,
+		entries: make(map[string]*StoredEntry),
+		index:   newEntryIndex(nil),
+	}
+	p.initDB()
+	p.loadFromDB()
+	return p
+}
+
+func (p *SQLiteProvider) initDB() {
+	db, err := sql.Open("sqlite3", p.dbPath)
+	if err != nil {
+		return
+	}
+	p.db = db
+
+	_, _ = db.Exec(`
+		CREATE TABLE IF NOT EXISTS whitelist_entries (
+			username_key TEXT PRIMARY KEY,
+			username TEXT NOT NULL,
+			uuid TEXT NOT NULL DEFAULT '',
+			tier INTEGER NOT NULL DEFAULT 0,
+			added_by TEXT NOT NULL DEFAULT '',
+			added_at INTEGER NOT NULL
+		)
+	`)
+
+	// Columns added for temporary entries; errors mean they already exist
+	_, _ = db.Exec(`ALTER TABLE whitelist_entries ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0`)
+	_, _ = db.Exec(`ALTER TABLE whitelist_entries ADD COLUMN guest INTEGER NOT NULL DEFAULT 0`)
+}
+
+func (p *SQLiteProvider) loadFromDB() {
+	if p.db == nil {
+		return
+	}
+
+	rows, err := p.db.Query(`SELECT username, uuid, tier, added_by, added_at, expires_at, guest FROM whitelist_entries`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for rows.Next() {
+		var e StoredEntry
+		var addedAt, expiresAt int64
+		if err := rows.Scan(&e.Username, &e.UUID, &e.Tier, &e.AddedBy, &addedAt, &expiresAt, &e.Guest); err != nil {
+			continue
+		}
+		e.AddedAt = time.UnixMilli(addedAt)
+		if expiresAt > 0 {
+			e.ExpiresAt = time.UnixMilli(expiresAt)
+		}
+		p.entries[strings.ToLower(e.Username)] = &e
+	}
+	p.rebuildIndex()
+}
+
+// rebuildIndex must be called with mu held
+func (p *SQLiteProvider) rebuildIndex() {
+	entries := make([]*Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, &e.Entry)
+	}
+	p.index = newEntryIndex(entries)
+}
+
+func (p *SQLiteProvider) Name() string {
+	return "sqlite"
+}
+
+func (p *SQLiteProvider) Check(ctx context.Context, req Request) Result {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return decide(p.index.lookup(req), req)
+}
+
+// Add inserts or replaces the entry for the player. guest marks a temporary pass.
+func (p *SQLiteProvider) Add(entry Entry, guest bool, addedBy string) error {
+	stored := &StoredEntry{
+		Entry:   entry,
+		Guest:   guest,
+		AddedBy: addedBy,
+		AddedAt: time.Now(),
+	}
+	key := strings.ToLower(entry.Username)
+
+	var expiresAt int64
+	if !entry.ExpiresAt.IsZero() {
+		expiresAt = entry.ExpiresAt.UnixMilli()
+	}
+
+	if p.db != nil {
+		_, err := p.db.Exec(`
+			INSERT OR REPLACE INTO whitelist_entries (username_key, username, uuid, tier, added_by, added_at, expires_at, guest)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, key, entry.Username, entry.UUID, entry.Tier, addedBy, stored.AddedAt.UnixMilli(), expiresAt, guest)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.entries[key] = stored
+	p.rebuildIndex()
+	p.mu.Unlock()
+	return nil
+}
+
+// Remove deletes the player's entry, returning false if there was none
+func (p *SQLiteProvider) Remove(username string) (bool, error) {
+	key := strings.ToLower(username)
+
+	p.mu.RLock()
+	_, exists := p.entries[key]
+	p.mu.RUnlock()
+	if !exists {
+		return false, nil
+	}
+
+	if p.db != nil {
+		if _, err := p.db.Exec(`DELETE FROM whitelist_entries WHERE username_key = ?`, key); err != nil {
+			return false, err
+		}
+	}
+
+	p.mu.Lock()
+	delete(p.entries, key)
+	p.rebuildIndex()
+	p.mu.Unlock()
+	return true, nil
+}
+
+// List returns all entries sorted by username
+func (p *SQLiteProvider) List() []*StoredEntry {
+	p.mu.RLock()
+	result := make([]*StoredEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		result = append(result, e)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Username) < strings.ToLower(result[j].Username)
+	})
+	return result
+}
+
+// PurgeExpired removes expired entries and returns how many were removed
+func (p *SQLiteProvider) PurgeExpired() int {
+	p.mu.Lock()
+	var expired []string
+	for key, e := range p.entries {
+		if e.Expired() {
+			expired = append(expired, key)
+			delete(p.entries, key)
+		}
+	}
+	if len(expired) > 0 {
+		p.rebuildIndex()
+	}
+	p.mu.Unlock()
+
+	if p.db != nil && len(expired) > 0 {
+		_, _ = p.db.Exec(`DELETE FROM whitelist_entries WHERE expires_at > 0 AND expires_at <= ?`, time.Now().UnixMilli())
+	}
+	return len(expired)
+}
+
+// StartPurgeLoop periodically removes expired guest passes until ctx is done
+func (p *SQLiteProvider) StartPurgeLoop(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n := p.PurgeExpired(); n > 0 {
+					p.log.Info("Purged expired whitelist entries", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// Close closes the database connection
+func (p *SQLiteProvider) Close() error {
+	if p.db != nil {
+		return p.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package whitelist
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestSQLiteProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whitelist.db")
+	p := NewSQLiteProvider(logr.Discard(), path)
+
+	add := func(e Entry, guest bool) {
+		t.Helper()
+		if err := p.Add(e, guest, "Admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(Entry{Username: "Steve", UUID: "uuid-1"}, false)
+	add(Entry{Username: "alex", Tier: 1}, false)
+	add(Entry{Username: "Guest", ExpiresAt: time.Now().Add(time.Hour)}, true)
+	add(Entry{Username: "Old", ExpiresAt: time.Now().Add(-time.Minute)}, true)
+	// Re-adding replaces the entry under the same name
+	add(Entry{Username: "Alex", Tier: 2}, false)
+
+	tests := []struct {
+		name string
+		req  Request
+		want CheckResult
+	}{
+		{"permanent", Request{Username: "STEVE"}, Allowed},
+		{"by uuid", Request{Username: "Renamed", UUID: "UUID-1"}, Allowed},
+		{"replaced tier", Request{Username: "alex", Tier: 2}, Allowed},
+		{"above tier", Request{Username: "alex", Tier: 3}, NotInWhitelist},
+		{"guest pass", Request{Username: "guest"}, Allowed},
+		{"expired guest pass", Request{Username: "Old"}, NoDecision},
+		{"unknown", Request{Username: "Herobrine"}, NoDecision},
+	}
+	check := func(label string, p *SQLiteProvider) {
+		t.Helper()
+		for _, tt := range tests {
+			t.Run(label+"/"+tt.name, func(t *testing.T) {
+				if got := p.Check(t.Context(), tt.req); got.Status != tt.want {
+					t.Fatalf("Check(%+v) = %+v, want status %d", tt.req, got, tt.want)
+				}
+			})
+		}
+	}
+	check("open", p)
+
+	list := p.List()
+	if len(list) != 4 || list[0].Username != "Alex" || list[2].Username != "Old" || list[3].Username != "Steve" {
+		t.Fatalf("List() = %+v, want 4 entries sorted by name", list)
+	}
+	if g := list[1]; !g.Guest || g.AddedBy != "Admin" || g.ExpiresAt.IsZero() {
+		t.Fatalf("guest entry = %+v", g)
+	}
+	p.Close()
+
+	// Entries, including expiry and guest flags, survive a restart
+	reopened := NewSQLiteProvider(logr.Discard(), path)
+	defer reopened.Close()
+	check("reopened", reopened)
+	if len(reopened.List()) != 4 {
+		t.Fatalf("List() after reopen = %+v", reopened.List())
+	}
+}
+
+func TestSQLiteProviderRemoveAndPurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whitelist.db")
+	p := NewSQLiteProvider(logr.Discard(), path)
+	for _, e := range []Entry{
+		{Username: "Steve"},
+		{Username: "Old", ExpiresAt: time.Now().Add(-time.Minute)},
+		{Username: "Older", ExpiresAt: time.Now().Add(-time.Hour)},
+		{Username: "Guest", ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		if err := p.Add(e, !e.ExpiresAt.IsZero(), "Admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"steve", true},
+		{"Steve", false},
+		{"Herobrine", false},
+	}
+	for _, tt := range tests {
+		removed, err := p.Remove(tt.username)
+		if err != nil || removed != tt.want {
+			t.Fatalf("Remove(%s) = %v, %v, want %v", tt.username, removed, err, tt.want)
+		}
+	}
+
+	if n := p.PurgeExpired(); n != 2 {
+		t.Fatalf("PurgeExpired() = %d, want 2", n)
+	}
+	if n := p.PurgeExpired(); n != 0 {
+		t.Fatalf("second PurgeExpired() = %d, want 0", n)
+	}
+	p.Close()
+
+	reopened := NewSQLiteProvider(logr.Discard(), path)
+	defer reopened.Close()
+	if list := reopened.List(); len(list) != 1 || list[0].Username != "Guest" {
+		t.Fatalf("List() after reopen = %+v, want only Guest", list)
+	}
+}
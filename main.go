@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -16,12 +20,23 @@ import (
 	"go.minekube.com/gate/cmd/gate"
 	"go.minekube.com/gate/pkg/command"
 	"go.minekube.com/gate/pkg/edition/java/proxy"
+	gateperm "go.minekube.com/gate/pkg/util/permission"
 
+	"github.com/RMS-Server/RMS-Gate/internal/altlimit"
+	"github.com/RMS-Server/RMS-Gate/internal/asn"
+	"github.com/RMS-Server/RMS-Gate/internal/audit"
 	"github.com/RMS-Server/RMS-Gate/internal/config"
 	"github.com/RMS-Server/RMS-Gate/internal/dynamicserver"
+	"github.com/RMS-Server/RMS-Gate/internal/iprules"
 	"github.com/RMS-Server/RMS-Gate/internal/loadbalancer"
+	"github.com/RMS-Server/RMS-Gate/internal/login"
+	"github.com/RMS-Server/RMS-Gate/internal/maintenance"
 	"github.com/RMS-Server/RMS-Gate/internal/mcsmanager"
 	"github.com/RMS-Server/RMS-Gate/internal/permission"
+	"github.com/RMS-Server/RMS-Gate/internal/revalidation"
+	"github.com/RMS-Server/RMS-Gate/internal/rmsapi"
+	"github.com/RMS-Server/RMS-Gate/internal/serveraccess"
+	"github.com/RMS-Server/RMS-Gate/internal/throttle"
 	"github.com/RMS-Server/RMS-Gate/internal/whitelist"
 )
 
@@ -44,11 +59,23 @@ type RMSWhitelist struct {
 	proxy         *proxy.Proxy
 	log           logr.Logger
 	config        *config.Config
+	api           *rmsapi.Client
 	checker       *whitelist.Checker
-	mcsClient     *mcsmanager.Client
+	localWL       *whitelist.SQLiteProvider
+	mcsPool       *mcsmanager.Pool
 	dynamicServer *dynamicserver.Manager
 	permission    *permission.Manager
 	loadBalancer  *loadbalancer.LoadBalancer
+	maintenance   *maintenance.Manager
+	loginPipeline *login.Pipeline
+	throttle      *throttle.Limiter
+	ipRules       *iprules.Manager
+	altLimit      *altlimit.Limiter
+	asnDB         *asn.Database
+	asnPolicy     *asn.Policy
+	audit         *audit.Log
+	serverAccess  *serveraccess.Policy
+	revalidation  *revalidation.Manager
 }
 
 func newRMSWhitelist(ctx context.Context, p *proxy.Proxy) *RMSWhitelist {
@@ -64,18 +91,112 @@ func (r *RMSWhitelist) init() error {
 
 	configDir := getPluginDataDir()
 	r.config = config.LoadConfig(configDir, r.log)
-	r.checker = whitelist.NewChecker(r.log)
+
+	pool := apiEndpointPool(r.config)
+	timeout := time.Duration(r.config.TimeoutSeconds) * time.Second
+	api, err := rmsapi.NewClient(apiAuthConfig(configDir, r.config.APIAuth), pool, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to set up RMS API client: %w", err)
+	}
+	r.api = api
+	r.log.Info("RMS API endpoints configured", "endpoints", len(pool.Status()))
+
+	var graceCache *whitelist.GraceCache
+	if r.config.OfflineGrace != nil && r.config.OfflineGrace.Enabled {
+		grace := time.Duration(r.config.OfflineGrace.GraceSeconds) * time.Second
+		graceCache = whitelist.NewGraceCache(r.log, configDir, grace)
+		r.log.Info("Whitelist offline grace cache enabled", "grace", grace)
+	}
+	r.checker = whitelist.NewChecker(r.log, r.buildWhitelistProvider(configDir), graceCache)
+	r.log.Info("Whitelist providers configured", "chain", r.checker.Provider().Name())
+
+	if r.config.Audit != nil && r.config.Audit.Enabled {
+		r.audit = audit.NewLog(r.log, configDir)
+	}
+
+	r.maintenance = maintenance.NewManager(r.log, configDir)
+
+	r.serverAccess = serveraccess.NewPolicy(serverAccessRules(r.config.ServerAccess))
+	if n := r.serverAccess.Servers(); n > 0 {
+		r.log.Info("Server access rules enabled", "servers", n)
+	}
+
+	r.ipRules = iprules.NewManager(r.log, configDir, r.config.IPRules.Allow, r.config.IPRules.Deny)
+	r.ipRules.StartPurgeLoop(r.ctx, time.Minute)
+
+	if r.config.ASN != nil && r.config.ASN.Enabled {
+		ac := r.config.ASN
+		policy, errs := asn.NewPolicy(ac.Policies, ac.DefaultPolicy)
+		for _, err := range errs {
+			r.log.Error(err, "Invalid ASN policy, ignoring")
+		}
+		r.asnPolicy = policy
+		path := resolveDataPath(configDir, ac.DatabasePath, "asn.csv")
+		r.asnDB = asn.NewDatabase(r.ctx, r.log, path, time.Duration(ac.ReloadIntervalSeconds)*time.Second)
+		r.log.Info("ASN detection enabled", "database", path, "defaultPolicy", ac.DefaultPolicy)
+	}
+
+	if r.config.AltLimit != nil && r.config.AltLimit.Enabled {
+		ac := r.config.AltLimit
+		r.altLimit = altlimit.NewLimiter(&altlimit.Config{
+			MaxAccounts:   ac.MaxAccounts,
+			IPv4PrefixLen: ac.IPv4PrefixLen,
+			IPv6PrefixLen: ac.IPv6PrefixLen,
+		})
+		r.log.Info("Alt-account limit enabled", "maxAccounts", ac.MaxAccounts, "ipv4PrefixLen", ac.IPv4PrefixLen, "ipv6PrefixLen", ac.IPv6PrefixLen)
+	}
+
+	if r.config.Throttle != nil && r.config.Throttle.Enabled {
+		tc := r.config.Throttle
+		r.throttle = throttle.NewLimiter(r.ctx, r.log, &throttle.Config{
+			PerIPPerMinute: tc.PerIPPerMinute,
+			PerIPBurst:     tc.PerIPBurst,
+			GlobalPerSec:   tc.GlobalPerSecond,
+			GlobalBurst:    tc.GlobalBurst,
+			BanThreshold:   tc.BanThreshold,
+			BanWindow:      time.Duration(tc.BanWindowSeconds) * time.Second,
+			BanDuration:    time.Duration(tc.BanSeconds) * time.Second,
+			MaxInFlight:    tc.MaxInFlight,
+			InFlightWait:   time.Duration(tc.InFlightWaitMillis) * time.Millisecond,
+		})
+		r.log.Info("Login throttling enabled", "perIpPerMinute", tc.PerIPPerMinute, "globalPerSecond", tc.GlobalPerSecond, "maxInFlight", tc.MaxInFlight)
+	}
+
+	r.loginPipeline = r.buildLoginPipeline([]login.LoginCheck{
+		login.NewCheck("iprules", r.ipRulesCheck),
+		login.NewCheck("asn", r.asnCheck),
+		login.NewCheck("throttle", r.throttleCheck),
+		login.NewCheck("maintenance", r.maintenanceCheck),
+		login.NewCheck("alts", r.altsCheck),
+		login.NewCheck("whitelist", r.whitelistCheck),
+	})
+	r.log.Info("Login checks configured", "checks", r.loginPipeline.Checks())
 
 	if r.config.MCSManager != nil && r.config.DynamicServer != nil {
-		mcsCfg := &mcsmanager.Config{
-			BaseURL:  r.config.MCSManager.BaseURL,
-			APIKey:   r.config.MCSManager.APIKey,
-			DaemonID: r.config.MCSManager.DaemonID,
+		panels := map[string]*mcsmanager.Config{
+			mcsmanager.DefaultPanel: mcsPanelConfig(&r.config.MCSManager.MCSManagerPanelConfig),
+		}
+		for name, panel := range r.config.MCSManager.Panels {
+			if panel == nil {
+				continue
+			}
+			// The top-level settings own the default name, an entry must not silently replace them
+			if strings.EqualFold(name, mcsmanager.DefaultPanel) {
+				r.log.Error(nil, "Ignoring mcsManager panel that uses the reserved name, configure the default panel at the top level",
+					"panel", name)
+				continue
+			}
+			panels[name] = mcsPanelConfig(panel)
+		}
+		r.mcsPool = mcsmanager.NewPool(r.log, panels)
+
+		instances := make(map[string]mcsmanager.Instance, len(r.config.DynamicServer.ServerUUIDMap))
+		for server, inst := range r.config.DynamicServer.ServerUUIDMap {
+			instances[server] = mcsmanager.Instance{Panel: inst.Panel, DaemonID: inst.DaemonID, UUID: inst.UUID}
 		}
-		r.mcsClient = mcsmanager.NewClient(r.log, mcsCfg)
 
 		dsCfg := &dynamicserver.Config{
-			ServerUUIDMap:              r.config.DynamicServer.ServerUUIDMap,
+			ServerUUIDMap:              instances,
 			AutoStartServers:           r.config.DynamicServer.AutoStartServers,
 			StartupTimeoutSeconds:      r.config.DynamicServer.StartupTimeoutSeconds,
 			PollIntervalSeconds:        r.config.DynamicServer.PollIntervalSeconds,
@@ -83,19 +204,41 @@ func (r *RMSWhitelist) init() error {
 			IdleShutdownSeconds:        r.config.DynamicServer.IdleShutdownSeconds,
 			MsgStarting:                r.config.DynamicServer.MsgStarting,
 			MsgStartupTimeout:          r.config.DynamicServer.MsgStartupTimeout,
+			MsgPanelUnavailable:        r.config.DynamicServer.MsgPanelUnavailable,
 		}
-		r.dynamicServer = dynamicserver.NewManager(r.ctx, r.log, r.proxy, r.mcsClient, dsCfg)
+		r.dynamicServer = dynamicserver.NewManager(r.ctx, r.log, r.proxy, r.mcsPool, dsCfg)
+		r.dynamicServer.SetAuditLog(r.audit)
 		r.log.Info("Dynamic server management enabled")
 	}
 
 	if r.config.Permission != nil && r.config.Permission.Enabled {
-		r.permission = permission.NewManager(r.log, r.config.APIUrl, r.config.Permission.CacheTTLSeconds, r.config.Permission.AdminCommands)
+		permCfg := r.config.Permission
+		if permCfg.VerifyResponses && (r.config.APIAuth == nil || (r.config.APIAuth.ResponseSecret == "" && r.config.APIAuth.HMACSecret == "")) {
+			r.log.Error(nil, "permission.verifyResponses is set but apiAuth has no responseSecret or hmacSecret, all permission responses will be rejected")
+		}
+		overridesPath := resolveDataPath(configDir, permCfg.OverridesPath, "permission-overrides.json")
+		overrides := permission.NewOverrides(r.ctx, r.log, overridesPath, 0)
+		r.permission = permission.NewManager(r.log, r.api, &permission.Config{
+			CacheTTLSeconds:        permCfg.CacheTTLSeconds,
+			RefreshIntervalSeconds: permCfg.RefreshIntervalSeconds,
+			NegativeCacheSeconds:   permCfg.NegativeCacheSeconds,
+			AdminCommands:          permCfg.AdminCommands,
+			PluginCommands:         pluginCommands,
+			CommandLevels:          permCfg.CommandLevels,
+			Nodes:                  permCfg.Nodes,
+			VerifyResponses:        permCfg.VerifyResponses,
+			Overrides:              overrides,
+			Elevation:              elevationConfig(permCfg.Elevation),
+			Audit:                  r.audit,
+		})
+		r.permission.Start(r.ctx)
 		r.log.Info("Permission management enabled", "adminCommands", r.config.Permission.AdminCommands)
 	}
 
 	if r.config.LoadBalancer != nil && r.config.LoadBalancer.Enabled {
 		lbCfg := convertLoadBalancerConfig(r.config.LoadBalancer)
 		lb := loadbalancer.NewLoadBalancer(r.ctx, r.log, r.proxy, lbCfg, configDir)
+		lb.SetAuditLog(r.audit)
 		if err := lb.Start(); err != nil {
 			r.log.Error(err, "Failed to start load balancer")
 		} else {
@@ -104,9 +247,27 @@ func (r *RMSWhitelist) init() error {
 		}
 	}
 
+	if r.config.Revalidation != nil && r.config.Revalidation.Enabled {
+		rvCfg := &revalidation.Config{
+			IntervalSeconds:  r.config.Revalidation.IntervalSeconds,
+			BatchSize:        r.config.Revalidation.BatchSize,
+			BatchDelayMillis: r.config.Revalidation.BatchDelayMillis,
+			ListenAddr:       r.config.Revalidation.ListenAddr,
+			Token:            r.config.Revalidation.Token,
+		}
+		r.revalidation = revalidation.NewManager(r.ctx, r.log, r.proxy, rvCfg, r.revalidatePlayer, r.revokePlayer)
+		r.revalidation.Start()
+		r.log.Info("Whitelist revalidation enabled", "interval", r.config.Revalidation.IntervalSeconds)
+	}
+
 	event.Subscribe(r.proxy.Event(), 0, r.onLogin)
+	event.Subscribe(r.proxy.Event(), 0, r.onChooseInitialServer)
 	event.Subscribe(r.proxy.Event(), -100, r.onServerPreConnect)
+	event.Subscribe(r.proxy.Event(), 0, r.onDisconnect)
+	event.Subscribe(r.proxy.Event(), 0, r.onPing)
 	event.Subscribe(r.proxy.Event(), -100, r.onCommandExecute)
+	// Late so our function wraps whatever other plugins installed
+	event.Subscribe(r.proxy.Event(), -100, r.onPermissionsSetup)
 
 	r.registerCommands()
 
@@ -114,6 +275,146 @@ func (r *RMSWhitelist) init() error {
 	return nil
 }
 
+// buildWhitelistProvider assembles the provider chain from config. The local
+// SQLite list backing /wl is always available and asked first unless placed explicitly.
+func (r *RMSWhitelist) buildWhitelistProvider(configDir string) whitelist.Provider {
+	wlCfg := r.config.Whitelist
+	if wlCfg == nil {
+		wlCfg = &config.WhitelistConfig{}
+	}
+	names := wlCfg.Providers
+	if len(names) == 0 {
+		names = []string{"http"}
+	}
+
+	r.localWL = whitelist.NewSQLiteProvider(r.log, resolveDataPath(configDir, wlCfg.SQLitePath, "whitelist.db"))
+	r.localWL.StartPurgeLoop(r.ctx, time.Minute)
+
+	providers := make([]whitelist.Provider, 0, len(names)+1)
+	hasLocal := false
+	for _, name := range names {
+		switch name {
+		case "http":
+			providers = append(providers, whitelist.NewHTTPProvider(r.log, r.api, contextFields(wlCfg.LoginContext)))
+		case "file":
+			path := resolveDataPath(configDir, wlCfg.FilePath, "whitelist.yml")
+			interval := time.Duration(wlCfg.ReloadIntervalSeconds) * time.Second
+			providers = append(providers, whitelist.NewFileProvider(r.ctx, r.log, path, interval))
+		case "sqlite":
+			if !hasLocal {
+				providers = append(providers, r.localWL)
+				hasLocal = true
+			}
+		default:
+			r.log.Error(nil, "Unknown whitelist provider, ignoring", "provider", name)
+		}
+	}
+
+	if !hasLocal {
+		providers = append([]whitelist.Provider{r.localWL}, providers...)
+	}
+	return whitelist.NewChain(providers...)
+}
+
+// apiEndpointPool builds the RMS API pool from apiEndpoints, falling back to apiUrl
+func apiEndpointPool(cfg *config.Config) *rmsapi.Pool {
+	var urls []string
+	var priorities []int
+	for _, ep := range cfg.APIEndpoints {
+		if ep == nil || ep.URL == "" {
+			continue
+		}
+		urls = append(urls, ep.URL)
+		priorities = append(priorities, ep.Priority)
+	}
+	if len(urls) == 0 && cfg.APIUrl != "" {
+		urls = []string{cfg.APIUrl}
+		priorities = []int{0}
+	}
+	return rmsapi.NewPool(urls, priorities, cfg.APIFailover.FailThreshold, time.Duration(cfg.APIFailover.CooldownSeconds)*time.Second)
+}
+
+// apiAuthConfig converts the apiAuth section, resolving certificate paths against the data dir
+func apiAuthConfig(configDir string, cfg *config.APIAuthConfig) *rmsapi.AuthConfig {
+	if cfg == nil {
+		return &rmsapi.AuthConfig{}
+	}
+	optional := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		return resolveDataPath(configDir, path, "")
+	}
+	return &rmsapi.AuthConfig{
+		BearerToken:    cfg.BearerToken,
+		HMACSecret:     cfg.HMACSecret,
+		CAFile:         optional(cfg.CAFile),
+		ClientCertFile: optional(cfg.ClientCertFile),
+		ClientKeyFile:  optional(cfg.ClientKeyFile),
+		ResponseSecret: cfg.ResponseSecret,
+	}
+}
+
+func contextFields(cfg *config.LoginContextConfig) whitelist.ContextFields {
+	if cfg == nil {
+		return whitelist.ContextFields{}
+	}
+	return whitelist.ContextFields{
+		IP:          cfg.IP,
+		Protocol:    cfg.Protocol,
+		VirtualHost: cfg.VirtualHost,
+		OnlineMode:  cfg.OnlineMode,
+		ASN:         cfg.ASN,
+	}
+}
+
+// resolveDataPath resolves a configured path relative to the plugin data directory
+func resolveDataPath(configDir, path, fallback string) string {
+	if path == "" {
+		path = fallback
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(configDir, path)
+}
+
+func serverAccessRules(cfg map[string]*config.ServerAccessConfig) map[string]*serveraccess.Rule {
+	rules := make(map[string]*serveraccess.Rule, len(cfg))
+	for name, sc := range cfg {
+		if sc != nil {
+			rules[name] = &serveraccess.Rule{MinLevel: sc.MinLevel, Members: sc.Members}
+		}
+	}
+	return rules
+}
+
+func mcsPanelConfig(cfg *config.MCSManagerPanelConfig) *mcsmanager.Config {
+	return &mcsmanager.Config{
+		BaseURL:  cfg.BaseURL,
+		APIKey:   cfg.APIKey,
+		DaemonID: cfg.DaemonID,
+	}
+}
+
+func elevationConfig(cfg *config.ElevationConfig) *permission.ElevationConfig {
+	if cfg == nil {
+		return nil
+	}
+	cooldown := cfg.CooldownSeconds
+	if cooldown <= 0 {
+		cooldown = cfg.MaxSeconds
+	}
+	return &permission.ElevationConfig{
+		Enabled:       cfg.Enabled,
+		MinLevel:      cfg.MinLevel,
+		Level:         cfg.Level,
+		MaxDuration:   time.Duration(cfg.MaxSeconds) * time.Second,
+		Cooldown:      time.Duration(cooldown) * time.Second,
+		RequireReason: cfg.RequireReason,
+	}
+}
+
 func convertLoadBalancerConfig(cfg *config.LoadBalancerConfig) *loadbalancer.Config {
 	servers := make(map[string]*loadbalancer.ServerConfig)
 	for name, srv := range cfg.Servers {
@@ -146,455 +447,2317 @@ func convertLoadBalancerConfig(cfg *config.LoadBalancerConfig) *loadbalancer.Con
 
 func (r *RMSWhitelist) onLogin(e *proxy.LoginEvent) {
 	player := e.Player()
-	username := player.Username()
-	uuid := player.ID().String()
-
-	result := r.checker.Check(r.ctx, username, uuid, r.config.APIUrl, r.config.TimeoutSeconds, r.config.ServerTier)
+	attempt := &login.Attempt{
+		Player:   player,
+		Username: player.Username(),
+		UUID:     player.ID().String(),
+		IP:       hostOf(player.RemoteAddr()),
+	}
+	if rec := r.lookupASN(attempt.IP); rec != nil {
+		attempt.ASN, attempt.ASOrg = rec.ASN, rec.Org
+	}
 
-	switch result {
-	case whitelist.Allowed:
-		r.log.Info("User is whitelisted", "username", username, "uuid", uuid)
-	case whitelist.NotInWhitelist:
-		r.log.Info("User is not in whitelist", "username", username, "uuid", uuid)
-		e.Deny(&component.Text{Content: r.config.MsgNotInWhitelist})
-	case whitelist.ServerError:
-		r.log.Error(nil, "Whitelist check failed", "username", username, "uuid", uuid)
-		e.Deny(&component.Text{Content: r.config.MsgServerError})
+	outcome := r.loginPipeline.Run(r.ctx, attempt)
+	if outcome.Decision.Denied {
+		e.Deny(outcome.Decision.Message)
 	}
 }
 
-func (r *RMSWhitelist) onServerPreConnect(e *proxy.ServerPreConnectEvent) {
-	r.log.Info("ServerPreConnectEvent triggered")
-
-	if r.dynamicServer == nil {
-		r.log.Info("dynamicServer is nil, skipping")
-		return
+func (r *RMSWhitelist) ipRulesCheck(_ context.Context, a *login.Attempt) login.Decision {
+	d := r.ipRules.Evaluate(a.IP)
+	if !d.Denied {
+		return login.Allow()
 	}
 
-	server := e.Server()
-	if server == nil {
-		r.log.Info("server is nil, skipping")
-		return
+	switch d.Rule {
+	case iprules.RuleNotAllowed:
+		return login.Deny("ip not in allow list", &component.Text{Content: r.config.IPRules.MsgNotAllowed})
+	case iprules.RuleBan:
+		return login.Deny("ip banned ("+d.Prefix.String()+")", r.ipBanMessage(d.Ban))
+	default:
+		return login.Deny("ip denied by config ("+d.Prefix.String()+")", &component.Text{Content: r.config.IPRules.MsgDenied})
 	}
+}
 
-	serverName := server.ServerInfo().Name()
-	player := e.Player()
-
-	r.log.Info("Checking server", "server", serverName, "player", player.Username())
-
-	if !r.dynamicServer.IsAutoStartServer(serverName) {
-		r.log.Info("Server is not in auto-start list, skipping", "server", serverName)
-		return
+func (r *RMSWhitelist) ipBanMessage(ban *iprules.Ban) component.Component {
+	msg := &component.Text{Content: r.config.IPRules.MsgDenied}
+	addLine := func(format string, args ...any) {
+		if format == "" {
+			return
+		}
+		msg.Extra = append(msg.Extra, &component.Text{
+			Content: "\n" + fmt.Sprintf(format, args...),
+			S:       component.Style{Color: color.Gray},
+		})
 	}
 
-	r.log.Info("Player attempting to connect to auto-start server", "player", player.Username(), "server", serverName)
+	if ban.Reason != "" {
+		addLine(r.config.MsgDenyReason, ban.Reason)
+	}
+	if !ban.ExpiresAt.IsZero() {
+		addLine(r.config.MsgBannedUntil, ban.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	return msg
+}
 
-	instanceUUID := r.config.DynamicServer.ServerUUIDMap[serverName]
-	status, err := r.mcsClient.GetInstanceStatus(r.ctx, instanceUUID)
-	r.log.Info("Checking instance status via MCSManager", "server", serverName, "status", status, "err", err)
+func (r *RMSWhitelist) asnCheck(_ context.Context, a *login.Attempt) login.Decision {
+	if r.asnPolicy == nil || a.ASN == 0 {
+		return login.Allow()
+	}
 
-	if status == 3 {
-		r.log.Info("Server is already running (MCSManager status=3)", "server", serverName)
-		return
+	rec := &asn.Record{ASN: a.ASN, Org: a.ASOrg}
+	switch r.asnPolicy.Decide(rec) {
+	case asn.Deny:
+		return login.Deny(fmt.Sprintf("AS%d (%s) denied", a.ASN, a.ASOrg), &component.Text{Content: r.config.ASN.MsgDenied})
+	case asn.Flag:
+		r.log.Info("Login from flagged ASN", "username", a.Username, "uuid", a.UUID, "ip", a.IP, "asn", a.ASN, "org", a.ASOrg)
 	}
+	return login.Allow()
+}
 
-	r.log.Info("Server is offline, starting it", "server", serverName, "player", player.Username())
+// lookupASN returns the ASN record of ip, or nil if ASN detection is off or the address is unknown
+func (r *RMSWhitelist) lookupASN(ip string) *asn.Record {
+	if r.asnDB == nil {
+		return nil
+	}
+	rec, ok := r.asnDB.Lookup(ip)
+	if !ok {
+		return nil
+	}
+	return rec
+}
 
-	msg := fmt.Sprintf(r.config.DynamicServer.MsgStarting, serverName)
-	player.SendMessage(&component.Text{Content: msg})
+func (r *RMSWhitelist) throttleCheck(_ context.Context, a *login.Attempt) login.Decision {
+	if r.throttle == nil || a.IP == "" {
+		return login.Allow()
+	}
 
-	started := r.dynamicServer.EnsureServerRunning(serverName)
-	if !started {
-		r.log.Error(nil, "Failed to start server", "server", serverName, "player", player.Username())
-		timeoutMsg := fmt.Sprintf(r.config.DynamicServer.MsgStartupTimeout, serverName)
-		player.SendMessage(&component.Text{Content: timeoutMsg})
-		e.Deny()
-	} else {
-		r.log.Info("Server started successfully", "server", serverName, "player", player.Username())
+	verdict, bannedUntil := r.throttle.Allow(a.IP)
+	switch verdict {
+	case throttle.Banned:
+		msg := fmt.Sprintf(r.config.Throttle.MsgBanned, bannedUntil.Local().Format("2006-01-02 15:04:05"))
+		return login.Deny("ip auto-banned", &component.Text{Content: msg})
+	case throttle.RateLimited:
+		return login.Deny("ip rate limited", &component.Text{Content: r.config.Throttle.MsgThrottled})
+	case throttle.GlobalLimited:
+		return login.Deny("global rate limited", &component.Text{Content: r.config.Throttle.MsgThrottled})
 	}
+	return login.Allow()
 }
 
-func isServerOnline(addr net.Addr) bool {
-	conn, err := net.DialTimeout(addr.Network(), addr.String(), 3*time.Second)
-	if err != nil {
-		return false
+func (r *RMSWhitelist) maintenanceCheck(_ context.Context, a *login.Attempt) login.Decision {
+	state := r.maintenance.State()
+	if !state.Enabled || r.bypassesMaintenance(a.UUID, a.Username) {
+		return login.Allow()
 	}
-	conn.Close()
-	return true
+	return login.Deny("maintenance", r.maintenanceMessage(state))
 }
 
-func (r *RMSWhitelist) onCommandExecute(e *proxy.CommandExecuteEvent) {
-	if r.permission == nil {
-		return
+func (r *RMSWhitelist) altsCheck(ctx context.Context, a *login.Attempt) login.Decision {
+	if r.altLimit == nil {
+		return login.Allow()
 	}
 
-	player, ok := e.Source().(proxy.Player)
-	if !ok {
-		return
+	count, ok := r.altLimit.Check(a.IP, a.UUID, r.onlineSessions())
+	if ok {
+		return login.Allow()
 	}
 
-	cmd := e.Command()
-	username := player.Username()
+	cfg := r.config.AltLimit
+	if cfg.ExemptLevel > 0 && r.permission != nil && r.permission.GetPermissionLevel(ctx, a.UUID, a.Username) >= cfg.ExemptLevel {
+		r.log.Info("Alt-account limit exceeded by exempt player", "username", a.Username, "group", r.altLimit.Key(a.IP), "online", count)
+		return login.Allow()
+	}
 
-	if !r.permission.CanExecute(r.ctx, username, cmd) {
-		e.SetAllowed(false)
-		r.log.Info("Command blocked due to insufficient permission", "player", username, "command", cmd)
-		player.SendMessage(&component.Text{
-			Content: r.config.Permission.MsgNoPermission,
-			S:       component.Style{Color: color.Red},
+	msg := fmt.Sprintf(cfg.MsgTooMany, count, cfg.MaxAccounts)
+	return login.Deny(fmt.Sprintf("%d accounts online from %s", count, r.altLimit.Key(a.IP)), &component.Text{Content: msg})
+}
+
+// onlineSessions lists the connected players with their addresses
+func (r *RMSWhitelist) onlineSessions() []altlimit.Session {
+	players := r.proxy.Players()
+	sessions := make([]altlimit.Session, 0, len(players))
+	for _, p := range players {
+		sessions = append(sessions, altlimit.Session{
+			Username: p.Username(),
+			UUID:     p.ID().String(),
+			IP:       hostOf(p.RemoteAddr()),
 		})
 	}
+	return sessions
 }
 
-func (r *RMSWhitelist) registerCommands() {
-	r.proxy.Command().Register(brigodier.Literal("dserver").
-		Then(brigodier.Literal("delay").
-			Then(brigodier.Argument("server", brigodier.String).
-				Then(brigodier.Argument("time", brigodier.String).
-					Executes(command.Command(func(ctx *command.Context) error {
-						return r.cmdDelay(ctx)
-					}))))).
-		Then(brigodier.Literal("autoshutdown").
-			Then(brigodier.Argument("server", brigodier.String).
-				Then(brigodier.Argument("toggle", brigodier.String).
-					Executes(command.Command(func(ctx *command.Context) error {
-						return r.cmdAutoShutdown(ctx)
-					}))))).
-		Executes(command.Command(func(ctx *command.Context) error {
-			return r.cmdHelp(ctx)
-		})))
+func (r *RMSWhitelist) whitelistCheck(ctx context.Context, a *login.Attempt) login.Decision {
+	if r.throttle != nil {
+		release, ok := r.throttle.Acquire(ctx)
+		if !ok {
+			return login.Deny("too many whitelist checks in flight", &component.Text{Content: r.config.Throttle.MsgBusy})
+		}
+		defer release()
+	}
 
-	r.proxy.Command().Register(brigodier.Literal("lb").
-		Then(brigodier.Literal("status").
-			Then(brigodier.Argument("server", brigodier.String).
-				Executes(command.Command(func(ctx *command.Context) error {
-					return r.cmdLBStatus(ctx)
-				}))).
-			Executes(command.Command(func(ctx *command.Context) error {
-				return r.cmdLBStatusAll(ctx)
-			}))).
-		Then(brigodier.Literal("disable").
-			Then(brigodier.Argument("server", brigodier.String).
-				Then(brigodier.Argument("backend", brigodier.String).
-					Executes(command.Command(func(ctx *command.Context) error {
-						return r.cmdLBDisable(ctx)
-					}))))).
-		Then(brigodier.Literal("enable").
-			Then(brigodier.Argument("server", brigodier.String).
-				Then(brigodier.Argument("backend", brigodier.String).
-					Executes(command.Command(func(ctx *command.Context) error {
-						return r.cmdLBEnable(ctx)
-					}))))).
-		Executes(command.Command(func(ctx *command.Context) error {
-			return r.cmdLBHelp(ctx)
-		})))
-}
+	result := r.checker.Check(ctx, r.whitelistRequest(a.Player, r.config.ServerTier))
 
-func (r *RMSWhitelist) cmdHelp(ctx *command.Context) error {
-	ctx.Source.SendMessage(&component.Text{Content: "Dynamic Server Management Commands:", S: component.Style{Color: color.Gold}})
-	ctx.Source.SendMessage(&component.Text{Content: "  /dserver delay <server> <time|off> - Set/clear protection period", S: component.Style{Color: color.Yellow}})
-	ctx.Source.SendMessage(&component.Text{Content: "    Time format: 10s, 5m, 2h or plain seconds", S: component.Style{Color: color.Gray}})
-	ctx.Source.SendMessage(&component.Text{Content: "  /dserver autoshutdown <server> <on|off> - Toggle auto-shutdown", S: component.Style{Color: color.Yellow}})
-	return nil
+	switch result.Status {
+	case whitelist.Allowed:
+		r.log.Info("User is whitelisted", "username", a.Username, "uuid", a.UUID, "source", result.Source, "provider", result.Provider)
+	case whitelist.NotInWhitelist:
+		r.log.Info("User is not in whitelist", "username", a.Username, "uuid", a.UUID, "source", result.Source, "provider", result.Provider,
+			"reason", result.Reason, "banUntil", result.BanUntil, "requiredTier", result.RequiredTier)
+		return login.Deny("not whitelisted", r.denyMessage(result))
+	case whitelist.ServerError:
+		r.log.Error(nil, "Whitelist check failed", "username", a.Username, "uuid", a.UUID, "source", result.Source)
+		return login.Deny("whitelist API error", &component.Text{Content: r.config.MsgServerError})
+	}
+	return login.Allow()
 }
 
-func (r *RMSWhitelist) cmdDelay(ctx *command.Context) error {
-	if r.dynamicServer == nil {
-		ctx.Source.SendMessage(&component.Text{Content: "Dynamic server management is not enabled", S: component.Style{Color: color.Red}})
-		return nil
+// buildLoginPipeline orders the available checks as configured. Checks that are
+// not listed keep running after the listed ones, so new checks are never dropped silently.
+func (r *RMSWhitelist) buildLoginPipeline(available []login.LoginCheck) *login.Pipeline {
+	byName := make(map[string]login.LoginCheck, len(available))
+	for _, c := range available {
+		byName[c.Name()] = c
 	}
 
-	serverName := ctx.String("server")
-	timeArg := ctx.String("time")
-
-	if !r.dynamicServer.IsAutoStartServer(serverName) {
-		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Server '%s' is not a managed dynamic server", serverName), S: component.Style{Color: color.Red}})
-		return nil
+	var checks []login.LoginCheck
+	seen := make(map[string]bool)
+	if r.config.Login != nil {
+		for _, cc := range r.config.Login.Checks {
+			if cc == nil || seen[cc.Name] {
+				continue
+			}
+			c, ok := byName[cc.Name]
+			if !ok {
+				r.log.Error(nil, "Unknown login check, ignoring", "check", cc.Name)
+				continue
+			}
+			seen[cc.Name] = true
+			if cc.Enabled {
+				checks = append(checks, c)
+			}
+		}
 	}
-
-	if timeArg == "off" {
-		r.dynamicServer.ClearProtectionPeriod(serverName)
-		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Cleared protection period for server '%s'", serverName), S: component.Style{Color: color.Green}})
-		return nil
+	for _, c := range available {
+		if !seen[c.Name()] {
+			checks = append(checks, c)
+		}
 	}
+	return login.NewPipeline(r.log, checks)
+}
 
-	seconds, err := parseTimeString(timeArg)
-	if err != nil || seconds < 0 {
-		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid time format: %s", timeArg), S: component.Style{Color: color.Red}})
-		ctx.Source.SendMessage(&component.Text{Content: "Use format like: 30s, 5m, 2h or plain seconds", S: component.Style{Color: color.Gray}})
-		return nil
+func (r *RMSWhitelist) revalidatePlayer(ctx context.Context, player proxy.Player) whitelist.Result {
+	return r.checker.Revalidate(ctx, r.whitelistRequest(player, r.config.ServerTier))
+}
+
+func (r *RMSWhitelist) revokePlayer(player proxy.Player, result whitelist.Result) {
+	player.Disconnect(r.denyMessage(result))
+}
+
+// bypassesMaintenance reports whether the player's permission level lets them join during maintenance
+func (r *RMSWhitelist) bypassesMaintenance(uuid, username string) bool {
+	if r.permission == nil {
+		return false
 	}
+	return r.permission.GetPermissionLevel(r.ctx, uuid, username) >= r.config.Maintenance.BypassLevel
+}
 
-	r.dynamicServer.SetShutdownDelay(serverName, seconds)
-	endTime := time.Now().Add(time.Duration(seconds) * time.Second)
-	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Set protection period for server '%s' to %s", serverName, formatDuration(seconds)), S: component.Style{Color: color.Green}})
-	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Protection ends at: %s", endTime.Format("2006-01-02 15:04:05")), S: component.Style{Color: color.Gray}})
-	return nil
+func (r *RMSWhitelist) maintenanceMessage(state maintenance.State) component.Component {
+	content := state.Message
+	if content == "" {
+		content = r.config.Maintenance.MsgDefault
+	}
+	msg := &component.Text{Content: content}
+	if !state.Until.IsZero() {
+		msg.Extra = append(msg.Extra, &component.Text{
+			Content: "\n" + fmt.Sprintf(r.config.Maintenance.MsgUntil, state.Until.Local().Format("2006-01-02 15:04:05")),
+			S:       component.Style{Color: color.Gray},
+		})
+	}
+	return msg
 }
 
-func (r *RMSWhitelist) cmdAutoShutdown(ctx *command.Context) error {
-	if r.dynamicServer == nil {
-		ctx.Source.SendMessage(&component.Text{Content: "Dynamic server management is not enabled", S: component.Style{Color: color.Red}})
-		return nil
+func (r *RMSWhitelist) onPing(e *proxy.PingEvent) {
+	if !r.maintenance.Active() || r.config.Maintenance.Motd == "" {
+		return
+	}
+	if ping := e.Ping(); ping != nil {
+		ping.Description = &component.Text{Content: r.config.Maintenance.Motd}
 	}
+}
 
-	serverName := ctx.String("server")
-	toggle := ctx.String("toggle")
+// kickForMaintenance counts down and disconnects players who may not stay during maintenance.
+// It stops early if maintenance is switched off or re-enabled in the meantime.
+func (r *RMSWhitelist) kickForMaintenance(setAt time.Time) {
+	countdown := r.config.Maintenance.KickCountdownSeconds
 
-	if !r.dynamicServer.IsAutoStartServer(serverName) {
-		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Server '%s' is not a managed dynamic server", serverName), S: component.Style{Color: color.Red}})
-		return nil
+	affected := func() []proxy.Player {
+		var players []proxy.Player
+		for _, p := range r.proxy.Players() {
+			if !r.bypassesMaintenance(p.ID().String(), p.Username()) {
+				players = append(players, p)
+			}
+		}
+		return players
 	}
 
-	var enabled bool
-	switch toggle {
-	case "on", "true":
-		enabled = true
-	case "off", "false":
-		enabled = false
-	default:
-		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid value: %s. Use 'on' or 'off'", toggle), S: component.Style{Color: color.Red}})
-		return nil
+	for remaining := countdown; remaining > 0; remaining-- {
+		if remaining == countdown || remaining%30 == 0 || remaining <= 5 || remaining == 10 {
+			msg := &component.Text{
+				Content: fmt.Sprintf(r.config.Maintenance.MsgKickCountdown, remaining),
+				S:       component.Style{Color: color.Red},
+			}
+			for _, p := range affected() {
+				p.SendMessage(msg)
+			}
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+
+		if state := r.maintenance.State(); !state.Enabled || !state.SetAt.Equal(setAt) {
+			r.log.Info("Maintenance kick countdown cancelled")
+			return
+		}
 	}
 
-	r.dynamicServer.SetAutoShutdownEnabled(serverName, enabled)
-	state := "disabled"
-	if enabled {
-		state = "enabled"
+	state := r.maintenance.State()
+	players := affected()
+	for _, p := range players {
+		p.Disconnect(r.maintenanceMessage(state))
 	}
-	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Auto-shutdown %s for server '%s'", state, serverName), S: component.Style{Color: color.Green}})
-	return nil
+	r.log.Info("Kicked players for maintenance", "count", len(players))
 }
 
-func parseTimeString(s string) (int, error) {
-	if len(s) == 0 {
-		return 0, fmt.Errorf("empty string")
+// whitelistRequest describes the player and their connection for the whitelist providers
+func (r *RMSWhitelist) whitelistRequest(player proxy.Player, tier int) whitelist.Request {
+	req := whitelist.Request{
+		Username:   player.Username(),
+		UUID:       player.ID().String(),
+		Tier:       tier,
+		IP:         hostOf(player.RemoteAddr()),
+		Protocol:   int(player.Protocol()),
+		OnlineMode: player.OnlineMode(),
 	}
+	if vhost := player.VirtualHost(); vhost != nil {
+		req.VirtualHost = hostOf(vhost)
+	}
+	if rec := r.lookupASN(req.IP); rec != nil {
+		req.ASN, req.ASOrg = rec.ASN, rec.Org
+	}
+	return req
+}
 
-	lastChar := s[len(s)-1]
-	var multiplier int
-	var numStr string
+// hostOf strips the port from a network address
+func hostOf(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
 
-	switch lastChar {
-	case 's':
-		multiplier = 1
-		numStr = s[:len(s)-1]
-	case 'm':
-		multiplier = 60
-		numStr = s[:len(s)-1]
-	case 'h':
-		multiplier = 3600
-		numStr = s[:len(s)-1]
+// denyMessage renders the disconnect component for a denied whitelist result
+func (r *RMSWhitelist) denyMessage(result whitelist.Result) component.Component {
+	if result.Message != "" {
+		return &component.Text{Content: result.Message}
+	}
+
+	msg := &component.Text{Content: r.config.MsgNotInWhitelist}
+	addLine := func(format string, args ...any) {
+		if format == "" {
+			return
+		}
+		msg.Extra = append(msg.Extra, &component.Text{
+			Content: "\n" + fmt.Sprintf(format, args...),
+			S:       component.Style{Color: color.Gray},
+		})
+	}
+
+	if result.Reason != "" {
+		addLine(r.config.MsgDenyReason, result.Reason)
+	}
+	if !result.BanUntil.IsZero() {
+		addLine(r.config.MsgBannedUntil, result.BanUntil.Local().Format("2006-01-02 15:04:05"))
+	}
+	if result.RequiredTier > 0 {
+		addLine(r.config.MsgRequiredTier, result.RequiredTier)
+	}
+	return msg
+}
+
+// checkServerTier checks the player against the tier configured for a server.
+// Servers without a configured tier are only covered by the login check.
+func (r *RMSWhitelist) checkServerTier(player proxy.Player, serverName string) (bool, component.Component) {
+	tier, ok := r.config.ServerTiers[serverName]
+	if !ok {
+		return true, nil
+	}
+
+	username := player.Username()
+	result := r.checker.CheckTier(r.ctx, r.whitelistRequest(player, tier))
+
+	switch result.Status {
+	case whitelist.Allowed:
+		return true, nil
+	case whitelist.NotInWhitelist:
+		r.log.Info("Player denied by server tier", "player", username, "server", serverName, "tier", tier, "reason", result.Reason)
+		if result.Message != "" {
+			return false, &component.Text{Content: result.Message, S: component.Style{Color: color.Red}}
+		}
+		return false, &component.Text{Content: fmt.Sprintf(r.config.MsgTierDenied, serverName), S: component.Style{Color: color.Red}}
 	default:
-		multiplier = 1
-		numStr = s
+		r.log.Error(nil, "Server tier check failed", "player", username, "server", serverName, "tier", tier)
+		return false, &component.Text{Content: r.config.MsgServerError, S: component.Style{Color: color.Red}}
+	}
+}
+
+func (r *RMSWhitelist) onChooseInitialServer(e *proxy.PlayerChooseInitialServerEvent) {
+	server := e.InitialServer()
+	if server == nil {
+		return
+	}
+
+	player := e.Player()
+	serverName := server.ServerInfo().Name()
+	if !r.canAccessServer(player, serverName) {
+		r.log.Info("Player denied initial server by access rule", "player", player.Username(), "server", serverName)
+		player.Disconnect(r.serverRestrictedMessage(serverName))
+		return
+	}
+	if ok, msg := r.checkServerTier(player, serverName); !ok {
+		player.Disconnect(msg)
+	}
+}
+
+// canAccessServer applies the serverAccess rule of serverName to the player
+func (r *RMSWhitelist) canAccessServer(player proxy.Player, serverName string) bool {
+	uuid, username := player.ID().String(), player.Username()
+	return r.serverAccess.Allowed(serverName, uuid, username, func() int {
+		if r.permission == nil {
+			return 0
+		}
+		return r.permission.GetPermissionLevel(r.ctx, uuid, username)
+	})
+}
+
+func (r *RMSWhitelist) serverRestrictedMessage(serverName string) component.Component {
+	return &component.Text{Content: fmt.Sprintf(r.config.MsgServerRestricted, serverName), S: component.Style{Color: color.Red}}
+}
+
+// visibleServers returns the registered servers the player may join, sorted by name,
+// and whether any server was hidden
+func (r *RMSWhitelist) visibleServers(player proxy.Player) ([]proxy.RegisteredServer, bool) {
+	all := r.proxy.Servers()
+	visible := make([]proxy.RegisteredServer, 0, len(all))
+	for _, s := range all {
+		if r.canAccessServer(player, s.ServerInfo().Name()) {
+			visible = append(visible, s)
+		}
+	}
+	sort.Slice(visible, func(i, j int) bool {
+		return visible[i].ServerInfo().Name() < visible[j].ServerInfo().Name()
+	})
+	return visible, len(visible) < len(all)
+}
+
+// showServerList answers "/server" and "/glist all" itself when some servers are hidden from
+// the player, since Gate's built-in listings show every server. It reports whether it did.
+func (r *RMSWhitelist) showServerList(player proxy.Player, cmd string) bool {
+	if r.serverAccess.Servers() == 0 {
+		return false
+	}
+	words := splitCommandWords(cmd)
+	// With serverCommand the /server registered by RMS Gate filters the list itself
+	listServers := len(words) == 1 && words[0] == "server" && !r.config.ServerCommand
+	listPlayers := len(words) == 2 && words[0] == "glist" && words[1] == "all"
+	if !listServers && !listPlayers {
+		return false
+	}
+
+	visible, hidden := r.visibleServers(player)
+	if !hidden {
+		return false
+	}
+
+	if listServers {
+		sendServerList(player, visible)
+		return true
+	}
+
+	total := 0
+	for _, s := range visible {
+		var names []string
+		s.Players().Range(func(p proxy.Player) bool {
+			names = append(names, p.Username())
+			return true
+		})
+		total += len(names)
+		sort.Strings(names)
+		player.SendMessage(&component.Text{
+			Content: fmt.Sprintf("[%s] (%d): %s", s.ServerInfo().Name(), len(names), strings.Join(names, ", ")),
+			S:       component.Style{Color: color.Yellow},
+		})
+	}
+	player.SendMessage(&component.Text{Content: fmt.Sprintf("There are %d players online.", total), S: component.Style{Color: color.Yellow}})
+	return true
+}
+
+func sendServerList(player proxy.Player, servers []proxy.RegisteredServer) {
+	if conn := player.CurrentServer(); conn != nil {
+		player.SendMessage(&component.Text{
+			Content: fmt.Sprintf("You are currently connected to %s.", conn.Server().ServerInfo().Name()),
+			S:       component.Style{Color: color.Yellow},
+		})
+	}
+	names := make([]string, len(servers))
+	for i, s := range servers {
+		names[i] = s.ServerInfo().Name()
+	}
+	player.SendMessage(&component.Text{Content: "Available servers: " + strings.Join(names, ", "), S: component.Style{Color: color.Yellow}})
+}
+
+// cmdServer lists the servers the player may join, replacing Gate's /server when serverCommand is set
+func (r *RMSWhitelist) cmdServer(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "server") {
+		return nil
+	}
+	player, ok := ctx.Source.(proxy.Player)
+	if !ok {
+		ctx.Source.SendMessage(&component.Text{Content: "Only players can use this command", S: component.Style{Color: color.Red}})
+		return nil
+	}
+	visible, _ := r.visibleServers(player)
+	sendServerList(player, visible)
+	return nil
+}
+
+// cmdServerConnect sends the player to a server. Restricted servers are reported as unknown,
+// so the command does not reveal servers that are hidden from the list.
+func (r *RMSWhitelist) cmdServerConnect(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "server") {
+		return nil
+	}
+	player, ok := ctx.Source.(proxy.Player)
+	if !ok {
+		ctx.Source.SendMessage(&component.Text{Content: "Only players can use this command", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	name := ctx.String("server")
+	server := r.proxy.Server(name)
+	if server == nil || !r.canAccessServer(player, server.ServerInfo().Name()) {
+		player.SendMessage(&component.Text{Content: fmt.Sprintf("Server %s does not exist", name), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	// Connecting may wait for a dynamic server to start, keep it off the command goroutine
+	go player.CreateConnectionRequest(server).ConnectWithIndication(r.ctx)
+	return nil
+}
+
+// suggestServers completes /server with the servers the player may join
+func (r *RMSWhitelist) suggestServers() brigodier.SuggestionProvider {
+	return command.SuggestFunc(func(c *command.Context, b *brigodier.SuggestionsBuilder) *brigodier.Suggestions {
+		player, ok := c.Source.(proxy.Player)
+		if !ok {
+			return b.Build()
+		}
+		visible, _ := r.visibleServers(player)
+		for _, s := range visible {
+			name := s.ServerInfo().Name()
+			if strings.HasPrefix(strings.ToLower(name), b.RemainingLowerCase) {
+				b.Suggest(name)
+			}
+		}
+		return b.Build()
+	})
+}
+
+// splitCommandWords lowercases a command line and splits it into words without the leading slash
+func splitCommandWords(cmd string) []string {
+	return strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(cmd), "/")))
+}
+
+func (r *RMSWhitelist) onDisconnect(e *proxy.DisconnectEvent) {
+	r.checker.ForgetSession(e.Player().ID().String())
+}
+
+func (r *RMSWhitelist) onServerPreConnect(e *proxy.ServerPreConnectEvent) {
+	r.log.Info("ServerPreConnectEvent triggered")
+
+	server := e.Server()
+	if server == nil {
+		r.log.Info("server is nil, skipping")
+		return
+	}
+
+	serverName := server.ServerInfo().Name()
+	player := e.Player()
+
+	if !r.canAccessServer(player, serverName) {
+		r.log.Info("Player denied by server access rule", "player", player.Username(), "server", serverName)
+		player.SendMessage(r.serverRestrictedMessage(serverName))
+		e.Deny()
+		return
+	}
+
+	if ok, msg := r.checkServerTier(player, serverName); !ok {
+		player.SendMessage(msg)
+		e.Deny()
+		return
+	}
+
+	if r.dynamicServer == nil {
+		r.log.Info("dynamicServer is nil, skipping")
+		return
+	}
+
+	r.log.Info("Checking server", "server", serverName, "player", player.Username())
+
+	if !r.dynamicServer.IsAutoStartServer(serverName) {
+		r.log.Info("Server is not in auto-start list, skipping", "server", serverName)
+		return
+	}
+
+	r.log.Info("Player attempting to connect to auto-start server", "player", player.Username(), "server", serverName)
+
+	state, err := r.dynamicServer.ServerState(serverName)
+	r.log.Info("Checking instance status via MCSManager", "server", serverName, "state", state, "err", err)
+
+	if err != nil {
+		// Without the panel nothing can be started, but a server that is already up can still be joined
+		if r.dynamicServer.IsReachable(serverName) {
+			r.log.Info("MCSManager unavailable but server is reachable, connecting", "server", serverName, "error", err)
+			return
+		}
+		r.log.Error(err, "Cannot start server", "server", serverName, "player", player.Username())
+		player.SendMessage(r.dynamicServerErrorMessage(serverName, err))
+		e.Deny()
+		return
+	}
+
+	switch state {
+	case mcsmanager.StateRunning:
+		r.log.Info("Server is already running", "server", serverName)
+		return
+	case mcsmanager.StateStopping:
+		r.log.Info("Server is stopping, cannot start it yet", "server", serverName, "player", player.Username())
+		player.SendMessage(r.dynamicServerErrorMessage(serverName, dynamicserver.ErrServerStopping))
+		e.Deny()
+		return
+	case mcsmanager.StateBusy:
+		r.log.Info("Server is busy, cannot start it", "server", serverName, "player", player.Username())
+		player.SendMessage(r.dynamicServerErrorMessage(serverName, dynamicserver.ErrServerBusy))
+		e.Deny()
+		return
+	case mcsmanager.StateStarting:
+		r.log.Info("Server is already starting, waiting for it", "server", serverName, "player", player.Username())
+		player.SendMessage(&component.Text{Content: fmt.Sprintf(r.config.DynamicServer.MsgAlreadyStarting, serverName)})
+	default:
+		r.log.Info("Server is offline, starting it", "server", serverName, "state", state, "player", player.Username())
+		player.SendMessage(&component.Text{Content: fmt.Sprintf(r.config.DynamicServer.MsgStarting, serverName)})
+	}
+
+	if err := r.dynamicServer.EnsureServerRunning(serverName); err != nil {
+		r.log.Error(err, "Failed to start server", "server", serverName, "player", player.Username())
+		player.SendMessage(r.dynamicServerErrorMessage(serverName, err))
+		e.Deny()
+	} else {
+		r.log.Info("Server started successfully", "server", serverName, "player", player.Username())
+	}
+}
+
+// dynamicServerErrorMessage tells the player why an auto-start server could not be joined
+func (r *RMSWhitelist) dynamicServerErrorMessage(serverName string, err error) component.Component {
+	switch {
+	case errors.Is(err, dynamicserver.ErrPanelUnavailable):
+		return &component.Text{Content: fmt.Sprintf(r.config.DynamicServer.MsgPanelUnavailable, serverName)}
+	case errors.Is(err, dynamicserver.ErrServerStopping):
+		return &component.Text{Content: fmt.Sprintf(r.config.DynamicServer.MsgStopping, serverName)}
+	case errors.Is(err, dynamicserver.ErrServerBusy):
+		return &component.Text{Content: fmt.Sprintf(r.config.DynamicServer.MsgBusy, serverName)}
+	case errors.Is(err, dynamicserver.ErrStartFailed):
+		return &component.Text{Content: fmt.Sprintf(r.config.DynamicServer.MsgStartupTimeout, serverName)}
+	default:
+		return &component.Text{Content: r.config.MsgServerError}
+	}
+}
+
+func isServerOnline(addr net.Addr) bool {
+	conn, err := net.DialTimeout(addr.Network(), addr.String(), 3*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func (r *RMSWhitelist) onCommandExecute(e *proxy.CommandExecuteEvent) {
+	player, ok := e.Source().(proxy.Player)
+	if !ok {
+		if !r.isOwnCommand(e.Command()) {
+			r.audit.Command("console", "", e.Command(), audit.OutcomeAllowed)
+		}
+		return
+	}
+
+	cmd := e.Command()
+	username := player.Username()
+	uuid := player.ID().String()
+
+	if r.permission != nil && !r.permission.CanExecute(r.ctx, uuid, username, cmd) {
+		e.SetAllowed(false)
+		r.log.Info("Command blocked due to insufficient permission", "player", username, "command", cmd)
+		r.audit.Command(username, uuid, cmd, audit.OutcomeDenied)
+		player.SendMessage(&component.Text{
+			Content: r.config.Permission.MsgNoPermission,
+			S:       component.Style{Color: color.Red},
+		})
+		return
+	}
+	if r.showServerList(player, cmd) {
+		e.SetAllowed(false)
+		r.audit.Command(username, uuid, r.auditedCommand(cmd), audit.OutcomeFiltered)
+		return
+	}
+	if !r.isOwnCommand(cmd) {
+		r.audit.Command(username, uuid, r.auditedCommand(cmd), audit.OutcomeAllowed)
+	}
+}
+
+// auditedCommand returns what the audit log keeps of a player's command. Commands that no
+// permission rule gates are forwarded to backend servers and may carry passwords or private
+// messages, so only their name is recorded.
+func (r *RMSWhitelist) auditedCommand(cmd string) string {
+	if r.permission != nil && r.permission.IsGated(cmd) {
+		return cmd
+	}
+	name, _, _ := strings.Cut(strings.TrimSpace(cmd), " ")
+	return name
+}
+
+// onPermissionsSetup resolves permission nodes from RMS levels for every player,
+// which filters command trees and tab completion and lets other plugins use HasPermission
+func (r *RMSWhitelist) onPermissionsSetup(e *proxy.PermissionsSetupEvent) {
+	if r.permission == nil {
+		return
+	}
+	player, ok := e.Subject().(proxy.Player)
+	if !ok {
+		return
+	}
+
+	fallback := e.Func()
+	e.SetFunc(func(node string) gateperm.TriState {
+		if allowed, ok := r.permission.HasNode(r.ctx, player.ID().String(), player.Username(), node); ok {
+			if allowed {
+				return gateperm.True
+			}
+			return gateperm.False
+		}
+		if fallback != nil {
+			return fallback(node)
+		}
+		return gateperm.Undefined
+	})
+}
+
+// requires hides a command node from players who can run none of the given paths
+func (r *RMSWhitelist) requires(paths ...string) brigodier.RequireFn {
+	return command.Requires(func(c *command.RequiresContext) bool {
+		if r.permission == nil {
+			return true
+		}
+		for _, path := range paths {
+			if c.Source.HasPermission(permission.CommandNode(path)) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// pluginCommands are the roots of the command trees registered below. They are admin only
+// unless commandLevels or a granted node says otherwise.
+var pluginCommands = []string{"dserver", "lb", "wl", "ipban", "perm", "elevate", "audit", "rmsgate"}
+
+func (r *RMSWhitelist) registerCommands() {
+	// Gate's own /server suggests every server; this one only those the player may join.
+	// Gate's builtinCommands must be off, or both trees end up merged.
+	if r.config.ServerCommand {
+		r.proxy.Command().Register(brigodier.Literal("server").
+			Requires(r.requires("server")).
+			Executes(r.audited(r.cmdServer)).
+			Then(brigodier.Argument("server", brigodier.String).
+				Suggests(r.suggestServers()).
+				Executes(r.audited(r.cmdServerConnect))),
+		)
+	}
+
+	r.proxy.Command().Register(brigodier.Literal("dserver").
+		Requires(r.requires("dserver", "dserver delay", "dserver autoshutdown")).
+		Then(brigodier.Literal("delay").
+			Requires(r.requires("dserver delay")).
+			Then(brigodier.Argument("server", brigodier.String).
+				Then(brigodier.Argument("time", brigodier.String).
+					Executes(r.audited(r.cmdDelay))))).
+		Then(brigodier.Literal("autoshutdown").
+			Requires(r.requires("dserver autoshutdown")).
+			Then(brigodier.Argument("server", brigodier.String).
+				Then(brigodier.Argument("toggle", brigodier.String).
+					Executes(r.audited(r.cmdAutoShutdown))))).
+		Executes(r.audited(r.cmdHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("lb").
+		Requires(r.requires("lb", "lb status", "lb disable", "lb enable")).
+		Then(brigodier.Literal("status").
+			Requires(r.requires("lb status")).
+			Then(brigodier.Argument("server", brigodier.String).
+				Executes(r.audited(r.cmdLBStatus))).
+			Executes(r.audited(r.cmdLBStatusAll))).
+		Then(brigodier.Literal("disable").
+			Requires(r.requires("lb disable")).
+			Then(brigodier.Argument("server", brigodier.String).
+				Then(brigodier.Argument("backend", brigodier.String).
+					Executes(r.audited(r.cmdLBDisable))))).
+		Then(brigodier.Literal("enable").
+			Requires(r.requires("lb enable")).
+			Then(brigodier.Argument("server", brigodier.String).
+				Then(brigodier.Argument("backend", brigodier.String).
+					Executes(r.audited(r.cmdLBEnable))))).
+		Executes(r.audited(r.cmdLBHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("wl").
+		Requires(r.requires("wl", "wl add", "wl guest", "wl remove", "wl list")).
+		Then(brigodier.Literal("add").
+			Requires(r.requires(wlAddPath(false))).
+			Then(brigodier.Argument("player", brigodier.String).
+				Then(brigodier.Argument("duration", brigodier.String).
+					Executes(r.audited(func(ctx *command.Context) error {
+						return r.cmdWLAdd(ctx, false, true)
+					}))).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdWLAdd(ctx, false, false)
+				})))).
+		Then(brigodier.Literal("guest").
+			Requires(r.requires(wlAddPath(true))).
+			Then(brigodier.Argument("player", brigodier.String).
+				Then(brigodier.Argument("duration", brigodier.String).
+					Executes(r.audited(func(ctx *command.Context) error {
+						return r.cmdWLAdd(ctx, true, true)
+					}))).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdWLAdd(ctx, true, false)
+				})))).
+		Then(brigodier.Literal("remove").
+			Requires(r.requires("wl remove")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Executes(r.audited(r.cmdWLRemove)))).
+		Then(brigodier.Literal("list").
+			Requires(r.requires("wl list")).
+			Executes(r.audited(r.cmdWLList))).
+		Executes(r.audited(r.cmdWLHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("ipban").
+		Requires(r.requires("ipban", "ipban add", "ipban remove", "ipban list")).
+		Then(brigodier.Literal("add").
+			Requires(r.requires("ipban add")).
+			Then(brigodier.Argument("args", brigodier.StringPhrase).
+				Executes(r.audited(r.cmdIPBanAdd)))).
+		Then(brigodier.Literal("remove").
+			Requires(r.requires("ipban remove")).
+			Then(brigodier.Argument("cidr", brigodier.StringPhrase).
+				Executes(r.audited(r.cmdIPBanRemove)))).
+		Then(brigodier.Literal("list").
+			Requires(r.requires("ipban list")).
+			Executes(r.audited(r.cmdIPBanList))).
+		Executes(r.audited(r.cmdIPBanHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("perm").
+		Requires(r.requires("perm", "perm grant", "perm revoke", "perm info")).
+		Then(brigodier.Literal("grant").
+			Requires(r.requires("perm grant")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Then(brigodier.Argument("role", brigodier.String).
+					Then(brigodier.Argument("duration", brigodier.String).
+						Executes(r.audited(func(ctx *command.Context) error {
+							return r.cmdPermGrant(ctx, true)
+						}))).
+					Executes(r.audited(func(ctx *command.Context) error {
+						return r.cmdPermGrant(ctx, false)
+					}))))).
+		Then(brigodier.Literal("revoke").
+			Requires(r.requires("perm revoke")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Then(brigodier.Argument("role", brigodier.String).
+					Executes(r.audited(func(ctx *command.Context) error {
+						return r.cmdPermRevoke(ctx, true)
+					}))).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdPermRevoke(ctx, false)
+				})))).
+		Then(brigodier.Literal("info").
+			Requires(r.requires("perm info")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Executes(r.audited(r.cmdPermInfo)))).
+		Executes(r.audited(r.cmdPermHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("elevate").
+		Requires(r.requires("elevate", "elevate list", "elevate revoke")).
+		Then(brigodier.Literal("list").
+			Requires(r.requires("elevate list")).
+			Executes(r.audited(r.cmdElevateList))).
+		Then(brigodier.Literal("revoke").
+			Requires(r.requires("elevate revoke")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Executes(r.audited(r.cmdElevateRevoke)))).
+		Then(brigodier.Argument("duration", brigodier.String).
+			Then(brigodier.Argument("reason", brigodier.StringPhrase).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdElevate(ctx, ctx.String("reason"))
+				}))).
+			Executes(r.audited(func(ctx *command.Context) error {
+				return r.cmdElevate(ctx, "")
+			}))).
+		Executes(r.audited(r.cmdElevateHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("audit").
+		Requires(r.requires("audit", "audit recent", "audit export")).
+		Then(brigodier.Literal("recent").
+			Requires(r.requires("audit recent")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdAuditRecent(ctx, ctx.String("player"))
+				}))).
+			Executes(r.audited(func(ctx *command.Context) error {
+				return r.cmdAuditRecent(ctx, "")
+			}))).
+		Then(brigodier.Literal("export").
+			Requires(r.requires("audit export")).
+			Then(brigodier.Argument("player", brigodier.String).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdAuditExport(ctx, ctx.String("player"))
+				}))).
+			Executes(r.audited(func(ctx *command.Context) error {
+				return r.cmdAuditExport(ctx, "")
+			}))).
+		Executes(r.audited(r.cmdAuditHelp)))
+
+	r.proxy.Command().Register(brigodier.Literal("rmsgate").
+		Requires(r.requires("rmsgate", "rmsgate status", "rmsgate maintenance", "rmsgate alts", "rmsgate throttle")).
+		Then(brigodier.Literal("status").
+			Requires(r.requires("rmsgate status")).
+			Executes(r.audited(r.cmdStatus))).
+		Then(brigodier.Literal("maintenance").
+			Requires(r.requires("rmsgate maintenance", "rmsgate maintenance on", "rmsgate maintenance off")).
+			Then(brigodier.Literal("on").
+				Requires(r.requires("rmsgate maintenance on")).
+				Then(brigodier.Argument("args", brigodier.StringPhrase).
+					Executes(r.audited(func(ctx *command.Context) error {
+						return r.cmdMaintenanceOn(ctx, ctx.String("args"))
+					}))).
+				Executes(r.audited(func(ctx *command.Context) error {
+					return r.cmdMaintenanceOn(ctx, "")
+				}))).
+			Then(brigodier.Literal("off").
+				Requires(r.requires("rmsgate maintenance off")).
+				Executes(r.audited(r.cmdMaintenanceOff))).
+			Executes(r.audited(r.cmdMaintenanceStatus))).
+		Then(brigodier.Literal("alts").
+			Requires(r.requires("rmsgate alts")).
+			Executes(r.audited(r.cmdAlts))).
+		Then(brigodier.Literal("throttle").
+			Requires(r.requires("rmsgate throttle", "rmsgate throttle unban")).
+			Then(brigodier.Literal("unban").
+				Requires(r.requires("rmsgate throttle unban")).
+				Then(brigodier.Argument("ip", brigodier.StringPhrase).
+					Executes(r.audited(r.cmdThrottleUnban)))).
+			Executes(r.audited(r.cmdThrottle))).
+		Executes(r.audited(r.cmdRMSGateHelp)))
+}
+
+func (r *RMSWhitelist) cmdHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "dserver", "dserver delay", "dserver autoshutdown") {
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: "Dynamic Server Management Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /dserver delay <server> <time|off> - Set/clear protection period", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "    Time format: 10s, 5m, 2h or plain seconds", S: component.Style{Color: color.Gray}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /dserver autoshutdown <server> <on|off> - Toggle auto-shutdown", S: component.Style{Color: color.Yellow}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdDelay(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "dserver delay") {
+		return nil
+	}
+	if r.dynamicServer == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Dynamic server management is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	serverName := ctx.String("server")
+	timeArg := ctx.String("time")
+
+	if !r.dynamicServer.IsAutoStartServer(serverName) {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Server '%s' is not a managed dynamic server", serverName), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	if timeArg == "off" {
+		r.dynamicServer.ClearProtectionPeriod(serverName, sourceName(ctx))
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Cleared protection period for server '%s'", serverName), S: component.Style{Color: color.Green}})
+		return nil
+	}
+
+	seconds, err := parseTimeString(timeArg)
+	if err != nil || seconds < 0 {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid time format: %s", timeArg), S: component.Style{Color: color.Red}})
+		ctx.Source.SendMessage(&component.Text{Content: "Use format like: 30s, 5m, 2h or plain seconds", S: component.Style{Color: color.Gray}})
+		return nil
+	}
+
+	r.dynamicServer.SetShutdownDelay(serverName, seconds, sourceName(ctx))
+	endTime := time.Now().Add(time.Duration(seconds) * time.Second)
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Set protection period for server '%s' to %s", serverName, formatDuration(seconds)), S: component.Style{Color: color.Green}})
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Protection ends at: %s", endTime.Format("2006-01-02 15:04:05")), S: component.Style{Color: color.Gray}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdAutoShutdown(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "dserver autoshutdown") {
+		return nil
+	}
+	if r.dynamicServer == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Dynamic server management is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	serverName := ctx.String("server")
+	toggle := ctx.String("toggle")
+
+	if !r.dynamicServer.IsAutoStartServer(serverName) {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Server '%s' is not a managed dynamic server", serverName), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	var enabled bool
+	switch toggle {
+	case "on", "true":
+		enabled = true
+	case "off", "false":
+		enabled = false
+	default:
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid value: %s. Use 'on' or 'off'", toggle), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	r.dynamicServer.SetAutoShutdownEnabled(serverName, enabled, sourceName(ctx))
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Auto-shutdown %s for server '%s'", state, serverName), S: component.Style{Color: color.Green}})
+	return nil
+}
+
+// requirePermission allows the console and players who may run one of the command paths,
+// and tells everyone else they lack permission. Like requires, it allows everyone when
+// permission management is disabled.
+func (r *RMSWhitelist) requirePermission(ctx *command.Context, paths ...string) bool {
+	player, ok := ctx.Source.(proxy.Player)
+	if !ok || r.permission == nil {
+		return true
+	}
+	for _, path := range paths {
+		if r.permission.CanExecute(r.ctx, player.ID().String(), player.Username(), path) {
+			return true
+		}
+	}
+
+	msg := "You do not have permission to use this command"
+	if r.config.Permission != nil && r.config.Permission.MsgNoPermission != "" {
+		msg = r.config.Permission.MsgNoPermission
+	}
+	if o, ok := ctx.Source.(interface{ deny() }); ok {
+		o.deny()
+	}
+	ctx.Source.SendMessage(&component.Text{Content: msg, S: component.Style{Color: color.Red}})
+	return false
+}
+
+// sourceName returns the player name of a command source, or "console"
+func sourceName(ctx *command.Context) string {
+	if player, ok := ctx.Source.(proxy.Player); ok {
+		return player.Username()
+	}
+	return "console"
+}
+
+// commandOutcome is what a handler did, judged from its replies: an error reply means it failed
+type commandOutcome struct {
+	failed bool
+	denied bool
+}
+
+func (o *commandOutcome) observe(msg component.Component) {
+	if t, ok := msg.(*component.Text); ok && t.S.Color == color.Red {
+		o.failed = true
+	}
+}
+
+func (o *commandOutcome) deny() { o.denied = true }
+
+func (o *commandOutcome) result() string {
+	switch {
+	case o.denied:
+		return audit.OutcomeDenied
+	case o.failed:
+		return audit.OutcomeFailed
+	}
+	return audit.OutcomeOK
+}
+
+// outcomeSource and outcomePlayer pass replies through to the command source and note
+// failures. The player variant keeps ctx.Source.(proxy.Player) working in handlers.
+type outcomeSource struct {
+	command.Source
+	*commandOutcome
+}
+
+func (s outcomeSource) SendMessage(msg component.Component, opts ...command.MessageOption) error {
+	s.observe(msg)
+	return s.Source.SendMessage(msg, opts...)
+}
+
+type outcomePlayer struct {
+	proxy.Player
+	*commandOutcome
+}
+
+func (p outcomePlayer) SendMessage(msg component.Component, opts ...command.MessageOption) error {
+	p.observe(msg)
+	return p.Player.SendMessage(msg, opts...)
+}
+
+// audited runs a command handler and records what it did in the audit log
+func (r *RMSWhitelist) audited(run func(ctx *command.Context) error) brigodier.Command {
+	return command.Command(func(ctx *command.Context) error {
+		outcome := &commandOutcome{}
+		actor, uuid := "console", ""
+		source := ctx.Source
+		if player, ok := source.(proxy.Player); ok {
+			actor, uuid = player.Username(), player.ID().String()
+			ctx.Source = outcomePlayer{Player: player, commandOutcome: outcome}
+		} else {
+			ctx.Source = outcomeSource{Source: source, commandOutcome: outcome}
+		}
+		defer func() { ctx.Source = source }()
+
+		err := run(ctx)
+		if err != nil {
+			outcome.failed = true
+		}
+		r.audit.Command(actor, uuid, ctx.Input, outcome.result())
+		return err
+	})
+}
+
+// isOwnCommand reports whether cmd belongs to a tree registered by RMS Gate, whose
+// handlers audit the command once it has run
+func (r *RMSWhitelist) isOwnCommand(cmd string) bool {
+	root, _, _ := strings.Cut(strings.TrimSpace(cmd), " ")
+	root = strings.ToLower(root)
+	if root == "server" {
+		return r.config.ServerCommand
+	}
+	for _, c := range pluginCommands {
+		if c == root {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RMSWhitelist) cmdWLHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "wl", "wl add", "wl guest", "wl remove", "wl list") {
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: "Local Whitelist Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /wl add <player> [duration] - Whitelist a player locally", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /wl guest <player> [duration] - Grant a temporary guest pass", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /wl remove <player> - Remove a local entry", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /wl list - List local entries", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "    Duration format: 30m, 2h, 7d or plain seconds", S: component.Style{Color: color.Gray}})
+	return nil
+}
+
+func wlAddPath(guest bool) string {
+	if guest {
+		return "wl guest"
+	}
+	return "wl add"
+}
+
+func (r *RMSWhitelist) cmdWLAdd(ctx *command.Context, guest, hasDuration bool) error {
+	if !r.requirePermission(ctx, wlAddPath(guest)) {
+		return nil
+	}
+
+	username := ctx.String("player")
+	seconds := 0
+	if hasDuration {
+		timeArg := ctx.String("duration")
+		var err error
+		seconds, err = parseTimeString(timeArg)
+		if err != nil || seconds <= 0 {
+			ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid duration: %s", timeArg), S: component.Style{Color: color.Red}})
+			return nil
+		}
+	} else if guest {
+		seconds = 86400
+		if r.config.Whitelist != nil && r.config.Whitelist.GuestDefaultSeconds > 0 {
+			seconds = r.config.Whitelist.GuestDefaultSeconds
+		}
+	}
+
+	entry := whitelist.Entry{Username: username}
+	if online := r.proxy.PlayerByName(username); online != nil {
+		entry.Username = online.Username()
+		entry.UUID = online.ID().String()
+	}
+	if seconds > 0 {
+		entry.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+
+	if err := r.localWL.Add(entry, guest, sourceName(ctx)); err != nil {
+		r.log.Error(err, "Failed to add local whitelist entry", "player", username)
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to whitelist '%s': %v", username, err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	kind := "Whitelisted"
+	if guest {
+		kind = "Granted guest pass to"
+	}
+	r.log.Info("Local whitelist entry added", "player", entry.Username, "guest", guest, "seconds", seconds, "by", sourceName(ctx))
+	if seconds > 0 {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("%s '%s' for %s (until %s)", kind, entry.Username, formatDuration(seconds), entry.ExpiresAt.Format("2006-01-02 15:04:05")),
+			S:       component.Style{Color: color.Green},
+		})
+	} else {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("%s '%s'", kind, entry.Username), S: component.Style{Color: color.Green}})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdWLRemove(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "wl remove") {
+		return nil
+	}
+
+	username := ctx.String("player")
+	removed, err := r.localWL.Remove(username)
+	if err != nil {
+		r.log.Error(err, "Failed to remove local whitelist entry", "player", username)
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to remove '%s': %v", username, err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	if !removed {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("'%s' is not in the local whitelist", username), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	r.log.Info("Local whitelist entry removed", "player", username, "by", sourceName(ctx))
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Removed '%s' from the local whitelist", username), S: component.Style{Color: color.Green}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdWLList(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "wl list") {
+		return nil
+	}
+
+	entries := r.localWL.List()
+	if len(entries) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "The local whitelist is empty", S: component.Style{Color: color.Yellow}})
+		return nil
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Local Whitelist (%d):", len(entries)), S: component.Style{Color: color.Gold}})
+	for _, e := range entries {
+		info := ""
+		if e.Guest {
+			info += " [guest]"
+		}
+		if !e.ExpiresAt.IsZero() {
+			if e.Expired() {
+				info += " (expired)"
+			} else {
+				info += fmt.Sprintf(" (until %s)", e.ExpiresAt.Format("2006-01-02 15:04:05"))
+			}
+		}
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  %s%s - added by %s", e.Username, info, e.AddedBy),
+			S:       component.Style{Color: color.Yellow},
+		})
+	}
+	return nil
+}
+
+func parseTimeString(s string) (int, error) {
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty string")
+	}
+
+	lastChar := s[len(s)-1]
+	var multiplier int
+	var numStr string
+
+	switch lastChar {
+	case 's':
+		multiplier = 1
+		numStr = s[:len(s)-1]
+	case 'm':
+		multiplier = 60
+		numStr = s[:len(s)-1]
+	case 'h':
+		multiplier = 3600
+		numStr = s[:len(s)-1]
+	case 'd':
+		multiplier = 86400
+		numStr = s[:len(s)-1]
+	default:
+		multiplier = 1
+		numStr = s
+	}
+
+	var num int
+	_, err := fmt.Sscanf(numStr, "%d", &num)
+	if err != nil {
+		return 0, err
+	}
+	return num * multiplier, nil
+}
+
+func formatDuration(seconds int) string {
+	if seconds >= 86400 && seconds%86400 == 0 {
+		return fmt.Sprintf("%d day(s)", seconds/86400)
+	} else if seconds >= 3600 && seconds%3600 == 0 {
+		return fmt.Sprintf("%d hour(s)", seconds/3600)
+	} else if seconds >= 60 && seconds%60 == 0 {
+		return fmt.Sprintf("%d minute(s)", seconds/60)
+	}
+	return fmt.Sprintf("%d second(s)", seconds)
+}
+
+func getPluginDataDir() string {
+	if dir := os.Getenv("GATE_PLUGIN_DATA_DIR"); dir != "" {
+		return dir
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	return filepath.Dir(exe)
+}
+
+func (r *RMSWhitelist) cmdLBHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "lb", "lb status", "lb disable", "lb enable") {
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: "Load Balancer Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /lb status [server] - Show backend status and health scores", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /lb disable <server> <backend> - Disable a backend", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /lb enable <server> <backend> - Enable a backend", S: component.Style{Color: color.Yellow}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdLBStatusAll(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "lb status") {
+		return nil
+	}
+	if r.loadBalancer == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	servers := r.loadBalancer.GetAllServers()
+	if len(servers) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "No load balanced servers configured", S: component.Style{Color: color.Yellow}})
+		return nil
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: "Load Balanced Servers:", S: component.Style{Color: color.Gold}})
+	for name, server := range servers {
+		backends := server.Backends()
+		availableCount := 0
+		for _, b := range backends {
+			if b.IsAvailable() {
+				availableCount++
+			}
+		}
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  %s: %d/%d backends available, strategy: %s",
+				name, availableCount, len(backends), server.Strategy().Name()),
+			S: component.Style{Color: color.Yellow},
+		})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdLBStatus(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "lb status") {
+		return nil
+	}
+	if r.loadBalancer == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	serverName := ctx.String("server")
+	stats := r.loadBalancer.GetServerStats(serverName)
+	if stats == nil {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Server '%s' not found", serverName), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	server := r.loadBalancer.GetServer(serverName)
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("Server '%s' (strategy: %s):", serverName, server.Strategy().Name()),
+		S:       component.Style{Color: color.Gold},
+	})
+
+	// Calculate min latency and jitter for relative scoring (same as strategy.go)
+	backends := server.Backends()
+	var minLatency, minJitter float64 = -1, -1
+	for _, b := range backends {
+		if !b.IsAvailable() {
+			continue
+		}
+		lat := b.AvgLatency()
+		jit := b.Jitter()
+		if lat > 0 && (minLatency < 0 || lat < minLatency) {
+			minLatency = lat
+		}
+		if jit > 0 && (minJitter < 0 || jit < minJitter) {
+			minJitter = jit
+		}
+	}
+	if minLatency <= 0 {
+		minLatency = 1
+	}
+	if minJitter <= 0 {
+		minJitter = 1
+	}
+
+	for _, stat := range stats {
+		statusColor := color.Green
+		statusText := "OK"
+		if stat.Disabled {
+			statusColor = color.Gray
+			statusText = "DISABLED"
+		} else if !stat.Healthy {
+			statusColor = color.Red
+			statusText = "UNHEALTHY"
+		}
+
+		// Calculate score the same way as strategy selection
+		score := 0
+		histScore := 0
+		for _, b := range backends {
+			if b.Addr == stat.Addr {
+				score = b.RelativeHealthScore(minLatency, minJitter)
+				if r.loadBalancer.History() != nil {
+					histScore = r.loadBalancer.History().HistoricalScore(b.Addr, b.AvgLatency(), b.Jitter())
+					score += histScore
+				}
+				break
+			}
+		}
+
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  %s [%s] - %d player(s)", stat.Addr, statusText, stat.CurrentConns),
+			S:       component.Style{Color: statusColor},
+		})
+		histInfo := ""
+		if histScore != 0 {
+			histInfo = fmt.Sprintf(" (hist: %+d)", histScore)
+		}
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("    Score: %d%s | Max: %d | Latency: %.1fms | Jitter: %.1fms | Fails: %d",
+				score, histInfo, stat.MaxConnections, stat.AvgLatency, stat.Jitter, stat.FailCount),
+			S: component.Style{Color: color.Gray},
+		})
+		if len(stat.Players) > 0 {
+			playerList := ""
+			for i, p := range stat.Players {
+				if i > 0 {
+					playerList += ", "
+				}
+				playerList += p
+			}
+			ctx.Source.SendMessage(&component.Text{
+				Content: fmt.Sprintf("    Players: %s", playerList),
+				S:       component.Style{Color: color.Aqua},
+			})
+		}
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdLBDisable(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "lb disable") {
+		return nil
+	}
+	if r.loadBalancer == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	serverName := ctx.String("server")
+	backendAddr := ctx.String("backend")
+
+	if r.loadBalancer.DisableBackend(serverName, backendAddr, sourceName(ctx)) {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("Backend '%s' disabled for server '%s'", backendAddr, serverName),
+			S:       component.Style{Color: color.Green},
+		})
+	} else {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("Backend '%s' not found for server '%s'", backendAddr, serverName),
+			S:       component.Style{Color: color.Red},
+		})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdLBEnable(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "lb enable") {
+		return nil
+	}
+	if r.loadBalancer == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	serverName := ctx.String("server")
+	backendAddr := ctx.String("backend")
+
+	if r.loadBalancer.EnableBackend(serverName, backendAddr, sourceName(ctx)) {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("Backend '%s' enabled for server '%s'", backendAddr, serverName),
+			S:       component.Style{Color: color.Green},
+		})
+	} else {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("Backend '%s' not found for server '%s'", backendAddr, serverName),
+			S:       component.Style{Color: color.Red},
+		})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdRMSGateHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate", "rmsgate status", "rmsgate maintenance", "rmsgate alts", "rmsgate throttle") {
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: "RMS Gate Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /rmsgate status - Show whitelist check statistics", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /rmsgate maintenance on [message] [until] - Enable maintenance mode", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "    Until: duration (30m, 2h) or time (2006-01-02T15:04)", S: component.Style{Color: color.Gray}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /rmsgate maintenance off - Disable maintenance mode", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /rmsgate throttle - Show login limits and current offenders", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /rmsgate throttle unban <ip> - Lift an automatic IP ban", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /rmsgate alts - List addresses shared by several online accounts", S: component.Style{Color: color.Yellow}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdAlts(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate alts") {
+		return nil
+	}
+	if r.altLimit == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Alt-account limit is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	cfg := r.altLimit.Config()
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("Limit: %d account(s) per /%d (IPv4) or /%d (IPv6)", cfg.MaxAccounts, cfg.IPv4PrefixLen, cfg.IPv6PrefixLen),
+		S:       component.Style{Color: color.Gray},
+	})
+
+	groups := r.altLimit.Shared(r.onlineSessions())
+	if len(groups) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "No addresses are shared by online accounts", S: component.Style{Color: color.Green}})
+		return nil
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Shared Addresses (%d):", len(groups)), S: component.Style{Color: color.Gold}})
+	for _, g := range groups {
+		names := make([]string, len(g.Sessions))
+		for i, s := range g.Sessions {
+			names[i] = s.Username
+		}
+		lineColor := color.Yellow
+		if len(g.Sessions) > cfg.MaxAccounts {
+			lineColor = color.Red
+		}
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  %s (%d): %s", g.Key, len(g.Sessions), strings.Join(names, ", ")),
+			S:       component.Style{Color: lineColor},
+		})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdThrottle(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate throttle") {
+		return nil
+	}
+	if r.throttle == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Login throttling is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	cfg := r.throttle.Config()
+	stats := r.throttle.Stats()
+
+	ctx.Source.SendMessage(&component.Text{Content: "Login Throttling:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("  Per IP: %d/min (burst %d), global: %d/s (burst %d)", cfg.PerIPPerMinute, cfg.PerIPBurst, cfg.GlobalPerSec, cfg.GlobalBurst),
+		S:       component.Style{Color: color.Yellow},
+	})
+	if cfg.BanThreshold > 0 {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  Auto-ban: %d rejects within %s bans for %s", cfg.BanThreshold,
+				formatDuration(int(cfg.BanWindow.Seconds())), formatDuration(int(cfg.BanDuration.Seconds()))),
+			S: component.Style{Color: color.Yellow},
+		})
+	}
+	if cfg.MaxInFlight > 0 {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  In-flight checks: %d/%d", stats.InFlight, cfg.MaxInFlight),
+			S:       component.Style{Color: color.Yellow},
+		})
+	}
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("  Allowed: %d, throttled: %d, banned rejects: %d, busy rejects: %d, auto-bans: %d, tracked IPs: %d",
+			stats.Allowed, stats.Throttled, stats.BanRejects, stats.BusyRejects, stats.AutoBans, stats.TrackedIPs),
+		S: component.Style{Color: color.Yellow},
+	})
+
+	offenders := r.throttle.Offenders()
+	if len(offenders) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "No current offenders", S: component.Style{Color: color.Green}})
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Offenders (%d):", len(offenders)), S: component.Style{Color: color.Gold}})
+	for _, o := range offenders {
+		line := fmt.Sprintf("  %s - %d violation(s)", o.IP, o.Violations)
+		lineColor := color.Yellow
+		if !o.BannedUntil.IsZero() {
+			line = fmt.Sprintf("  %s - banned for %s", o.IP, formatDuration(int(time.Until(o.BannedUntil).Seconds())+1))
+			lineColor = color.Red
+		}
+		ctx.Source.SendMessage(&component.Text{Content: line, S: component.Style{Color: lineColor}})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdThrottleUnban(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate throttle unban") {
+		return nil
+	}
+	if r.throttle == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Login throttling is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	ip := strings.TrimSpace(ctx.String("ip"))
+	if !r.throttle.Unban(ip) {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("%s is not banned", ip), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	r.log.Info("Throttle ban lifted", "ip", ip, "by", sourceName(ctx))
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Lifted ban on %s", ip), S: component.Style{Color: color.Green}})
+	return nil
+}
+
+// parseMaintenanceArgs splits "[message] [until]" where until is the last word if it
+// is a suffixed duration (30m, 2h, 1d) or a local time (2006-01-02T15:04)
+func parseMaintenanceArgs(args string) (string, time.Time) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return "", time.Time{}
+	}
+
+	message := args
+	last := args
+	if i := strings.LastIndex(args, " "); i >= 0 {
+		message = strings.TrimSpace(args[:i])
+		last = args[i+1:]
+	} else {
+		message = ""
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02T15:04", last, time.Local); err == nil {
+		return message, t
+	}
+	if strings.ContainsAny(last[len(last)-1:], "smhd") {
+		if seconds, err := parseTimeString(last); err == nil && seconds > 0 {
+			return message, time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	return args, time.Time{}
+}
+
+func (r *RMSWhitelist) cmdMaintenanceOn(ctx *command.Context, args string) error {
+	if !r.requirePermission(ctx, "rmsgate maintenance on") {
+		return nil
+	}
+
+	message, until := parseMaintenanceArgs(args)
+	if err := r.maintenance.Enable(message, until, sourceName(ctx)); err != nil {
+		r.log.Error(err, "Failed to persist maintenance state")
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Maintenance enabled but not saved: %v", err), S: component.Style{Color: color.Red}})
+	} else {
+		ctx.Source.SendMessage(&component.Text{Content: "Maintenance mode enabled", S: component.Style{Color: color.Green}})
+	}
+	if !until.IsZero() {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Ends at: %s", until.Format("2006-01-02 15:04:05")), S: component.Style{Color: color.Gray}})
+	}
+
+	if r.config.Maintenance.KickOnline {
+		go r.kickForMaintenance(r.maintenance.State().SetAt)
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("Players below level %d will be kicked in %s", r.config.Maintenance.BypassLevel, formatDuration(r.config.Maintenance.KickCountdownSeconds)),
+			S:       component.Style{Color: color.Gray},
+		})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdMaintenanceOff(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate maintenance off") {
+		return nil
+	}
+
+	if err := r.maintenance.Disable(sourceName(ctx)); err != nil {
+		r.log.Error(err, "Failed to persist maintenance state")
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Maintenance disabled but not saved: %v", err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: "Maintenance mode disabled", S: component.Style{Color: color.Green}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdMaintenanceStatus(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate maintenance", "rmsgate maintenance on", "rmsgate maintenance off") {
+		return nil
+	}
+	state := r.maintenance.State()
+	if !state.Enabled {
+		ctx.Source.SendMessage(&component.Text{Content: "Maintenance mode is off", S: component.Style{Color: color.Green}})
+		return nil
+	}
+
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("Maintenance mode is on (by %s since %s)", state.SetBy, state.SetAt.Format("2006-01-02 15:04:05")),
+		S:       component.Style{Color: color.Gold},
+	})
+	if state.Message != "" {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("  Message: %s", state.Message), S: component.Style{Color: color.Yellow}})
+	}
+	if !state.Until.IsZero() {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("  Ends at: %s", state.Until.Format("2006-01-02 15:04:05")), S: component.Style{Color: color.Yellow}})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdStatus(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "rmsgate status") {
+		return nil
+	}
+	stats := r.checker.Stats()
+
+	if r.maintenance.Active() {
+		ctx.Source.SendMessage(&component.Text{Content: "Maintenance mode is ON", S: component.Style{Color: color.Red}})
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: "Whitelist Checks:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("  Providers: %s", r.checker.Provider().Name()),
+		S:       component.Style{Color: color.Yellow},
+	})
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("  Live: %d allowed, %d denied, %d error(s)", stats.LiveAllowed, stats.LiveDenied, stats.LiveErrors),
+		S:       component.Style{Color: color.Yellow},
+	})
+
+	if gc := r.checker.GraceCache(); gc != nil {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  Offline cache: %d allowed, %d denied (grace: %s)",
+				stats.CacheAllowed, stats.CacheDenied, formatDuration(int(gc.Grace().Seconds()))),
+			S: component.Style{Color: color.Yellow},
+		})
+	} else {
+		ctx.Source.SendMessage(&component.Text{Content: "  Offline cache: disabled", S: component.Style{Color: color.Gray}})
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: "Login Checks:", S: component.Style{Color: color.Gold}})
+	for _, cs := range r.loginPipeline.Stats() {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  %s: %d passed, %d denied (avg %s)", cs.Name, cs.Passed, cs.Denied, cs.AvgLatency.Round(time.Microsecond)),
+			S:       component.Style{Color: color.Yellow},
+		})
+	}
+
+	if r.asnDB != nil {
+		prefixes, asns := r.asnDB.Counts()
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  ASN database: %d prefixes, %d ASNs", prefixes, asns),
+			S:       component.Style{Color: color.Gray},
+		})
+	}
+
+	if r.permission != nil {
+		ps := r.permission.Status()
+		line := "  Permissions: not loaded"
+		lineColor := color.Red
+		if ps.Loaded {
+			line = fmt.Sprintf("  Permissions: %d user(s), refreshed %s ago", ps.Users, formatDuration(int(time.Since(ps.LastRefresh).Seconds())))
+			lineColor = color.Yellow
+			if ps.Stale {
+				line += " (stale)"
+				lineColor = color.Red
+			}
+		}
+		if ps.LastError != nil {
+			line += fmt.Sprintf(", last error: %v", ps.LastError)
+		}
+		ctx.Source.SendMessage(&component.Text{Content: line, S: component.Style{Color: lineColor}})
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: "RMS API Endpoints:", S: component.Style{Color: color.Gold}})
+	for _, ep := range r.api.Pool().Status() {
+		line := fmt.Sprintf("  %s (priority %d)", ep.URL, ep.Priority)
+		lineColor := color.Yellow
+		switch {
+		case ep.Cooldown > 0:
+			line += fmt.Sprintf(" - cooldown %s", formatDuration(int(ep.Cooldown.Seconds())+1))
+			lineColor = color.Red
+		case ep.Active:
+			line += " - active"
+			lineColor = color.Green
+		case ep.Failures > 0:
+			line += fmt.Sprintf(" - %d failure(s)", ep.Failures)
+		}
+		if ep.LastError != "" && ep.Cooldown > 0 {
+			line += fmt.Sprintf(", last error: %s", ep.LastError)
+		}
+		ctx.Source.SendMessage(&component.Text{Content: line, S: component.Style{Color: lineColor}})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdIPBanHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "ipban", "ipban add", "ipban remove", "ipban list") {
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: "IP Ban Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /ipban add <cidr> [duration] [reason] - Ban an address or range", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /ipban remove <cidr> - Lift a ban", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /ipban list - List active bans", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "    CIDR: 203.0.113.0/24, 2001:db8::/32 or a single address", S: component.Style{Color: color.Gray}})
+	ctx.Source.SendMessage(&component.Text{Content: "    Duration format: 30m, 2h, 7d; omit for a permanent ban", S: component.Style{Color: color.Gray}})
+	return nil
+}
+
+// parseIPBanArgs splits "<cidr> [duration] [reason]". The second word is only
+// taken as a duration if it parses as one, otherwise it starts the reason.
+func parseIPBanArgs(args string) (cidr string, seconds int, reason string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return "", 0, ""
+	}
+	cidr, fields = fields[0], fields[1:]
+
+	if len(fields) > 0 {
+		if s, err := parseTimeString(fields[0]); err == nil && s > 0 {
+			seconds = s
+			fields = fields[1:]
+		}
+	}
+	return cidr, seconds, strings.Join(fields, " ")
+}
+
+func (r *RMSWhitelist) cmdIPBanAdd(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "ipban add") {
+		return nil
+	}
+
+	cidr, seconds, reason := parseIPBanArgs(ctx.String("args"))
+	prefix, err := iprules.ParsePrefix(cidr)
+	if err != nil {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid address or CIDR: %s", cidr), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	ban, err := r.ipRules.Add(prefix, time.Duration(seconds)*time.Second, reason, sourceName(ctx))
+	if err != nil {
+		r.log.Error(err, "Failed to add IP ban", "prefix", prefix.String())
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to ban %s: %v", prefix, err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	r.log.Info("IP ban added", "prefix", prefix.String(), "seconds", seconds, "reason", reason, "by", sourceName(ctx))
+	if seconds > 0 {
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("Banned %s for %s (until %s)", prefix, formatDuration(seconds), ban.ExpiresAt.Format("2006-01-02 15:04:05")),
+			S:       component.Style{Color: color.Green},
+		})
+	} else {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Banned %s permanently", prefix), S: component.Style{Color: color.Green}})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdIPBanRemove(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "ipban remove") {
+		return nil
+	}
+
+	cidr := strings.TrimSpace(ctx.String("cidr"))
+	prefix, err := iprules.ParsePrefix(cidr)
+	if err != nil {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid address or CIDR: %s", cidr), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	removed, err := r.ipRules.Remove(prefix)
+	if err != nil {
+		r.log.Error(err, "Failed to remove IP ban", "prefix", prefix.String())
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to remove ban on %s: %v", prefix, err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	if !removed {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("%s is not banned", prefix), S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	r.log.Info("IP ban removed", "prefix", prefix.String(), "by", sourceName(ctx))
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Lifted ban on %s", prefix), S: component.Style{Color: color.Green}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdIPBanList(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "ipban list") {
+		return nil
+	}
+
+	allow, deny, _ := r.ipRules.Counts()
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("Config rules: %d allow, %d deny", allow, deny),
+		S:       component.Style{Color: color.Gray},
+	})
+
+	bans := r.ipRules.List()
+	if len(bans) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "No active IP bans", S: component.Style{Color: color.Yellow}})
+		return nil
+	}
+
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("IP Bans (%d):", len(bans)), S: component.Style{Color: color.Gold}})
+	for _, b := range bans {
+		info := " (permanent)"
+		if !b.ExpiresAt.IsZero() {
+			info = fmt.Sprintf(" (until %s)", b.ExpiresAt.Format("2006-01-02 15:04:05"))
+		}
+		if b.Reason != "" {
+			info += ": " + b.Reason
+		}
+		ctx.Source.SendMessage(&component.Text{
+			Content: fmt.Sprintf("  %s%s - added by %s", b.Prefix, info, b.AddedBy),
+			S:       component.Style{Color: color.Yellow},
+		})
 	}
+	return nil
+}
 
-	var num int
-	_, err := fmt.Sscanf(numStr, "%d", &num)
-	if err != nil {
-		return 0, err
+func (r *RMSWhitelist) cmdPermHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "perm", "perm grant", "perm revoke", "perm info") {
+		return nil
 	}
-	return num * multiplier, nil
+	ctx.Source.SendMessage(&component.Text{Content: "Permission Override Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /perm grant <player> <group|level> [duration] - Grant a group or level", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /perm revoke <player> [group|level] - Revoke one or all grants", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /perm info <player> - Show levels and grants", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "    Duration format: 30m, 2h, 7d; omit for a permanent grant", S: component.Style{Color: color.Gray}})
+	if r.permission != nil && r.permission.Overrides() != nil {
+		if groups := r.permission.Overrides().Groups(); len(groups) > 0 {
+			ctx.Source.SendMessage(&component.Text{Content: "    Groups: " + strings.Join(groups, ", "), S: component.Style{Color: color.Gray}})
+		}
+	}
+	return nil
 }
 
-func formatDuration(seconds int) string {
-	if seconds >= 3600 && seconds%3600 == 0 {
-		return fmt.Sprintf("%d hour(s)", seconds/3600)
-	} else if seconds >= 60 && seconds%60 == 0 {
-		return fmt.Sprintf("%d minute(s)", seconds/60)
+// permOverrides returns the override store, telling the source when permission management is off
+func (r *RMSWhitelist) permOverrides(ctx *command.Context) *permission.Overrides {
+	if r.permission == nil || r.permission.Overrides() == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Permission management is not enabled", S: component.Style{Color: color.Red}})
+		return nil
 	}
-	return fmt.Sprintf("%d second(s)", seconds)
+	return r.permission.Overrides()
 }
 
-func getPluginDataDir() string {
-	if dir := os.Getenv("GATE_PLUGIN_DATA_DIR"); dir != "" {
-		return dir
+// permTarget resolves a player name to its UUID and spelling when the player is online
+func (r *RMSWhitelist) permTarget(username string) (uuid, name string) {
+	if online := r.proxy.PlayerByName(username); online != nil {
+		return online.ID().String(), online.Username()
 	}
-	exe, err := os.Executable()
-	if err != nil {
-		return "."
+	return "", username
+}
+
+// parseRole reads a grant target: a positive number is a level, anything else a group name
+func parseRole(s string) (group string, level int) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return "", n
 	}
-	return filepath.Dir(exe)
+	return strings.ToLower(s), 0
 }
 
-func (r *RMSWhitelist) cmdLBHelp(ctx *command.Context) error {
-	ctx.Source.SendMessage(&component.Text{Content: "Load Balancer Commands:", S: component.Style{Color: color.Gold}})
-	ctx.Source.SendMessage(&component.Text{Content: "  /lb status [server] - Show backend status and health scores", S: component.Style{Color: color.Yellow}})
-	ctx.Source.SendMessage(&component.Text{Content: "  /lb disable <server> <backend> - Disable a backend", S: component.Style{Color: color.Yellow}})
-	ctx.Source.SendMessage(&component.Text{Content: "  /lb enable <server> <backend> - Enable a backend", S: component.Style{Color: color.Yellow}})
-	return nil
+func describeGrant(g *permission.Grant) string {
+	target := fmt.Sprintf("level %d", g.Level)
+	if g.Group != "" {
+		target = "group " + g.Group
+	}
+	if g.ExpiresAt.IsZero() {
+		return target + " (permanent)"
+	}
+	return fmt.Sprintf("%s (until %s)", target, g.ExpiresAt.Format("2006-01-02 15:04:05"))
 }
 
-func (r *RMSWhitelist) cmdLBStatusAll(ctx *command.Context) error {
-	if r.loadBalancer == nil {
-		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+func (r *RMSWhitelist) cmdPermGrant(ctx *command.Context, hasDuration bool) error {
+	if !r.requirePermission(ctx, "perm grant") {
 		return nil
 	}
-
-	servers := r.loadBalancer.GetAllServers()
-	if len(servers) == 0 {
-		ctx.Source.SendMessage(&component.Text{Content: "No load balanced servers configured", S: component.Style{Color: color.Yellow}})
+	overrides := r.permOverrides(ctx)
+	if overrides == nil {
 		return nil
 	}
 
-	ctx.Source.SendMessage(&component.Text{Content: "Load Balanced Servers:", S: component.Style{Color: color.Gold}})
-	for name, server := range servers {
-		backends := server.Backends()
-		availableCount := 0
-		for _, b := range backends {
-			if b.IsAvailable() {
-				availableCount++
-			}
+	uuid, username := r.permTarget(ctx.String("player"))
+	group, level := parseRole(ctx.String("role"))
+	granted := level
+	if group != "" {
+		groupLevel, ok := overrides.GroupLevel(group)
+		if !ok {
+			ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Unknown group: %s", group), S: component.Style{Color: color.Red}})
+			return nil
 		}
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("  %s: %d/%d backends available, strategy: %s",
-				name, availableCount, len(backends), server.Strategy().Name()),
-			S: component.Style{Color: color.Yellow},
-		})
+		granted = groupLevel
+	}
+
+	// Players cannot hand out more than they have themselves. Elevation does not count,
+	// or a temporary elevation could be turned into a permanent grant.
+	if player, ok := ctx.Source.(proxy.Player); ok {
+		own := r.permission.BaseLevel(r.ctx, player.ID().String(), player.Username())
+		if granted > own {
+			ctx.Source.SendMessage(&component.Text{
+				Content: fmt.Sprintf("You cannot grant level %d above your own level %d", granted, own),
+				S:       component.Style{Color: color.Red},
+			})
+			return nil
+		}
+		// Group nodes bypass levels entirely (rmsgate.command.* opens every command), so only
+		// admins may hand them out
+		if group != "" && len(overrides.GroupNodes(group)) > 0 && own <= permission.LevelAdmin {
+			ctx.Source.SendMessage(&component.Text{
+				Content: fmt.Sprintf("Group %s grants permission nodes, only admins can assign it", group),
+				S:       component.Style{Color: color.Red},
+			})
+			return nil
+		}
+	}
+
+	grant := permission.Grant{Group: group, Level: level, GrantedBy: sourceName(ctx)}
+	if hasDuration {
+		timeArg := ctx.String("duration")
+		seconds, err := parseTimeString(timeArg)
+		if err != nil || seconds <= 0 {
+			ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid duration: %s", timeArg), S: component.Style{Color: color.Red}})
+			return nil
+		}
+		grant.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+
+	if err := overrides.Grant(uuid, username, grant); err != nil {
+		r.log.Error(err, "Failed to grant permission override", "player", username)
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to grant '%s': %v", username, err), S: component.Style{Color: color.Red}})
+		return nil
 	}
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Granted %s to '%s'", describeGrant(&grant), username), S: component.Style{Color: color.Green}})
 	return nil
 }
 
-func (r *RMSWhitelist) cmdLBStatus(ctx *command.Context) error {
-	if r.loadBalancer == nil {
-		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+func (r *RMSWhitelist) cmdPermRevoke(ctx *command.Context, hasRole bool) error {
+	if !r.requirePermission(ctx, "perm revoke") {
+		return nil
+	}
+	overrides := r.permOverrides(ctx)
+	if overrides == nil {
 		return nil
 	}
 
-	serverName := ctx.String("server")
-	stats := r.loadBalancer.GetServerStats(serverName)
-	if stats == nil {
-		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Server '%s' not found", serverName), S: component.Style{Color: color.Red}})
+	uuid, username := r.permTarget(ctx.String("player"))
+	var group string
+	var level int
+	if hasRole {
+		group, level = parseRole(ctx.String("role"))
+	}
+
+	removed, err := overrides.Revoke(uuid, username, group, level)
+	if err != nil {
+		r.log.Error(err, "Failed to revoke permission override", "player", username)
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to revoke '%s': %v", username, err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	if removed == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("'%s' has no matching grants", username), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Revoked %d grant(s) from '%s'", removed, username), S: component.Style{Color: color.Green}})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdPermInfo(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "perm info") {
+		return nil
+	}
+	overrides := r.permOverrides(ctx)
+	if overrides == nil {
 		return nil
 	}
 
-	server := r.loadBalancer.GetServer(serverName)
+	uuid, username := r.permTarget(ctx.String("player"))
+	apiLevel := r.permission.APILevel(r.ctx, uuid, username)
+	eff := overrides.Effective(uuid, username)
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Permissions of '%s':", username), S: component.Style{Color: color.Gold}})
 	ctx.Source.SendMessage(&component.Text{
-		Content: fmt.Sprintf("Server '%s' (strategy: %s):", serverName, server.Strategy().Name()),
-		S:       component.Style{Color: color.Gold},
+		Content: fmt.Sprintf("  Level: %d (API %d, overrides %d)", max(apiLevel, eff.Level), apiLevel, eff.Level),
+		S:       component.Style{Color: color.Yellow},
 	})
+	if len(eff.Nodes) > 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "  Nodes: " + strings.Join(eff.Nodes, ", "), S: component.Style{Color: color.Yellow}})
+	}
 
-	// Calculate min latency and jitter for relative scoring (same as strategy.go)
-	backends := server.Backends()
-	var minLatency, minJitter float64 = -1, -1
-	for _, b := range backends {
-		if !b.IsAvailable() {
-			continue
-		}
-		lat := b.AvgLatency()
-		jit := b.Jitter()
-		if lat > 0 && (minLatency < 0 || lat < minLatency) {
-			minLatency = lat
+	po := overrides.Player(uuid, username)
+	if po == nil || len(po.Grants) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "  No local grants", S: component.Style{Color: color.Gray}})
+		return nil
+	}
+	for _, g := range po.Grants {
+		lineColor := color.Yellow
+		line := "  " + describeGrant(g)
+		if g.Expired() {
+			line += " - expired"
+			lineColor = color.Gray
 		}
-		if jit > 0 && (minJitter < 0 || jit < minJitter) {
-			minJitter = jit
+		if g.GrantedBy != "" {
+			line += " - granted by " + g.GrantedBy
 		}
+		ctx.Source.SendMessage(&component.Text{Content: line, S: component.Style{Color: lineColor}})
 	}
-	if minLatency <= 0 {
-		minLatency = 1
+	return nil
+}
+
+func (r *RMSWhitelist) cmdAuditHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "audit", "audit recent", "audit export") {
+		return nil
 	}
-	if minJitter <= 0 {
-		minJitter = 1
+	ctx.Source.SendMessage(&component.Text{Content: "Audit Log Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /audit recent [player] - Show the latest commands and state changes", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /audit export [player] - Export the log to a JSON file in the plugin directory", S: component.Style{Color: color.Yellow}})
+	return nil
+}
+
+// auditLog returns the audit log, telling the source when auditing is off
+func (r *RMSWhitelist) auditLog(ctx *command.Context) *audit.Log {
+	if r.audit == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Audit log is not enabled", S: component.Style{Color: color.Red}})
 	}
+	return r.audit
+}
 
-	for _, stat := range stats {
-		statusColor := color.Green
-		statusText := "OK"
-		if stat.Disabled {
-			statusColor = color.Gray
-			statusText = "DISABLED"
-		} else if !stat.Healthy {
-			statusColor = color.Red
-			statusText = "UNHEALTHY"
-		}
+func (r *RMSWhitelist) cmdAuditRecent(ctx *command.Context, actor string) error {
+	if !r.requirePermission(ctx, "audit recent") {
+		return nil
+	}
+	log := r.auditLog(ctx)
+	if log == nil {
+		return nil
+	}
 
-		// Calculate score the same way as strategy selection
-		score := 0
-		histScore := 0
-		for _, b := range backends {
-			if b.Addr == stat.Addr {
-				score = b.RelativeHealthScore(minLatency, minJitter)
-				if r.loadBalancer.History() != nil {
-					histScore = r.loadBalancer.History().HistoricalScore(b.Addr, b.AvgLatency(), b.Jitter())
-					score += histScore
-				}
-				break
-			}
-		}
+	limit := r.config.Audit.RecentLimit
+	if limit <= 0 {
+		limit = 15
+	}
+	entries, err := log.Recent(limit, actor)
+	if err != nil {
+		r.log.Error(err, "Failed to read audit log")
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to read audit log: %v", err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	if len(entries) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "No audit entries", S: component.Style{Color: color.Yellow}})
+		return nil
+	}
 
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("  %s [%s] - %d player(s)", stat.Addr, statusText, stat.CurrentConns),
-			S:       component.Style{Color: statusColor},
-		})
-		histInfo := ""
-		if histScore != 0 {
-			histInfo = fmt.Sprintf(" (hist: %+d)", histScore)
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Audit Log (latest %d):", len(entries)), S: component.Style{Color: color.Gold}})
+	// Oldest first so the newest entry ends up at the bottom of the chat
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		line := fmt.Sprintf("  %s %s: %s", e.Time.Format("01-02 15:04:05"), e.Actor, e.Command)
+		if e.Args != "" {
+			line += " " + e.Args
 		}
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("    Score: %d%s | Max: %d | Latency: %.1fms | Jitter: %.1fms | Fails: %d",
-				score, histInfo, stat.MaxConnections, stat.AvgLatency, stat.Jitter, stat.FailCount),
-			S: component.Style{Color: color.Gray},
-		})
-		if len(stat.Players) > 0 {
-			playerList := ""
-			for i, p := range stat.Players {
-				if i > 0 {
-					playerList += ", "
-				}
-				playerList += p
-			}
-			ctx.Source.SendMessage(&component.Text{
-				Content: fmt.Sprintf("    Players: %s", playerList),
-				S:       component.Style{Color: color.Aqua},
-			})
+		lineColor := color.Yellow
+		switch e.Outcome {
+		case audit.OutcomeDenied, audit.OutcomeFailed:
+			line += " [" + e.Outcome + "]"
+			lineColor = color.Red
 		}
+		ctx.Source.SendMessage(&component.Text{Content: line, S: component.Style{Color: lineColor}})
 	}
 	return nil
 }
 
-func (r *RMSWhitelist) cmdLBDisable(ctx *command.Context) error {
-	if r.loadBalancer == nil {
-		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+func (r *RMSWhitelist) cmdAuditExport(ctx *command.Context, actor string) error {
+	if !r.requirePermission(ctx, "audit export") {
+		return nil
+	}
+	log := r.auditLog(ctx)
+	if log == nil {
 		return nil
 	}
 
-	serverName := ctx.String("server")
-	backendAddr := ctx.String("backend")
+	path, count, err := log.ExportFile(actor)
+	if err != nil {
+		r.log.Error(err, "Failed to export audit log")
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Failed to export audit log: %v", err), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	r.log.Info("Audit log exported", "path", path, "entries", count, "by", sourceName(ctx))
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Exported %d entries to %s", count, path), S: component.Style{Color: color.Green}})
+	return nil
+}
 
-	if r.loadBalancer.DisableBackend(serverName, backendAddr) {
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("Backend '%s' disabled for server '%s'", backendAddr, serverName),
-			S:       component.Style{Color: color.Green},
-		})
-	} else {
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("Backend '%s' not found for server '%s'", backendAddr, serverName),
-			S:       component.Style{Color: color.Red},
-		})
+func (r *RMSWhitelist) cmdElevateHelp(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "elevate", "elevate list", "elevate revoke") {
+		return nil
 	}
+	ctx.Source.SendMessage(&component.Text{Content: "Elevation Commands:", S: component.Style{Color: color.Gold}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /elevate <duration> [reason] - Temporarily raise your own level", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /elevate list - List active elevations", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "  /elevate revoke <player> - End an elevation early", S: component.Style{Color: color.Yellow}})
+	ctx.Source.SendMessage(&component.Text{Content: "    Duration format: 10m, 1h", S: component.Style{Color: color.Gray}})
 	return nil
 }
 
-func (r *RMSWhitelist) cmdLBEnable(ctx *command.Context) error {
-	if r.loadBalancer == nil {
-		ctx.Source.SendMessage(&component.Text{Content: "Load balancer is not enabled", S: component.Style{Color: color.Red}})
+func (r *RMSWhitelist) cmdElevate(ctx *command.Context, reason string) error {
+	if !r.requirePermission(ctx, "elevate") {
+		return nil
+	}
+	player, ok := ctx.Source.(proxy.Player)
+	if !ok {
+		ctx.Source.SendMessage(&component.Text{Content: "Only players can elevate", S: component.Style{Color: color.Red}})
+		return nil
+	}
+	if r.permission == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Permission management is not enabled", S: component.Style{Color: color.Red}})
 		return nil
 	}
 
-	serverName := ctx.String("server")
-	backendAddr := ctx.String("backend")
+	timeArg := ctx.String("duration")
+	seconds, err := parseTimeString(timeArg)
+	if err != nil || seconds <= 0 {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Invalid duration: %s", timeArg), S: component.Style{Color: color.Red}})
+		return nil
+	}
 
-	if r.loadBalancer.EnableBackend(serverName, backendAddr) {
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("Backend '%s' enabled for server '%s'", backendAddr, serverName),
-			S:       component.Style{Color: color.Green},
-		})
-	} else {
-		ctx.Source.SendMessage(&component.Text{
-			Content: fmt.Sprintf("Backend '%s' not found for server '%s'", backendAddr, serverName),
-			S:       component.Style{Color: color.Red},
-		})
+	e, err := r.permission.Elevate(r.ctx, player.ID().String(), player.Username(), time.Duration(seconds)*time.Second, strings.TrimSpace(reason))
+	if err != nil {
+		msg := fmt.Sprintf("Cannot elevate: %v", err)
+		switch {
+		case errors.Is(err, permission.ErrElevationTooLong) && r.config.Permission.Elevation != nil:
+			msg = fmt.Sprintf("Cannot elevate for more than %s", formatDuration(r.config.Permission.Elevation.MaxSeconds))
+		case errors.Is(err, permission.ErrAlreadyElevated):
+			msg = "You are already elevated"
+		case errors.Is(err, permission.ErrElevationCooldown):
+			msg = "Cannot elevate again so soon after the last elevation ended"
+		}
+		ctx.Source.SendMessage(&component.Text{Content: msg, S: component.Style{Color: color.Red}})
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{
+		Content: fmt.Sprintf("Elevated to level %d for %s (until %s)", e.Level, formatDuration(seconds), e.ExpiresAt.Format("2006-01-02 15:04:05")),
+		S:       component.Style{Color: color.Green},
+	})
+	return nil
+}
+
+func (r *RMSWhitelist) cmdElevateList(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "elevate list") {
+		return nil
+	}
+	if r.permission == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Permission management is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	active := r.permission.Elevations()
+	if len(active) == 0 {
+		ctx.Source.SendMessage(&component.Text{Content: "No active elevations", S: component.Style{Color: color.Yellow}})
+		return nil
+	}
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Active Elevations (%d):", len(active)), S: component.Style{Color: color.Gold}})
+	for _, e := range active {
+		line := fmt.Sprintf("  %s - level %d, %s left", e.Username, e.Level, formatDuration(int(time.Until(e.ExpiresAt).Seconds())+1))
+		if e.Reason != "" {
+			line += ": " + e.Reason
+		}
+		ctx.Source.SendMessage(&component.Text{Content: line, S: component.Style{Color: color.Yellow}})
+	}
+	return nil
+}
+
+func (r *RMSWhitelist) cmdElevateRevoke(ctx *command.Context) error {
+	if !r.requirePermission(ctx, "elevate revoke") {
+		return nil
+	}
+	if r.permission == nil {
+		ctx.Source.SendMessage(&component.Text{Content: "Permission management is not enabled", S: component.Style{Color: color.Red}})
+		return nil
+	}
+
+	username := ctx.String("player")
+	if !r.permission.RevokeElevation(username, sourceName(ctx)) {
+		ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("'%s' is not elevated", username), S: component.Style{Color: color.Red}})
+		return nil
+	}
+	if online := r.proxy.PlayerByName(username); online != nil {
+		online.SendMessage(&component.Text{Content: fmt.Sprintf("Your elevation was revoked by %s", sourceName(ctx)), S: component.Style{Color: color.Red}})
 	}
+	ctx.Source.SendMessage(&component.Text{Content: fmt.Sprintf("Revoked elevation of '%s'", username), S: component.Style{Color: color.Green}})
 	return nil
 }